@@ -81,33 +81,177 @@ type GCSBackend struct {
 }
 
 type WebhookRef struct {
-	// +kubebuilder:validation:Enum=slack;mattermost
+	// +kubebuilder:validation:Enum=slack;mattermost;teams
 	Type     string     `json:"type,omitempty"`
 	Endpoint string     `json:"webhook,omitempty"`
 	Channel  string     `json:"channel,omitempty"`
 	UserName string     `json:"username,omitempty"`
 	IconURL  string     `json:"icon_url,omitempty"`
 	Secret   *SecretRef `json:"secret,omitempty"`
+	// MinSeverity is the lowest severity of the results sent to the sink, all are sent when unset
+	MinSeverity Severity `json:"minSeverity,omitempty"`
+	// ResultURL links Teams cards to the result, {namespace} and {name} are replaced by the
+	// namespace and name of the result, e.g. the page of the result in a cluster dashboard
+	ResultURL string `json:"resultURL,omitempty"`
 }
 
+// SinkSpec is a sink results are sent to and the results it receives. Every filter that is set
+// must match for a result to be sent.
+type SinkSpec struct {
+	// Name identifies the sink in the delivery status of results, the sink configured by
+	// spec.sink is named default
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	Name       string `json:"name"`
+	WebhookRef `json:",inline"`
+	// Kinds are the kinds of the results sent to the sink, e.g. Pod
+	Kinds []string `json:"kinds,omitempty"`
+	// Namespaces are the namespaces of the objects whose results are sent to the sink, results of
+	// cluster scoped objects are not sent when it is set
+	Namespaces []string `json:"namespaces,omitempty"`
+	// LifeCycles are the changes of results sent to the sink
+	LifeCycles []SinkLifeCycle `json:"lifecycles,omitempty"`
+}
+
+// SinkLifeCycle is a change of a result sent to sinks
+// +kubebuilder:validation:Enum=created;updated;resolved
+type SinkLifeCycle string
+
+const (
+	SinkLifeCycleCreated  SinkLifeCycle = "created"
+	SinkLifeCycleUpdated  SinkLifeCycle = "updated"
+	SinkLifeCycleResolved SinkLifeCycle = "resolved"
+)
+
 type BackOff struct {
 	// +kubebuilder:default:=false
 	Enabled bool `json:"enabled"`
 	// +kubebuilder:default:=5
 	MaxRetries int `json:"maxRetries"`
+	// Cooldown is how long AI explanations stay disabled after MaxRetries consecutive
+	// failures before a single probe request is allowed through
+	// +kubebuilder:default:="5m"
+	// +kubebuilder:validation:Pattern=`^([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$`
+	Cooldown string `json:"cooldown,omitempty"`
 }
 
 type AutoRemediation struct {
 	// +kubebuilder:default:=false
 	Enabled bool `json:"enabled"`
-	// Defaults to 10%
+	// SimilarityRequirement is the minimum similarity, 100 minus the risk score of the change,
+	// a mutation needs to be applied. Defaults to 90, roughly an image change.
 	// +kubebuilder:default="90"
 	SimilarityRequirement string `json:"similarityRequirement"`
 	// Support Pod, Deployment, Service and Ingress
+	// Only kinds in this list are remediated
 	// +kubebuilder:default:={"Pod","Deployment","Service","Ingress"}
 	Resources []string `json:"resources"`
+	// Namespaces limits remediation to objects in these namespaces, all namespaces are eligible when empty
+	Namespaces []string `json:"namespaces,omitempty"`
+	// ExcludeNamespaces lists namespaces that are never remediated, it takes precedence over Namespaces
+	ExcludeNamespaces []string `json:"excludeNamespaces,omitempty"`
+	// LabelSelector limits remediation to objects whose labels match
+	LabelSelector *metav1.LabelSelector `json:"labelSelector,omitempty"`
+	// ApprovalMode controls whether mutations are applied automatically or wait for a reviewer.
+	// In Manual mode the planned change is dry-run applied and its diff stored on the Mutation.
+	// +kubebuilder:default:=Automatic
+	// +kubebuilder:validation:Enum=Automatic;Manual
+	ApprovalMode ApprovalMode `json:"approvalMode,omitempty"`
+	// Rollback restores the pre-change configuration when a remediation fails. Rollback is enabled
+	// with a 30m pending deadline when unset.
+	Rollback *RollbackPolicy `json:"rollback,omitempty"`
+	// RetryBudget is how many failed backend queries and apply attempts a mutation is retried
+	// before it moves to the Failed phase
+	// +kubebuilder:default:=3
+	// +kubebuilder:validation:Minimum=0
+	RetryBudget int `json:"retryBudget,omitempty"`
+	// ForceApply lets fixes take over fields owned by other field managers. Without it a fix that
+	// conflicts with another manager fails the mutation and the conflicts are reported in its status.
+	ForceApply bool `json:"forceApply,omitempty"`
+	// Policies are CEL expressions every mutation must satisfy before it is applied
+	// +listType=map
+	// +listMapKey=name
+	Policies []RemediationPolicy `json:"policies,omitempty"`
+	// PromptTemplates names a ConfigMap in the namespace of the K8sGPT instance holding Go
+	// text/template prompts per step and kind, the built-in prompts are used when it is unset
+	PromptTemplates *corev1.LocalObjectReference `json:"promptTemplates,omitempty"`
+	// BlastRadius limits how many objects auto remediation changes and when. Mutations held back
+	// wait in the Pending phase.
+	BlastRadius *BlastRadius `json:"blastRadius,omitempty"`
+	// MinSeverity is the lowest severity of the results that are remediated, all are when unset
+	MinSeverity Severity `json:"minSeverity,omitempty"`
+}
+
+// BlastRadius limits the changes auto remediation makes. Limits set to 0 are not enforced.
+type BlastRadius struct {
+	// MaxInFlight caps the mutations being applied or awaiting their result across the cluster
+	// +kubebuilder:validation:Minimum=0
+	MaxInFlight int `json:"maxInFlight,omitempty"`
+	// MaxInFlightPerNamespace caps the mutations being applied or awaiting their result per
+	// namespace of the remediated objects
+	// +kubebuilder:validation:Minimum=0
+	MaxInFlightPerNamespace int `json:"maxInFlightPerNamespace,omitempty"`
+	// Cooldown is how long after a mutation of an object was applied before the object may be
+	// mutated again
+	// +kubebuilder:validation:Pattern=`^([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$`
+	Cooldown string `json:"cooldown,omitempty"`
+	// MaxDailyPerTarget caps how many mutations of the same object are applied in 24 hours
+	// +kubebuilder:validation:Minimum=0
+	MaxDailyPerTarget int `json:"maxDailyPerTarget,omitempty"`
+	// MaintenanceWindows are when mutations may be applied, mutations are applied at any time when empty
+	MaintenanceWindows []MaintenanceWindow `json:"maintenanceWindows,omitempty"`
+}
+
+// MaintenanceWindow is a recurring period in which mutations may be applied
+type MaintenanceWindow struct {
+	// Schedule is a cron expression of when the window opens, e.g. "0 2 * * 1-5"
+	// +kubebuilder:validation:MinLength=1
+	Schedule string `json:"schedule"`
+	// Duration is how long the window stays open
+	// +kubebuilder:validation:Pattern=`^([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$`
+	Duration string `json:"duration"`
+	// TimeZone is the IANA time zone of the schedule, e.g. "Europe/Berlin", UTC when unset
+	TimeZone string `json:"timeZone,omitempty"`
 }
 
+// RemediationPolicy is a rule on the changes auto remediation may make. The expression is
+// evaluated with oldObject, the live object or null when it does not exist, and newObject,
+// the target configuration of the fix, and must return true for the fix to be applied.
+type RemediationPolicy struct {
+	// Name identifies the policy in the violations reported on a mutation
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+	// Expression is a CEL expression returning a bool
+	// +kubebuilder:validation:MinLength=1
+	Expression string `json:"expression"`
+	// Message is reported when the expression returns false, the expression is reported when empty
+	Message string `json:"message,omitempty"`
+}
+
+type RollbackPolicy struct {
+	// +kubebuilder:default:=true
+	Enabled bool `json:"enabled,omitempty"`
+	// PendingDeadline is how long a mutation may stay Pending, with its result still present,
+	// after it has been applied before it is rolled back
+	// +kubebuilder:default:="30m"
+	// +kubebuilder:validation:Pattern=`^([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$`
+	PendingDeadline string `json:"pendingDeadline,omitempty"`
+}
+
+type ApprovalMode string
+
+const (
+	ApprovalModeAutomatic ApprovalMode = "Automatic"
+	ApprovalModeManual    ApprovalMode = "Manual"
+)
+
+const (
+	// AutoRemediationAnnotation opts a single object out of auto remediation when set to "disabled"
+	AutoRemediationAnnotation = "k8sgpt.ai/auto-remediation"
+	// AutoRemediationDisabled is the AutoRemediationAnnotation value that opts an object out
+	AutoRemediationDisabled = "disabled"
+)
+
 type AISpec struct {
 	AutoRemediation AutoRemediation `json:"autoRemediation,omitempty"`
 	// +kubebuilder:default:=openai
@@ -150,6 +294,45 @@ type AnalysisConfig struct {
 	// Interval is the time between analysis runs
 	// +kubebuilder:validation:Pattern=`^[0-9]+[smh]$`
 	Interval string `json:"interval,omitempty"`
+	// SeverityRules classify results before the built-in rules, the first matching rule wins
+	SeverityRules []SeverityRule `json:"severityRules,omitempty"`
+	// ResolvedTTL is how long the history of a resolved result is kept for it to reappear,
+	// 1h when unset
+	// +kubebuilder:validation:Pattern=`^([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$`
+	ResolvedTTL string `json:"resolvedTTL,omitempty"`
+}
+
+// SeverityRule assigns a severity to the results of a kind whose errors match a pattern
+type SeverityRule struct {
+	// Kind is the analyzer kind of the results, e.g. Pod, any kind matches when empty
+	Kind string `json:"kind,omitempty"`
+	// Pattern is a regular expression matched against the errors of the results, any result
+	// of the kind matches when empty
+	Pattern string `json:"pattern,omitempty"`
+	// +kubebuilder:validation:Required
+	Severity Severity `json:"severity"`
+}
+
+// MetricsConfig configures the result metrics of the K8sGPT instance
+type MetricsConfig struct {
+	// MinSeverity leaves results below this severity out of the result metrics
+	MinSeverity Severity `json:"minSeverity,omitempty"`
+}
+
+// RetentionConfig bounds the results the K8sGPT instance keeps
+type RetentionConfig struct {
+	// MaxResults is the most results the instance keeps, results of the lowest severity that were
+	// found last are evicted first. Unlimited when unset
+	// +kubebuilder:validation:Minimum=1
+	MaxResults int `json:"maxResults,omitempty"`
+	// MaxAge is how long a result whose content does not change is kept, e.g. a problem that
+	// nobody fixes or a result no analysis run finds while the analysis fails. Unlimited when unset
+	// +kubebuilder:validation:Pattern=`^([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$`
+	MaxAge string `json:"maxAge,omitempty"`
+	// MaxDetailsLength is the most bytes of details a result keeps, longer details are truncated.
+	// Unlimited when unset
+	// +kubebuilder:validation:Minimum=1
+	MaxDetailsLength int `json:"maxDetailsLength,omitempty"`
 }
 
 // K8sGPTSpec defines the desired state of K8sGPT
@@ -165,12 +348,18 @@ type K8sGPTSpec struct {
 	Filters          []string                     `json:"filters,omitempty"`
 	ExtraOptions     *ExtraOptionsRef             `json:"extraOptions,omitempty"`
 	Sink             *WebhookRef                  `json:"sink,omitempty"`
+	// Sinks are sinks results are sent to in addition to sink
+	// +listType=map
+	// +listMapKey=name
+	Sinks []SinkSpec `json:"sinks,omitempty"`
 	AI               *AISpec                      `json:"ai,omitempty"`
 	RemoteCache      *RemoteCacheRef              `json:"remoteCache,omitempty"`
 	Integrations     *Integrations                `json:"integrations,omitempty"`
 	NodeSelector     map[string]string            `json:"nodeSelector,omitempty"`
 	TargetNamespace  string                       `json:"targetNamespace,omitempty"`
 	Analysis         *AnalysisConfig              `json:"analysis,omitempty"`
+	Metrics          *MetricsConfig               `json:"metrics,omitempty"`
+	Retention        *RetentionConfig             `json:"retention,omitempty"`
 	// Define the kubeconfig the Deployment must use.
 	// If empty, the Deployment will use the ServiceAccount provided by Kubernetes itself.
 	Kubeconfig *SecretRef `json:"kubeconfig,omitempty"`
@@ -188,16 +377,87 @@ const (
 	IBMWatsonxAI    = "ibmwatsonxai"
 )
 
+// Condition types reported in K8sGPTStatus.Conditions
+const (
+	// ConditionReady summarises whether the instance is serving analysis results
+	ConditionReady = "Ready"
+	// ConditionServerAvailable reports whether the k8sgpt server deployment is reachable
+	ConditionServerAvailable = "ServerAvailable"
+	// ConditionAIBackendAvailable reports whether AI explanations are being requested from the backend
+	ConditionAIBackendAvailable = "AIBackendAvailable"
+	// ConditionAnalysisSucceeded reports the outcome of the last analysis run
+	ConditionAnalysisSucceeded = "AnalysisSucceeded"
+	// ConditionSinkHealthy reports whether results are being delivered to the configured sinks
+	ConditionSinkHealthy = "SinkHealthy"
+	// ConditionSeverityRulesValid reports whether every severity rule is used, invalid rules are skipped
+	ConditionSeverityRulesValid = "SeverityRulesValid"
+)
+
+// CircuitBreakerState is the state of the AI backend circuit breaker
+// +kubebuilder:validation:Enum=Closed;Open;HalfOpen
+type CircuitBreakerState string
+
+const (
+	// CircuitBreakerClosed means AI explanations are requested on every analysis
+	CircuitBreakerClosed CircuitBreakerState = "Closed"
+	// CircuitBreakerOpen means AI explanations are skipped until the cooldown elapses
+	CircuitBreakerOpen CircuitBreakerState = "Open"
+	// CircuitBreakerHalfOpen means a single probe analysis with AI explanations is in flight
+	CircuitBreakerHalfOpen CircuitBreakerState = "HalfOpen"
+)
+
+// CircuitBreakerStatus tracks the AI backend circuit breaker of a K8sGPT instance
+type CircuitBreakerStatus struct {
+	State CircuitBreakerState `json:"state,omitempty"`
+	// ConsecutiveFailures is the number of AI backed analyses that failed in a row
+	ConsecutiveFailures int `json:"consecutiveFailures,omitempty"`
+	// OpenedAt is when the circuit last opened, the cooldown is measured from here
+	OpenedAt *metav1.Time `json:"openedAt,omitempty"`
+	// LastTransitionTime is when the circuit last changed state
+	LastTransitionTime *metav1.Time `json:"lastTransitionTime,omitempty"`
+	// Reason is the error that caused the circuit to open
+	Reason string `json:"reason,omitempty"`
+}
+
 // K8sGPTStatus defines the observed state of K8sGPT
-// show the current backend used
-// +kubebuilder:printcolumn:name="Backend",type="string",JSONPath=".spec.ai.backend",description="The current backend used"
 type K8sGPTStatus struct {
-	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
-	// Important: Run "make" to regenerate code after modifying this file
+	// ObservedGeneration is the most recent generation observed by the controller
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Conditions represent the latest available observations of the instance
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// LastAnalysisTime is when the last successful analysis that changed the results finished
+	LastAnalysisTime *metav1.Time `json:"lastAnalysisTime,omitempty"`
+	// LastAnalysisDuration is how long the analysis of LastAnalysisTime took
+	LastAnalysisDuration *metav1.Duration `json:"lastAnalysisDuration,omitempty"`
+	// ResultCount is the number of results produced by the last analysis
+	ResultCount int `json:"resultCount,omitempty"`
+	// ResultsByKind is the number of results produced by the last analysis, grouped by kind
+	ResultsByKind map[string]int `json:"resultsByKind,omitempty"`
+	// ServerImage is the image currently deployed for the k8sgpt server
+	ServerImage string `json:"serverImage,omitempty"`
+	// LastError is the error from the most recent reconcile, empty when it succeeded
+	LastError string `json:"lastError,omitempty"`
+	// AIBackendCircuit is the circuit breaker guarding AI explanations
+	AIBackendCircuit *CircuitBreakerStatus `json:"aiBackendCircuit,omitempty"`
+	// ResultTombstones keep the history of recently resolved results
+	// +listType=map
+	// +listMapKey=name
+	ResultTombstones []ResultTombstone `json:"resultTombstones,omitempty"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Backend",type="string",JSONPath=".spec.ai.backend",description="The current backend used"
+//+kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].status",description="Whether the instance is ready"
+//+kubebuilder:printcolumn:name="Results",type="integer",JSONPath=".status.resultCount",description="Number of results from the last analysis"
+//+kubebuilder:printcolumn:name="Last Analysis",type="date",JSONPath=".status.lastAnalysisTime",description="Time of the last successful analysis"
+//+kubebuilder:printcolumn:name="AI Circuit",type="string",JSONPath=".status.aiBackendCircuit.state",description="State of the AI backend circuit breaker",priority=1
+//+kubebuilder:printcolumn:name="Image",type="string",JSONPath=".status.serverImage",description="The deployed k8sgpt server image",priority=1
+//+kubebuilder:printcolumn:name="Error",type="string",JSONPath=".status.lastError",description="The last reconcile error",priority=1
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
 // K8sGPT is the Schema for the k8sgpts API
 type K8sGPT struct {
@@ -28,12 +28,64 @@ import (
 type MutationSpec struct {
 	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
-	SimilarityScore     string                 `json:"similarityScore,omitempty"`
-	ResourceGVK         string                 `json:"resourceGVK,omitempty"`
-	ResourceRef         corev1.ObjectReference `json:"resource,omitempty"`
-	ResultRef           corev1.ObjectReference `json:"result,omitempty"`
+	// SimilarityScore is 100 minus RiskScore, it is compared with the SimilarityRequirement
+	SimilarityScore string `json:"similarityScore,omitempty"`
+	// RiskScore weighs the fields the target configuration changes, from 0 to 100.
+	// Changes to image, command, securityContext, volumes, serviceAccountName and hostNetwork weigh more.
+	RiskScore int `json:"riskScore,omitempty"`
+	// Patch is the JSON patch (RFC 6902) from the origin to the target configuration
+	Patch       string                 `json:"patch,omitempty"`
+	ResourceGVK string                 `json:"resourceGVK,omitempty"`
+	ResourceRef corev1.ObjectReference `json:"resource,omitempty"`
+	ResultRef   corev1.ObjectReference `json:"result,omitempty"`
+	// ResultRefs are all the results that contributed to the mutation, e.g. the results of
+	// every pod of a Deployment. The mutation is successful once none of them exist.
+	ResultRefs []corev1.ObjectReference `json:"resultRefs,omitempty"`
+	// Workload is the top level owner of the resource, e.g. the Deployment of a Pod. Only one
+	// mutation is applied to a workload at a time.
+	Workload            corev1.ObjectReference `json:"workload,omitempty"`
 	OriginConfiguration string                 `json:"originConfiguration,omitempty"`
 	TargetConfiguration string                 `json:"targetConfiguration,omitempty"`
+	// Approval is set by a reviewer to approve or reject a mutation awaiting approval.
+	// The k8sgpt.ai/approval and k8sgpt.ai/reviewer annotations can be used instead. The
+	// reviewer must be allowed the approve verb on the mutation.
+	Approval *MutationApproval `json:"approval,omitempty"`
+}
+
+type ApprovalDecision string
+
+const (
+	ApprovalDecisionApproved ApprovalDecision = "Approved"
+	ApprovalDecisionRejected ApprovalDecision = "Rejected"
+)
+
+const (
+	// ApprovalAnnotation approves or rejects a mutation, the value is approved or rejected
+	ApprovalAnnotation = "k8sgpt.ai/approval"
+	// ReviewerAnnotation names the reviewer that set the ApprovalAnnotation
+	ReviewerAnnotation = "k8sgpt.ai/reviewer"
+)
+
+type MutationApproval struct {
+	// +kubebuilder:validation:Enum=Approved;Rejected
+	Decision ApprovalDecision `json:"decision"`
+	// Reviewer is the user name of who made the decision, decisions without one are ignored
+	Reviewer string `json:"reviewer,omitempty"`
+}
+
+// PolicyViolation is a remediation policy the target configuration of a mutation does not satisfy
+type PolicyViolation struct {
+	// Policy is the name of the remediation policy
+	Policy string `json:"policy"`
+	// Message is the message of the policy, or why it could not be evaluated
+	Message string `json:"message"`
+}
+
+// ApprovalRecord is the decision taken on a mutation as observed by the controller
+type ApprovalRecord struct {
+	Decision ApprovalDecision `json:"decision"`
+	Reviewer string           `json:"reviewer,omitempty"`
+	Time     metav1.Time      `json:"time"`
 }
 
 // MutationStatus defines the observed state of Mutation.
@@ -42,10 +94,96 @@ type MutationStatus struct {
 	// Important: Run "make" to regenerate code after modifying this file
 	Phase   AutoRemediationPhase `json:"phase,omitempty"`
 	Message string               `json:"message,omitempty"`
+	// PlannedConfiguration is the manifest that will be written once the mutation is approved
+	PlannedConfiguration string `json:"plannedConfiguration,omitempty"`
+	// PlannedAt is when the planned configuration was stored, decisions made before are ignored
+	PlannedAt *metav1.Time `json:"plannedAt,omitempty"`
+	// Diff is the unified diff between the live object and the server-side dry-run of the planned configuration
+	Diff string `json:"diff,omitempty"`
+	// ValidationErrors are the errors the API server reported for the dry-run
+	ValidationErrors []string `json:"validationErrors,omitempty"`
+	// GuardrailViolations are the reasons the target configuration was refused before it was applied
+	GuardrailViolations []string `json:"guardrailViolations,omitempty"`
+	// PolicyViolations are the remediation policies the target configuration does not satisfy
+	PolicyViolations []PolicyViolation `json:"policyViolations,omitempty"`
+	// Conflicts are the fields another field manager owns that applying the fix would take over
+	Conflicts []string `json:"conflicts,omitempty"`
+	// Approval records who approved or rejected the mutation and when
+	Approval *ApprovalRecord `json:"approval,omitempty"`
+	// HeldBy is why the blast radius limits of its instance hold a mutation that is ready to be
+	// applied in the Pending phase. Pending mutations without it were applied and await their result
+	HeldBy string `json:"heldBy,omitempty"`
+	// AppliedAt is when the mutation was written to the cluster
+	AppliedAt *metav1.Time `json:"appliedAt,omitempty"`
+	// PreviousConfiguration is the manifest of the written object as it was before the mutation,
+	// it is restored on rollback
+	PreviousConfiguration string `json:"previousConfiguration,omitempty"`
+	// CrashLoopsBeforeApply is the number of crash looping containers of the written object before the mutation
+	CrashLoopsBeforeApply int `json:"crashLoopsBeforeApply,omitempty"`
+	// RolloutStalledBeforeApply records whether the rollout of the written deployment had exceeded
+	// its progress deadline before the mutation
+	RolloutStalledBeforeApply bool `json:"rolloutStalledBeforeApply,omitempty"`
+	// RollbackReason records why the mutation was rolled back
+	RollbackReason string `json:"rollbackReason,omitempty"`
+	// FailureReason records why the mutation moved to the Failed phase
+	FailureReason MutationFailureReason `json:"failureReason,omitempty"`
+	// Retries counts the failed backend queries and apply attempts of the mutation
+	Retries int `json:"retries,omitempty"`
+	// TemplateVersion is the version of the prompt template used for the last backend query
+	TemplateVersion string `json:"templateVersion,omitempty"`
+	// PromptHash is the SHA-256 of the last prompt sent to the backend
+	PromptHash string `json:"promptHash,omitempty"`
+	// ResponseHash is the SHA-256 of the last response of the backend
+	ResponseHash string `json:"responseHash,omitempty"`
+	// History is the audit log of the mutation, oldest first and bounded to the most recent entries
+	History []MutationHistoryEntry `json:"history,omitempty"`
+}
+
+// MutationHistoryEntry records a phase transition or retry of a mutation
+type MutationHistoryEntry struct {
+	PreviousPhase   AutoRemediationPhase `json:"previousPhase"`
+	Phase           AutoRemediationPhase `json:"phase"`
+	Time            metav1.Time          `json:"time"`
+	Message         string               `json:"message,omitempty"`
+	TemplateVersion string               `json:"templateVersion,omitempty"`
+	PromptHash      string               `json:"promptHash,omitempty"`
+	ResponseHash    string               `json:"responseHash,omitempty"`
+	SimilarityScore string               `json:"similarityScore,omitempty"`
+	// ApplyResult is set when the entry records an attempt to write the target, e.g. Applied or Failed
+	ApplyResult string `json:"applyResult,omitempty"`
 }
 
+type MutationFailureReason string
+
+const (
+	// MutationFailureQueryFailed means the backend could not be queried for a target configuration
+	MutationFailureQueryFailed MutationFailureReason = "QueryFailed"
+	// MutationFailureNoKnownFix means the backend did not know how to fix the resource
+	MutationFailureNoKnownFix MutationFailureReason = "NoKnownFix"
+	// MutationFailureInvalidTarget means the target configuration could not be converted to an object,
+	// or changes a field that cannot be updated
+	MutationFailureInvalidTarget MutationFailureReason = "InvalidTarget"
+	// MutationFailureResolveFailed means the object to write could not be derived from the target configuration
+	MutationFailureResolveFailed MutationFailureReason = "ResolveFailed"
+	// MutationFailureUnsupportedKind means there is no executor for the kind of the resource
+	MutationFailureUnsupportedKind MutationFailureReason = "UnsupportedKind"
+	// MutationFailureApplyFailed means the target could not be written to the cluster
+	MutationFailureApplyFailed MutationFailureReason = "ApplyFailed"
+	// MutationFailureConflict means the fix conflicts with fields owned by another field manager
+	MutationFailureConflict MutationFailureReason = "Conflict"
+	// MutationFailureGuardrailViolation means the target configuration failed the guardrails, see GuardrailViolations
+	MutationFailureGuardrailViolation MutationFailureReason = "GuardrailViolation"
+	// MutationFailurePolicyViolation means the target configuration does not satisfy a remediation
+	// policy, see PolicyViolations
+	MutationFailurePolicyViolation MutationFailureReason = "PolicyViolation"
+	// MutationFailureOutOfScope means the object the mutation writes, e.g. the workload of a pod,
+	// is outside the remediation scope of the instance
+	MutationFailureOutOfScope MutationFailureReason = "OutOfScope"
+)
+
 // +kubebuilder:object:root=true
 
+// +kubebuilder:subresource:status
 // Display in wide format the autoremediationphase status and similarity score
 // +kubebuilder:printcolumn:name="State",type="string",JSONPath=".status.message",description="Updates of the autoremediation phase"
 // +kubebuilder:printcolumn:name="Similarity Score",type="string",JSONPath=".spec.similarityScore",description="The similarity score of the autoremediation"
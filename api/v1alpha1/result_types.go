@@ -17,6 +17,8 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"fmt"
+
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -43,13 +45,70 @@ const (
 	AutoRemediationPhaseCompleted
 	AutoRemediationPhaseSuccessful
 	AutoRemediationPending
-	AutoRemediationAborted = 5
+	AutoRemediationAborted AutoRemediationPhase = 5
+	// AutoRemediationAwaitingApproval holds a planned mutation until a reviewer approves or rejects it
+	AutoRemediationAwaitingApproval AutoRemediationPhase = 6
+	// AutoRemediationRolledBack means the origin configuration was restored after the mutation failed
+	AutoRemediationRolledBack AutoRemediationPhase = 7
+	// AutoRemediationFailed means the mutation could not be carried out, the reason is recorded in its status
+	AutoRemediationFailed AutoRemediationPhase = 8
 )
 
+var autoRemediationPhaseNames = map[AutoRemediationPhase]string{
+	AutoRemediationPhaseNotStarted:  "NotStarted",
+	AutoRemediationPhaseInProgress:  "InProgress",
+	AutoRemediationPhaseCompleted:   "Completed",
+	AutoRemediationPhaseSuccessful:  "Successful",
+	AutoRemediationPending:          "Pending",
+	AutoRemediationAborted:          "Aborted",
+	AutoRemediationAwaitingApproval: "AwaitingApproval",
+	AutoRemediationRolledBack:       "RolledBack",
+	AutoRemediationFailed:           "Failed",
+}
+
+func (p AutoRemediationPhase) String() string {
+	if name, ok := autoRemediationPhaseNames[p]; ok {
+		return name
+	}
+	return fmt.Sprintf("AutoRemediationPhase(%d)", int(p))
+}
+
 type AutoRemediationStatus struct {
 	Phase AutoRemediationPhase `json:"phase,omitempty"`
 }
 
+// Severity ranks how urgent a result is, from Info to Critical
+// +kubebuilder:validation:Enum=Info;Low;Medium;High;Critical
+type Severity string
+
+const (
+	SeverityInfo     Severity = "Info"
+	SeverityLow      Severity = "Low"
+	SeverityMedium   Severity = "Medium"
+	SeverityHigh     Severity = "High"
+	SeverityCritical Severity = "Critical"
+)
+
+var severityRanks = map[Severity]int{
+	SeverityInfo:     1,
+	SeverityLow:      2,
+	SeverityMedium:   3,
+	SeverityHigh:     4,
+	SeverityCritical: 5,
+}
+
+// Valid reports whether the severity is one of the known severities
+func (s Severity) Valid() bool {
+	_, ok := severityRanks[s]
+	return ok
+}
+
+// AtLeast reports whether the severity is min or above, every severity is when min is empty.
+// Results that were not classified are below any minimum.
+func (s Severity) AtLeast(min Severity) bool {
+	return min == "" || severityRanks[s] >= severityRanks[min]
+}
+
 // ResultSpec defines the desired state of Result
 type ResultSpec struct {
 	Backend               string                `json:"backend"`
@@ -59,18 +118,73 @@ type ResultSpec struct {
 	Error                 []Failure             `json:"error"`
 	Details               string                `json:"details"`
 	ParentObject          string                `json:"parentObject"`
+	// Severity is assigned by the operator from the severity rules of the K8sGPT instance and
+	// the built-in rules
+	Severity Severity `json:"severity,omitempty"`
 }
 
 // ResultStatus defines the observed state of Result
 type ResultStatus struct {
 	LifeCycle string `json:"lifecycle,omitempty"`
 	Webhook   string `json:"webhook,omitempty"`
+	// FirstSeen is when the problem was first found, it is kept when the result is resolved and
+	// reappears shortly after
+	FirstSeen *metav1.Time `json:"firstSeen,omitempty"`
+	// LastSeen is when the last analysis run found the problem
+	LastSeen *metav1.Time `json:"lastSeen,omitempty"`
+	// LastChanged is when the content of the result, its errors, details or parent object, last
+	// changed
+	LastChanged *metav1.Time `json:"lastChanged,omitempty"`
+	// Occurrences is the number of analysis runs that found the problem
+	Occurrences int `json:"occurrences,omitempty"`
+	// Reappearances are the recent times the result came back after it was resolved
+	Reappearances []metav1.Time `json:"reappearances,omitempty"`
+	// Flapping is true when the result keeps being resolved and coming back
+	Flapping bool `json:"flapping,omitempty"`
+	// Deliveries are the last deliveries of the result to each sink
+	// +listType=map
+	// +listMapKey=name
+	Deliveries []SinkDelivery `json:"deliveries,omitempty"`
+}
+
+// SinkDelivery is the last delivery of a result to a sink
+type SinkDelivery struct {
+	// Name is the name of the sink
+	Name string `json:"name"`
+	// LifeCycle is the change of the result that was sent, created, updated or resolved
+	LifeCycle string `json:"lifecycle,omitempty"`
+	// DeliveredAt is when the sink last received the result
+	DeliveredAt *metav1.Time `json:"deliveredAt,omitempty"`
+	// Error is why the last delivery failed, it is empty once the sink received the result
+	Error string `json:"error,omitempty"`
+}
+
+// ResultTombstone keeps the history of a resolved or evicted result for a while, so that it
+// continues when the result reappears
+type ResultTombstone struct {
+	Name string `json:"name"`
+	// ResolvedAt is when the result was resolved or evicted, or last left out by an analysis run
+	// while its eviction holds
+	ResolvedAt    metav1.Time   `json:"resolvedAt"`
+	FirstSeen     *metav1.Time  `json:"firstSeen,omitempty"`
+	Occurrences   int           `json:"occurrences,omitempty"`
+	Reappearances []metav1.Time `json:"reappearances,omitempty"`
+	// Evicted is why the retention evicted the result, max_results or max_age. Empty when the
+	// result was resolved
+	Evicted string `json:"evicted,omitempty"`
+	// ContentHash is the content of a result evicted for its age. Analysis runs leave the result
+	// out until its content changes
+	ContentHash string `json:"contentHash,omitempty"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
 // +kubebuilder:printcolumn:name="Kind",type="string",JSONPath=".spec.kind",description="Kind"
+// +kubebuilder:printcolumn:name="Severity",type="string",JSONPath=".spec.severity",description="Severity"
 // +kubebuilder:printcolumn:name="Backend",type="string",JSONPath=".spec.backend",description="Backend"
+// +kubebuilder:printcolumn:name="Seen",type="integer",JSONPath=".status.occurrences",description="Analysis runs that found the problem",priority=1
+// +kubebuilder:printcolumn:name="Flapping",type="boolean",JSONPath=".status.flapping",description="Whether the result keeps coming back",priority=1
+// +kubebuilder:printcolumn:name="First Seen",type="date",JSONPath=".status.firstSeen",description="When the problem was first found",priority=1
 // +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp",description="Age"
 // Result is the Schema for the results API
 type Result struct {
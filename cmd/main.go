@@ -23,7 +23,7 @@ import (
 
 	"github.com/k8sgpt-ai/k8sgpt-operator/internal/controller/k8sgpt"
 	"github.com/k8sgpt-ai/k8sgpt-operator/internal/controller/mutation"
-	"github.com/k8sgpt-ai/k8sgpt-operator/internal/controller/types"
+	"github.com/k8sgpt-ai/k8sgpt-operator/internal/controller/shared"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
@@ -35,7 +35,9 @@ import (
 	"github.com/k8sgpt-ai/k8sgpt-operator/pkg/sinks"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/discovery"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	openapicached "k8s.io/client-go/openapi/cached"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
@@ -131,14 +133,25 @@ func main() {
 
 	metricsBuilder := metrics.InitializeMetrics()
 
-	// This channel allows us to indicate when K8sGPT deployment is ready for active comms
+	// The registry holds the connection of every K8sGPT deployment that is ready for active comms
 	// This is a necessity for the mutation system to work
-	ready := make(chan types.InterControllerSignal, 10)
+	clientRegistry := shared.NewClientRegistry()
+
+	// Targets of mutations are validated against the schemas the API server publishes
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(mgr.GetConfig())
+	if err != nil {
+		setupLog.Error(err, "unable to create discovery client")
+		os.Exit(1)
+	}
 
 	if err = (&mutation.MutationReconciler{
 		Client:         mgr.GetClient(),
 		Scheme:         mgr.GetScheme(),
 		MetricsBuilder: metricsBuilder,
+		ClientRegistry: clientRegistry,
+		Recorder:       mgr.GetEventRecorderFor("mutation-controller"),
+		Schemas:        openapicached.NewClient(discoveryClient.OpenAPIV3()),
+		APIReader:      mgr.GetAPIReader(),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Mutation")
 		os.Exit(1)
@@ -147,15 +160,24 @@ func main() {
 	if err = (&k8sgpt.K8sGPTReconciler{
 		Client:              mgr.GetClient(),
 		Scheme:              mgr.GetScheme(),
-		Signal:              ready,
+		ClientRegistry:      clientRegistry,
 		Integrations:        integration,
 		SinkClient:          sinkClient,
 		MetricsBuilder:      metricsBuilder,
 		EnableResultLogging: enableResultLogging,
+		Recorder:            mgr.GetEventRecorderFor("k8sgpt-controller"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "K8sGPT")
 		os.Exit(1)
 	}
+
+	if err = mgr.Add(&k8sgpt.ResultGarbageCollector{
+		Client:         mgr.GetClient(),
+		MetricsBuilder: metricsBuilder,
+	}); err != nil {
+		setupLog.Error(err, "unable to add result garbage collector")
+		os.Exit(1)
+	}
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -0,0 +1,342 @@
+package conversions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+// ErrRemovedField is returned when a fix leaves out fields of an object that is updated in
+// place, server-side apply would keep them
+var ErrRemovedField = errors.New("fix removes fields that cannot be removed by server-side apply:")
+
+// ErrApplyConflict is returned when applying a fix would take over fields owned by another
+// field manager and force apply is not enabled
+var ErrApplyConflict = errors.New("field manager conflict")
+
+// applyRules restrict the fields applied to kinds that are updated in place to the fields the
+// kind allows to be updated
+var applyRules = map[string]func(live, owned *unstructured.Unstructured) error{
+	"Deployment":  immutableFields("selector"),
+	"Service":     serviceRule,
+	"StatefulSet": statefulSetRule,
+	"DaemonSet":   immutableFields("selector"),
+}
+
+// ApplyObject returns the object applied for a target: the identity of the target and the
+// fields the fix changes. The fix is measured against the origin configuration when it has the
+// kind of the target, the backend was asked to change that, and against the live object otherwise.
+func ApplyObject(origin string, live, target *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	base := applyBase(origin, live, target)
+	var baseContent map[string]interface{}
+	if base != nil {
+		baseContent = base.Object
+	}
+
+	owned := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	for field, value := range ownedFields(baseContent, target.Object) {
+		if field != "apiVersion" && field != "kind" && field != "metadata" {
+			owned.Object[field] = value
+		}
+	}
+	for _, field := range []string{"labels", "annotations"} {
+		desired, _, _ := unstructured.NestedMap(target.Object, "metadata", field)
+		current, _, _ := unstructured.NestedMap(baseContent, "metadata", field)
+		if changed := ownedFields(current, desired); len(changed) > 0 {
+			if err := unstructured.SetNestedMap(owned.Object, changed, "metadata", field); err != nil {
+				return nil, err
+			}
+		}
+	}
+	owned.SetGroupVersionKind(target.GroupVersionKind())
+	owned.SetName(target.GetName())
+	owned.SetNamespace(target.GetNamespace())
+
+	// Server-side apply only removes fields the remediation field manager owns, a fix that removes
+	// fields cannot be applied
+	if removed := removedFields(target.GetKind(), baseContent, target.Object); len(removed) > 0 {
+		return nil, fmt.Errorf("%s %s/%s: %w %s", target.GetKind(), target.GetNamespace(), target.GetName(),
+			ErrRemovedField, strings.Join(removed, ", "))
+	}
+
+	if rule, ok := applyRules[target.GetKind()]; ok && live != nil {
+		if err := rule(live, owned); err != nil {
+			return nil, fmt.Errorf("%s %s/%s: %w", target.GetKind(), target.GetNamespace(), target.GetName(), err)
+		}
+	}
+	return owned, nil
+}
+
+// applyBase returns what a fix is measured against: the origin configuration when it has the
+// kind of the target, otherwise the live object, which is nil when it does not exist
+func applyBase(origin string, live, target *unstructured.Unstructured) *unstructured.Unstructured {
+	var originObj unstructured.Unstructured
+	if err := yaml.Unmarshal([]byte(origin), &originObj.Object); err == nil && originObj.Object != nil &&
+		originObj.GetKind() == target.GetKind() {
+		return &originObj
+	}
+	return live
+}
+
+// HasChanges reports whether an object returned by ApplyObject changes anything
+func HasChanges(owned *unstructured.Unstructured) bool {
+	for field := range owned.Object {
+		if field != "apiVersion" && field != "kind" && field != "metadata" {
+			return true
+		}
+	}
+	_, labels, _ := unstructured.NestedMap(owned.Object, "metadata", "labels")
+	_, annotations, _ := unstructured.NestedMap(owned.Object, "metadata", "annotations")
+	return labels || annotations
+}
+
+// allocatedFields are filled in by the API server, a target that leaves them out keeps them
+var allocatedFields = map[string][]string{
+	"Service": {"spec.clusterIP", "spec.clusterIPs", "spec.ipFamilies", "spec.ipFamilyPolicy",
+		"spec.healthCheckNodePort"},
+	// Changes to the volume claim templates are not applied, see statefulSetRule
+	"StatefulSet": {"spec.volumeClaimTemplates"},
+}
+
+// removedFields returns the paths of the fields of base a fix leaves out of desired, e.g.
+// spec.template.spec.containers[web].env[DEBUG]. Of the metadata only the labels are compared,
+// annotations are mostly written by tools. The status, empty fields and the fields the API
+// server allocates for the kind are not compared either.
+func removedFields(kind string, base, desired map[string]interface{}) []string {
+	comparable := func(content map[string]interface{}) map[string]interface{} {
+		fields := map[string]interface{}{}
+		for field, value := range content {
+			if field != "apiVersion" && field != "kind" && field != "metadata" && field != "status" {
+				fields[field] = value
+			}
+		}
+		labels, _, _ := unstructured.NestedFieldNoCopy(content, "metadata", "labels")
+		fields["metadata"] = map[string]interface{}{"labels": labels}
+		return fields
+	}
+	removed := slices.DeleteFunc(missingFields("", comparable(base), comparable(desired)), func(path string) bool {
+		return slices.ContainsFunc(allocatedFields[kind], func(allocated string) bool {
+			return path == allocated || strings.HasPrefix(path, allocated+".") || strings.HasPrefix(path, allocated+"[")
+		})
+	})
+	slices.Sort(removed)
+	return removed
+}
+
+// missingFields returns the paths of the fields of base that desired does not have. Lists of
+// named objects are compared element by element, other lists are replaced as a whole.
+func missingFields(path string, base, desired map[string]interface{}) []string {
+	var missing []string
+	for field, value := range base {
+		if isEmptyField(value) {
+			continue
+		}
+		fieldPath := field
+		if path != "" {
+			fieldPath = path + "." + field
+		}
+		current, ok := desired[field]
+		if !ok || current == nil {
+			missing = append(missing, fieldPath)
+			continue
+		}
+		switch value := value.(type) {
+		case map[string]interface{}:
+			if currentMap, ok := current.(map[string]interface{}); ok {
+				missing = append(missing, missingFields(fieldPath, value, currentMap)...)
+			}
+		case []interface{}:
+			if currentList, ok := current.([]interface{}); ok {
+				missing = append(missing, missingNamedElements(fieldPath, value, currentList)...)
+			}
+		}
+	}
+	return missing
+}
+
+func missingNamedElements(path string, base, desired []interface{}) []string {
+	named := func(list []interface{}) (map[string]map[string]interface{}, bool) {
+		elements := map[string]map[string]interface{}{}
+		for _, element := range list {
+			element, ok := element.(map[string]interface{})
+			name, hasName := element["name"].(string)
+			if !ok || !hasName {
+				return nil, false
+			}
+			elements[name] = element
+		}
+		return elements, true
+	}
+	baseElements, ok := named(base)
+	if !ok {
+		return nil
+	}
+	desiredElements, ok := named(desired)
+	if !ok {
+		return nil
+	}
+	var missing []string
+	for name, element := range baseElements {
+		elementPath := fmt.Sprintf("%s[%s]", path, name)
+		if current, ok := desiredElements[name]; ok {
+			missing = append(missing, missingFields(elementPath, element, current)...)
+		} else {
+			missing = append(missing, elementPath)
+		}
+	}
+	return missing
+}
+
+func isEmptyField(value interface{}) bool {
+	switch value := value.(type) {
+	case nil:
+		return true
+	case map[string]interface{}:
+		return len(value) == 0
+	case []interface{}:
+		return len(value) == 0
+	case string:
+		return value == ""
+	}
+	return false
+}
+
+// ownedFields returns the fields of desired that are added or changed relative to base. Lists
+// of named objects, such as containers, are compared element by element and keep the name of
+// each changed element; other lists are taken as a whole.
+func ownedFields(base, desired map[string]interface{}) map[string]interface{} {
+	owned := map[string]interface{}{}
+	for field, value := range desired {
+		if value == nil {
+			continue
+		}
+		current := base[field]
+		if apiequality.Semantic.DeepEqual(current, value) {
+			continue
+		}
+		switch value := value.(type) {
+		case map[string]interface{}:
+			currentMap, _ := current.(map[string]interface{})
+			if changed := ownedFields(currentMap, value); len(changed) > 0 {
+				owned[field] = changed
+			}
+		case []interface{}:
+			currentList, _ := current.([]interface{})
+			if changed, ok := ownedNamedElements(currentList, value); ok {
+				if len(changed) > 0 {
+					owned[field] = changed
+				}
+				continue
+			}
+			owned[field] = value
+		default:
+			owned[field] = value
+		}
+	}
+	return owned
+}
+
+func ownedNamedElements(base, desired []interface{}) ([]interface{}, bool) {
+	named := map[string]map[string]interface{}{}
+	for _, element := range base {
+		element, ok := element.(map[string]interface{})
+		name, hasName := element["name"].(string)
+		if !ok || !hasName {
+			return nil, false
+		}
+		named[name] = element
+	}
+	changed := []interface{}{}
+	for _, element := range desired {
+		element, ok := element.(map[string]interface{})
+		name, hasName := element["name"].(string)
+		if !ok || !hasName {
+			return nil, false
+		}
+		fields := ownedFields(named[name], element)
+		if len(fields) > 0 {
+			fields["name"] = name
+			changed = append(changed, fields)
+		}
+	}
+	return changed, true
+}
+
+// immutableFields returns an apply rule refusing changes to the spec fields
+func immutableFields(fields ...string) func(live, owned *unstructured.Unstructured) error {
+	return func(live, owned *unstructured.Unstructured) error {
+		return checkImmutable(live, owned, fields...)
+	}
+}
+
+// serviceRule never applies the cluster IPs of a service, they are allocated by the API server
+func serviceRule(_, owned *unstructured.Unstructured) error {
+	unstructured.RemoveNestedField(owned.Object, "spec", "clusterIP")
+	unstructured.RemoveNestedField(owned.Object, "spec", "clusterIPs")
+	return nil
+}
+
+// statefulSetMutableFields are the spec fields of a stateful set that can be updated
+var statefulSetMutableFields = []string{"replicas", "template", "updateStrategy", "minReadySeconds",
+	"revisionHistoryLimit", "persistentVolumeClaimRetentionPolicy", "ordinals"}
+
+// statefulSetRule refuses changes to the identity of a stateful set and drops changes to its
+// volume claim templates, which cannot be updated and whose defaulted fields do not round trip
+func statefulSetRule(live, owned *unstructured.Unstructured) error {
+	if err := checkImmutable(live, owned, "selector", "serviceName", "podManagementPolicy"); err != nil {
+		return err
+	}
+	spec, found, _ := unstructured.NestedMap(owned.Object, "spec")
+	if !found {
+		return nil
+	}
+	for field := range spec {
+		if !slices.Contains(statefulSetMutableFields, field) {
+			unstructured.RemoveNestedField(owned.Object, "spec", field)
+		}
+	}
+	if spec, _, _ := unstructured.NestedMap(owned.Object, "spec"); len(spec) == 0 {
+		unstructured.RemoveNestedField(owned.Object, "spec")
+	}
+	return nil
+}
+
+// checkImmutable returns ErrImmutableField when the object sets a spec field to a value other
+// than the live one
+func checkImmutable(live, obj *unstructured.Unstructured, fields ...string) error {
+	for _, field := range fields {
+		desired, found, _ := unstructured.NestedFieldNoCopy(obj.Object, "spec", field)
+		if !found {
+			continue
+		}
+		current, _, _ := unstructured.NestedFieldNoCopy(live.Object, "spec", field)
+		if !apiequality.Semantic.DeepEqual(current, desired) {
+			return fmt.Errorf("%w spec.%s", ErrImmutableField, field)
+		}
+	}
+	return nil
+}
+
+// Apply server-side applies the object under the remediation field manager. Without force a
+// conflict with another field manager returns ErrApplyConflict and the conflicting fields.
+func Apply(ctx context.Context, c client.Client, obj *unstructured.Unstructured, force bool) ([]string, error) {
+	opts := []client.PatchOption{client.FieldOwner(RemediationFieldManager)}
+	if force {
+		opts = append(opts, client.ForceOwnership)
+	}
+	if err := c.Patch(ctx, obj, client.Apply, opts...); err != nil {
+		if apierrors.IsConflict(err) {
+			return ValidationErrors(err), fmt.Errorf("%w: %v", ErrApplyConflict, err)
+		}
+		return nil, err
+	}
+	return nil, nil
+}
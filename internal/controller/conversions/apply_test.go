@@ -0,0 +1,99 @@
+package conversions
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func Test_ApplyObjectOwnsChangedFields(t *testing.T) {
+	origin := newDeployment("nginx:1.0")
+	origin.Spec.Template.Spec.Containers = append(origin.Spec.Template.Spec.Containers,
+		corev1.Container{Name: "sidecar", Image: "envoy:1.0"})
+	originManifest, err := ToManifest(toPrevious(t, origin))
+	require.NoError(t, err)
+
+	// An autoscaler has scaled the deployment since the backend saw it
+	live := origin.DeepCopy()
+	replicas := int32(5)
+	live.Spec.Replicas = &replicas
+
+	// The backend echoes the origin with a new image for one container and a new label
+	target := origin.DeepCopy()
+	target.Labels = map[string]string{"fixed-by": "k8sgpt"}
+	target.Spec.Template.Spec.Containers[0].Image = "nginx:1.1"
+
+	owned, err := ApplyObject(originManifest, toPrevious(t, live), toPrevious(t, target))
+	require.NoError(t, err)
+	assert.True(t, HasChanges(owned))
+	assert.Equal(t, map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata": map[string]interface{}{
+			"name":      "web",
+			"namespace": "default",
+			"labels":    map[string]interface{}{"fixed-by": "k8sgpt"},
+		},
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"containers": []interface{}{map[string]interface{}{"name": "web", "image": "nginx:1.1"}},
+				},
+			},
+		},
+	}, owned.Object)
+
+	// Without an origin of the same kind the fix is measured against the live object
+	owned, err = ApplyObject("kind: Pod\napiVersion: v1\n", toPrevious(t, live), toPrevious(t, live))
+	require.NoError(t, err)
+	assert.False(t, HasChanges(owned))
+}
+
+func Test_ApplyObjectRules(t *testing.T) {
+	changedSelector := newDeployment("nginx:1.0")
+	changedSelector.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "other"}}
+	_, err := ApplyObject("", toPrevious(t, newDeployment("nginx:1.0")), toPrevious(t, changedSelector))
+	assert.ErrorIs(t, err, ErrImmutableField)
+
+	live := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1", "kind": "Service",
+		"metadata": map[string]interface{}{"name": "web", "namespace": "default"},
+		"spec":     map[string]interface{}{"clusterIP": "10.0.0.10", "selector": map[string]interface{}{"app": "wbe"}},
+	}}
+	target := live.DeepCopy()
+	require.NoError(t, unstructured.SetNestedField(target.Object, "10.0.0.99", "spec", "clusterIP"))
+	require.NoError(t, unstructured.SetNestedField(target.Object, "web", "spec", "selector", "app"))
+	owned, err := ApplyObject("", live, target)
+	require.NoError(t, err)
+	spec, _, _ := unstructured.NestedMap(owned.Object, "spec")
+	assert.Equal(t, map[string]interface{}{"selector": map[string]interface{}{"app": "web"}}, spec)
+}
+
+func Test_ApplyObjectRemovedFields(t *testing.T) {
+	origin := newDeployment("nginx:1.0")
+	origin.Spec.Template.Spec.Containers[0].Args = []string{"--bad-flag"}
+	origin.Spec.Template.Spec.Containers[0].Env = []corev1.EnvVar{{Name: "MODE", Value: "prod"}, {Name: "DEBUG", Value: "1"}}
+	origin.Annotations = map[string]string{"deployment.kubernetes.io/revision": "3"}
+	originManifest, err := ToManifest(toPrevious(t, origin))
+	require.NoError(t, err)
+
+	// The fix drops the bad flag and an env entry, and the annotations the backend does not care about
+	target := origin.DeepCopy()
+	target.Annotations = nil
+	target.Spec.Template.Spec.Containers[0].Args = nil
+	target.Spec.Template.Spec.Containers[0].Env = target.Spec.Template.Spec.Containers[0].Env[:1]
+	_, err = ApplyObject(originManifest, toPrevious(t, origin), toPrevious(t, target))
+	assert.ErrorIs(t, err, ErrRemovedField)
+	assert.ErrorContains(t, err, "spec.template.spec.containers[web].args, spec.template.spec.containers[web].env[DEBUG]")
+
+	// Leaving out annotations is not a removal
+	target = origin.DeepCopy()
+	target.Annotations = nil
+	target.Spec.Template.Spec.Containers[0].Image = "nginx:1.1"
+	_, err = ApplyObject(originManifest, toPrevious(t, origin), toPrevious(t, target))
+	assert.NoError(t, err)
+}
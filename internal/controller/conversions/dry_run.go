@@ -0,0 +1,137 @@
+package conversions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/k8sgpt-ai/k8sgpt-operator/internal/controller/util"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+// RemediationFieldManager is the field manager the operator applies remediations with
+const RemediationFieldManager = "k8sgpt-remediation"
+
+// ToApplyObject converts a resolved target into an unstructured object that can be applied,
+// dropping the server populated metadata and status the backend may have echoed back
+func ToApplyObject(target client.Object) (*unstructured.Unstructured, error) {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(target)
+	if err != nil {
+		return nil, err
+	}
+	obj := &unstructured.Unstructured{Object: content}
+	obj.SetGroupVersionKind(target.GetObjectKind().GroupVersionKind())
+	stripServerFields(obj)
+	return obj, nil
+}
+
+// ToManifest renders an object as YAML without server populated fields, nil renders as empty
+func ToManifest(obj *unstructured.Unstructured) (string, error) {
+	if obj == nil {
+		return "", nil
+	}
+	clean := obj.DeepCopy()
+	stripServerFields(clean)
+	data, err := yaml.Marshal(clean.Object)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// FromManifest parses a manifest produced by ToManifest
+func FromManifest(manifest string) (*unstructured.Unstructured, error) {
+	obj := &unstructured.Unstructured{}
+	if err := yaml.Unmarshal([]byte(manifest), &obj.Object); err != nil {
+		return nil, err
+	}
+	if obj.GetKind() == "" || obj.GetAPIVersion() == "" {
+		return nil, errors.New("manifest has no apiVersion or kind")
+	}
+	return obj, nil
+}
+
+func stripServerFields(obj *unstructured.Unstructured) {
+	for _, field := range []string{"resourceVersion", "uid", "generation", "creationTimestamp",
+		"deletionTimestamp", "managedFields", "selfLink"} {
+		unstructured.RemoveNestedField(obj.Object, "metadata", field)
+	}
+	unstructured.RemoveNestedField(obj.Object, "status")
+}
+
+// DryRunApply server-side applies the target in dry-run mode and returns the unified diff
+// between the live object and the object the API server would persist. Objects updated in place
+// are dry-run with the fields the fix changes, as they are applied. Rejections from validation,
+// admission or conflicts with other field managers are returned as validation errors so a
+// reviewer can see them; err is only set when the dry-run itself could not be performed.
+func DryRunApply(ctx context.Context, c client.Client, target *unstructured.Unstructured,
+	origin string, force bool) (string, []string, error) {
+	live := &unstructured.Unstructured{}
+	live.SetGroupVersionKind(target.GroupVersionKind())
+	if err := c.Get(ctx, client.ObjectKeyFromObject(target), live); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return "", nil, err
+		}
+		live = nil
+	}
+
+	applied := target.DeepCopy()
+	if live != nil && !slices.Contains(recreatedKinds, target.GetKind()) {
+		owned, err := ApplyObject(origin, live, target)
+		if errors.Is(err, ErrImmutableField) || errors.Is(err, ErrRemovedField) {
+			return "", []string{err.Error()}, nil
+		}
+		if err != nil {
+			return "", nil, err
+		}
+		applied = owned
+	}
+	opts := []client.PatchOption{client.DryRunAll, client.FieldOwner(RemediationFieldManager)}
+	if force {
+		opts = append(opts, client.ForceOwnership)
+	}
+	if err := c.Patch(ctx, applied, client.Apply, opts...); err != nil {
+		if apierrors.IsInvalid(err) || apierrors.IsBadRequest(err) ||
+			apierrors.IsForbidden(err) || apierrors.IsConflict(err) {
+			return "", ValidationErrors(err), nil
+		}
+		return "", nil, err
+	}
+
+	from, err := ToManifest(live)
+	if err != nil {
+		return "", nil, err
+	}
+	to, err := ToManifest(applied)
+	if err != nil {
+		return "", nil, err
+	}
+	name := fmt.Sprintf("%s/%s/%s", target.GetKind(), target.GetNamespace(), target.GetName())
+	diff, err := util.UnifiedDiff(from, to, "live/"+name, "planned/"+name)
+	if err != nil {
+		return "", nil, err
+	}
+	return diff, nil, nil
+}
+
+// ValidationErrors flattens the causes of an API status error into field: message strings
+func ValidationErrors(err error) []string {
+	var status apierrors.APIStatus
+	if errors.As(err, &status) && status.Status().Details != nil && len(status.Status().Details.Causes) > 0 {
+		var messages []string
+		for _, cause := range status.Status().Details.Causes {
+			if cause.Field == "" {
+				messages = append(messages, cause.Message)
+				continue
+			}
+			messages = append(messages, fmt.Sprintf("%s: %s", cause.Field, cause.Message))
+		}
+		return messages
+	}
+	return []string{err.Error()}
+}
@@ -0,0 +1,77 @@
+package conversions
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/k8sgpt-ai/k8sgpt-operator/internal/controller/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func Test_PlannedManifestRoundTrip(t *testing.T) {
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "web",
+			Namespace:       "default",
+			ResourceVersion: "42",
+			UID:             "1234",
+			ManagedFields:   []metav1.ManagedFieldsEntry{{Manager: "kubectl"}},
+		},
+		Status: appsv1.DeploymentStatus{Replicas: 3},
+	}
+	deployment.SetGroupVersionKind(appsv1.SchemeGroupVersion.WithKind("Deployment"))
+
+	obj, err := ToApplyObject(deployment)
+	require.NoError(t, err)
+	assert.Empty(t, obj.GetResourceVersion())
+	assert.Empty(t, obj.GetUID())
+	assert.Empty(t, obj.GetManagedFields())
+	assert.NotContains(t, obj.Object, "status")
+
+	manifest, err := ToManifest(obj)
+	require.NoError(t, err)
+	parsed, err := FromManifest(manifest)
+	require.NoError(t, err)
+	assert.Equal(t, "apps/v1", parsed.GetAPIVersion())
+	assert.Equal(t, "Deployment", parsed.GetKind())
+	assert.Equal(t, "web", parsed.GetName())
+
+	_, err = FromManifest("metadata:\n  name: web\n")
+	assert.Error(t, err)
+}
+
+func Test_ValidationErrors(t *testing.T) {
+	invalid := apierrors.NewInvalid(schema.GroupKind{Group: "apps", Kind: "Deployment"}, "web", field.ErrorList{
+		field.Required(field.NewPath("spec", "selector"), ""),
+	})
+	messages := ValidationErrors(invalid)
+	require.Len(t, messages, 1)
+	assert.Contains(t, messages[0], "spec.selector")
+
+	assert.Equal(t, []string{"boom"}, ValidationErrors(errors.New("boom")))
+}
+
+func Test_UnifiedDiff(t *testing.T) {
+	diff, err := util.UnifiedDiff("image: nginx:1.0\n", "image: nginx:1.1\n", "live", "planned")
+	require.NoError(t, err)
+	assert.Contains(t, diff, "-image: nginx:1.0")
+	assert.Contains(t, diff, "+image: nginx:1.1")
+}
+
+func Test_FromConfigParsesStoredGVK(t *testing.T) {
+	obj, err := util.FromConfig(util.FromObjectConfig{
+		Kind:      "Deployment",
+		GvkStr:    "apps/v1, Kind=Deployment",
+		Config:    "metadata:\n  name: web\n",
+		Name:      "web",
+		Namespace: "default",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, appsv1.SchemeGroupVersion.WithKind("Deployment"), obj.GetObjectKind().GroupVersionKind())
+}
@@ -0,0 +1,131 @@
+package conversions
+
+import (
+	"errors"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Executor writes a target returned by ResolveTarget to the cluster. It reports whether the
+// target is in place; executors that recreate objects take several calls to get there.
+type Executor func(config ObjectExecutionConfig, target client.Object) (bool, error)
+
+// Executors holds the executor of every kind auto remediation can write. Kinds without an
+// executor fail with ErrUnsupportedKind.
+var Executors = map[string]Executor{
+	"Pod": func(config ObjectExecutionConfig, target client.Object) (bool, error) {
+		config.Obj = target
+		return staticPodExecution(config)
+	},
+	"Deployment":  applyExecution,
+	"Service":     applyExecution,
+	"Ingress":     applyExecution,
+	"StatefulSet": applyExecution,
+	"DaemonSet":   applyExecution,
+	"Job":         jobExecution,
+	"CronJob":     applyExecution,
+}
+
+// recreatedKinds are deleted and created again rather than updated in place
+var recreatedKinds = []string{"Pod", "Job"}
+
+// ErrImmutableField is returned by executors when the target changes a field its kind does not
+// allow to be updated
+var ErrImmutableField = errors.New("immutable field changed")
+
+// applyExecution server-side applies the fields a fix changes to an object that is updated in
+// place. Conflicts with other field managers are recorded on the mutation.
+func applyExecution(config ObjectExecutionConfig, target client.Object) (bool, error) {
+	desired, err := ToApplyObject(target)
+	if err != nil {
+		return false, err
+	}
+	live := &unstructured.Unstructured{}
+	live.SetGroupVersionKind(desired.GroupVersionKind())
+	if err := config.Rc.Get(config.Ctx, client.ObjectKeyFromObject(desired), live); err != nil {
+		config.Log.Error(err, "unable to get object", "kind", desired.GetKind(), "object", desired.GetName())
+		return false, err
+	}
+	owned, err := ApplyObject(config.Mutation.Spec.OriginConfiguration, live, desired)
+	if err != nil {
+		return false, err
+	}
+	if !HasChanges(owned) {
+		config.Log.Info("Fix does not change the object, nothing to apply", "kind", desired.GetKind(), "object", desired.GetName())
+		return true, nil
+	}
+	if err := snapshot(&config, live); err != nil {
+		config.Log.Error(err, "unable to snapshot object", "kind", desired.GetKind(), "object", desired.GetName())
+		return false, err
+	}
+	conflicts, err := Apply(config.Ctx, config.Rc, owned, config.ForceApply)
+	config.Mutation.Status.Conflicts = conflicts
+	if err != nil {
+		config.Log.Error(err, "unable to apply object", "kind", desired.GetKind(), "object", desired.GetName())
+		return false, err
+	}
+	config.Log.Info("Successfully applied object", "kind", desired.GetKind(), "object", desired.GetName())
+	return true, nil
+}
+
+// jobExecution recreates a job, as the pod template of a job cannot be updated. The job is
+// deleted with its pods first and created from the target on a later call.
+func jobExecution(config ObjectExecutionConfig, target client.Object) (bool, error) {
+	desired, err := ToApplyObject(target)
+	if err != nil {
+		return false, err
+	}
+	live := &unstructured.Unstructured{}
+	live.SetGroupVersionKind(desired.GroupVersionKind())
+	if err := config.Rc.Get(config.Ctx, client.ObjectKeyFromObject(desired), live); err != nil {
+		if !apierrors.IsNotFound(err) {
+			config.Log.Error(err, "unable to get job", "job", desired.GetName())
+			return false, err
+		}
+		if err := config.Rc.Create(config.Ctx, recreatable(desired), client.FieldOwner(RemediationFieldManager)); err != nil {
+			config.Log.Error(err, "unable to create job", "job", desired.GetName())
+			return false, err
+		}
+		config.Log.Info("Successfully recreated job", "job", desired.GetName())
+		return true, nil
+	}
+	if live.GetDeletionTimestamp() != nil {
+		config.Log.Info("Job has a deletion timestamp, it is being deleted", "job", desired.GetName())
+		return false, nil
+	}
+	// Keep the job as it was so that it can be restored on rollback
+	if err := snapshot(&config, live); err != nil {
+		config.Log.Error(err, "unable to snapshot job", "job", desired.GetName())
+		return false, err
+	}
+	if err := config.Rc.Delete(config.Ctx, live, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil {
+		config.Log.Error(err, "unable to delete job", "job", desired.GetName())
+		return false, err
+	}
+	return false, nil
+}
+
+// jobGeneratedLabels are added to the pod template by the API server when it generates the
+// selector of a job, they name the UID of the job and must not be sent when it is recreated
+var jobGeneratedLabels = []string{"controller-uid", "batch.kubernetes.io/controller-uid",
+	"job-name", "batch.kubernetes.io/job-name"}
+
+// recreatable returns a copy of the object that can be created again after it was deleted
+func recreatable(obj *unstructured.Unstructured) *unstructured.Unstructured {
+	obj = obj.DeepCopy()
+	stripServerFields(obj)
+	if obj.GetKind() != "Job" {
+		return obj
+	}
+	if manual, _, _ := unstructured.NestedBool(obj.Object, "spec", "manualSelector"); manual {
+		return obj
+	}
+	unstructured.RemoveNestedField(obj.Object, "spec", "selector")
+	for _, label := range jobGeneratedLabels {
+		unstructured.RemoveNestedField(obj.Object, "spec", "template", "metadata", "labels", label)
+	}
+	return obj
+}
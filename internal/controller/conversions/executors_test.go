@@ -0,0 +1,169 @@
+package conversions
+
+import (
+	"context"
+	"testing"
+
+	schemav1 "buf.build/gen/go/k8sgpt-ai/k8sgpt/protocolbuffers/go/schema/v1"
+	"github.com/go-logr/logr"
+	corev1alpha1 "github.com/k8sgpt-ai/k8sgpt-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// The executors apply fixes with server-side apply, they are tested against the API server in the
+// envtest suite of the k8sgpt controller
+
+func executorConfig(c client.Client) (ObjectExecutionConfig, *corev1alpha1.Mutation) {
+	mutation := &corev1alpha1.Mutation{}
+	return ObjectExecutionConfig{Ctx: context.Background(), Rc: c, Log: logr.Discard(), Mutation: mutation}, mutation
+}
+
+// targetOf mimics a target configuration returned by the backend for the object
+func targetOf(t *testing.T, obj client.Object, edit func(spec map[string]interface{})) *unstructured.Unstructured {
+	target, err := ToApplyObject(obj)
+	require.NoError(t, err)
+	spec, _, _ := unstructured.NestedMap(target.Object, "spec")
+	edit(spec)
+	require.NoError(t, unstructured.SetNestedMap(target.Object, spec, "spec"))
+	return target
+}
+
+func webPodSpec(image string) corev1.PodSpec {
+	return corev1.PodSpec{
+		RestartPolicy: corev1.RestartPolicyNever,
+		Containers:    []corev1.Container{{Name: "web", Image: image}},
+	}
+}
+
+func setImage(image string) func(spec map[string]interface{}) {
+	return func(spec map[string]interface{}) {
+		containers, _, _ := unstructured.NestedSlice(spec, "template", "spec", "containers")
+		containers[0].(map[string]interface{})["image"] = image
+		_ = unstructured.SetNestedSlice(spec, containers, "template", "spec", "containers")
+	}
+}
+
+func Test_ResolveTargetUsesExecutors(t *testing.T) {
+	target := &unstructured.Unstructured{}
+	target.SetAPIVersion("batch/v1")
+	target.SetKind("CronJob")
+	resolved, err := ResolveTarget(ObjectExecutionConfig{Obj: target, Log: logr.Discard()})
+	require.NoError(t, err)
+	assert.Same(t, target, resolved)
+
+	target.SetKind("ConfigMap")
+	_, err = ResolveTarget(ObjectExecutionConfig{Obj: target, Log: logr.Discard()})
+	assert.ErrorIs(t, err, ErrUnsupportedKind)
+}
+
+// queryStub answers every backend query with the same response
+type queryStub struct {
+	response string
+	queries  int
+}
+
+func (q *queryStub) Query(_ context.Context, _ *schemav1.QueryRequest, _ ...grpc.CallOption) (*schemav1.QueryResponse, error) {
+	q.queries++
+	return &schemav1.QueryResponse{Response: q.response}, nil
+}
+
+// controlledPod returns a pod of the web workload controlled by owner
+func controlledPod(owner client.Object, kind string) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web-0"},
+		Spec:       webPodSpec("nginx:broken"),
+	}
+	pod.OwnerReferences = []metav1.OwnerReference{*metav1.NewControllerRef(owner, appsv1.SchemeGroupVersion.WithKind(kind))}
+	return pod
+}
+
+func podTarget(pod *corev1.Pod) *unstructured.Unstructured {
+	target := &unstructured.Unstructured{}
+	target.SetAPIVersion("v1")
+	target.SetKind("Pod")
+	target.SetNamespace(pod.Namespace)
+	target.SetName(pod.Name)
+	return target
+}
+
+func Test_ResolveTargetOfStatefulSetPod(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	statefulSet := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web", UID: "web-uid"},
+		Spec: appsv1.StatefulSetSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+			Template: corev1.PodTemplateSpec{Spec: webPodSpec("nginx:broken")},
+		},
+	}
+	pod := controlledPod(statefulSet, "StatefulSet")
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(statefulSet, pod).Build()
+
+	query := &queryStub{response: `apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: web
+  namespace: default
+spec:
+  template:
+    spec:
+      containers:
+      - name: web
+        image: nginx:1.1
+`}
+	config, mutation := executorConfig(c)
+	config.Obj = podTarget(pod)
+	config.QueryClient = query
+	resolved, err := ResolveTarget(config)
+	require.NoError(t, err)
+	assert.Equal(t, 1, query.queries)
+	assert.Equal(t, appsv1.SchemeGroupVersion.WithKind("StatefulSet"), resolved.GetObjectKind().GroupVersionKind())
+	assert.Equal(t, "web", resolved.GetName())
+	assert.NotEmpty(t, mutation.Status.PromptHash)
+
+	// The workload recorded on the mutation is used without following the owner chain
+	mutation.Spec.Workload = corev1.ObjectReference{APIVersion: "apps/v1", Kind: "StatefulSet",
+		Namespace: "default", Name: "web", UID: "web-uid"}
+	resolved, err = ResolveTarget(config)
+	require.NoError(t, err)
+	assert.Equal(t, "StatefulSet", resolved.GetObjectKind().GroupVersionKind().Kind)
+
+	// A backend answering with another kind is refused
+	query.response = "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\n"
+	_, err = ResolveTarget(config)
+	var refused *GuardrailError
+	assert.ErrorAs(t, err, &refused)
+}
+
+func Test_ResolveTargetOfUnownedReplicaSetPod(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	replicaSet := &appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web-5d4f", UID: "rs-uid"}}
+	pod := controlledPod(replicaSet, "ReplicaSet")
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(replicaSet, pod).Build()
+
+	query := &queryStub{}
+	config, _ := executorConfig(c)
+	config.Obj = podTarget(pod)
+	config.QueryClient = query
+	_, err := ResolveTarget(config)
+	assert.ErrorIs(t, err, ErrUnsupportedKind)
+	assert.Zero(t, query.queries)
+
+	// A pod without a controller is recreated from the target configuration
+	pod.OwnerReferences = nil
+	require.NoError(t, c.Update(context.Background(), pod))
+	resolved, err := ResolveTarget(config)
+	require.NoError(t, err)
+	assert.Same(t, config.Obj, resolved)
+}
@@ -0,0 +1,228 @@
+package conversions
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/managedfields"
+	"k8s.io/client-go/openapi"
+	"k8s.io/kube-openapi/pkg/spec3"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// GuardrailError lists why a manifest produced by the backend is refused
+type GuardrailError struct {
+	Violations []string
+}
+
+func (e *GuardrailError) Error() string {
+	return fmt.Sprintf("guardrails refused the target: %s", strings.Join(e.Violations, "; "))
+}
+
+// CheckIdentity returns the violations of a manifest that does not keep the apiVersion, kind,
+// name and namespace of the object it was asked to fix
+func CheckIdentity(expected schema.GroupVersionKind, name string, namespace string, obj client.Object) []string {
+	var violations []string
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	if gvk.GroupVersion() != expected.GroupVersion() {
+		violations = append(violations, fmt.Sprintf("apiVersion changed from %q to %q",
+			expected.GroupVersion().String(), gvk.GroupVersion().String()))
+	}
+	if gvk.Kind != expected.Kind {
+		violations = append(violations, fmt.Sprintf("kind changed from %q to %q", expected.Kind, gvk.Kind))
+	}
+	if obj.GetName() != name {
+		violations = append(violations, fmt.Sprintf("name changed from %q to %q", name, obj.GetName()))
+	}
+	if obj.GetNamespace() != namespace {
+		violations = append(violations, fmt.Sprintf("namespace changed from %q to %q", namespace, obj.GetNamespace()))
+	}
+	return violations
+}
+
+// Guardrails checks a resolved target before it is applied: it must not escalate the privileges
+// of its pods relative to the configuration the backend was given and, when schemas is set, it
+// must validate against the OpenAPI schema the API server publishes for its kind.
+func Guardrails(config ObjectExecutionConfig, target client.Object, schemas openapi.Client) error {
+	obj, err := ToApplyObject(target)
+	if err != nil {
+		return err
+	}
+	live, err := liveObject(config, obj)
+	if err != nil {
+		return err
+	}
+	violations := CheckSecurity(applyBase(config.Mutation.Spec.OriginConfiguration, live, obj), obj)
+	if schemas != nil {
+		schemaViolations, err := CheckSchema(schemas, obj)
+		if err != nil {
+			return err
+		}
+		violations = append(violations, schemaViolations...)
+	}
+	if len(violations) > 0 {
+		return &GuardrailError{Violations: violations}
+	}
+	return nil
+}
+
+// liveObject returns the object the target replaces, or nil when it does not exist
+func liveObject(config ObjectExecutionConfig, obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	live := &unstructured.Unstructured{}
+	live.SetGroupVersionKind(obj.GroupVersionKind())
+	if err := config.Rc.Get(config.Ctx, client.ObjectKeyFromObject(obj), live); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return live, nil
+}
+
+// CheckSchema validates the object against the OpenAPI v3 schema of its group version. Unknown
+// fields and values of the wrong type are violations.
+func CheckSchema(schemas openapi.Client, obj *unstructured.Unstructured) ([]string, error) {
+	gv := obj.GroupVersionKind().GroupVersion()
+	path := "apis/" + gv.String()
+	if gv.Group == "" {
+		path = "api/" + gv.Version
+	}
+	paths, err := schemas.Paths()
+	if err != nil {
+		return nil, err
+	}
+	groupVersion, ok := paths[path]
+	if !ok {
+		return []string{fmt.Sprintf("apiVersion %q is not served by the cluster", gv.String())}, nil
+	}
+	data, err := groupVersion.Schema("application/json")
+	if err != nil {
+		return nil, err
+	}
+	var doc spec3.OpenAPI
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Components == nil {
+		return nil, fmt.Errorf("schema of %s has no components", gv.String())
+	}
+	converter, err := managedfields.NewTypeConverter(doc.Components.Schemas, false)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := converter.ObjectToTyped(obj); err != nil {
+		var violations []string
+		for _, line := range strings.Split(err.Error(), "\n") {
+			if line = strings.TrimSpace(line); line != "" && line != "errors:" {
+				violations = append(violations, "schema: "+line)
+			}
+		}
+		return violations, nil
+	}
+	return nil, nil
+}
+
+// podSpecPath returns where the pod spec of a kind is, or nil for kinds without pods
+func podSpecPath(kind string) []string {
+	switch kind {
+	case "Pod":
+		return []string{"spec"}
+	case "CronJob":
+		return []string{"spec", "jobTemplate", "spec", "template", "spec"}
+	case "Deployment", "StatefulSet", "DaemonSet", "ReplicaSet", "Job":
+		return []string{"spec", "template", "spec"}
+	}
+	return nil
+}
+
+// CheckSecurity returns the privilege escalations the target adds to the pods of base: privileged
+// containers, added capabilities or privilege escalation, hostPath volumes, hostNetwork and a
+// different service account. A nil base counts every such setting as added.
+func CheckSecurity(base, target *unstructured.Unstructured) []string {
+	path := podSpecPath(target.GetKind())
+	if path == nil {
+		return nil
+	}
+	desired, _, _ := unstructured.NestedMap(target.Object, path...)
+	var current map[string]interface{}
+	if base != nil {
+		current, _, _ = unstructured.NestedMap(base.Object, path...)
+	}
+
+	var violations []string
+	if enabled, _, _ := unstructured.NestedBool(desired, "hostNetwork"); enabled {
+		if was, _, _ := unstructured.NestedBool(current, "hostNetwork"); !was {
+			violations = append(violations, "hostNetwork enabled")
+		}
+	}
+	if account := serviceAccount(desired); account != serviceAccount(current) {
+		violations = append(violations, fmt.Sprintf("serviceAccountName changed from %q to %q", serviceAccount(current), account))
+	}
+
+	currentVolumes := namedElements(current, "volumes")
+	for name, volume := range namedElements(desired, "volumes") {
+		hostPath, found, _ := unstructured.NestedFieldNoCopy(volume, "hostPath")
+		if !found {
+			continue
+		}
+		previous, _, _ := unstructured.NestedFieldNoCopy(currentVolumes[name], "hostPath")
+		if !apiequality.Semantic.DeepEqual(previous, hostPath) {
+			violations = append(violations, fmt.Sprintf("hostPath volume %q added", name))
+		}
+	}
+
+	for _, field := range []string{"initContainers", "containers", "ephemeralContainers"} {
+		currentContainers := namedElements(current, field)
+		for name, container := range namedElements(desired, field) {
+			violations = append(violations, containerEscalations(name, currentContainers[name], container)...)
+		}
+	}
+	slices.Sort(violations)
+	return violations
+}
+
+func containerEscalations(name string, current, desired map[string]interface{}) []string {
+	var violations []string
+	for _, setting := range []string{"privileged", "allowPrivilegeEscalation"} {
+		if enabled, _, _ := unstructured.NestedBool(desired, "securityContext", setting); enabled {
+			if was, _, _ := unstructured.NestedBool(current, "securityContext", setting); !was {
+				violations = append(violations, fmt.Sprintf("container %q sets %s", name, setting))
+			}
+		}
+	}
+	added, _, _ := unstructured.NestedStringSlice(desired, "securityContext", "capabilities", "add")
+	existing, _, _ := unstructured.NestedStringSlice(current, "securityContext", "capabilities", "add")
+	for _, capability := range added {
+		if !slices.Contains(existing, capability) {
+			violations = append(violations, fmt.Sprintf("container %q adds capability %s", name, capability))
+		}
+	}
+	return violations
+}
+
+func serviceAccount(podSpec map[string]interface{}) string {
+	if account, _, _ := unstructured.NestedString(podSpec, "serviceAccountName"); account != "" {
+		return account
+	}
+	account, _, _ := unstructured.NestedString(podSpec, "serviceAccount")
+	return account
+}
+
+// namedElements indexes the objects of a list field by their name
+func namedElements(obj map[string]interface{}, field string) map[string]map[string]interface{} {
+	elements := map[string]map[string]interface{}{}
+	list, _, _ := unstructured.NestedSlice(obj, field)
+	for _, element := range list {
+		if element, ok := element.(map[string]interface{}); ok {
+			name, _ := element["name"].(string)
+			elements[name] = element
+		}
+	}
+	return elements
+}
@@ -0,0 +1,95 @@
+package conversions
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/openapi/openapitest"
+)
+
+func Test_CheckIdentity(t *testing.T) {
+	expected := appsv1.SchemeGroupVersion.WithKind("Deployment")
+	deployment := toPrevious(t, newDeployment("nginx:1.0"))
+	assert.Empty(t, CheckIdentity(expected, "web", "default", deployment))
+
+	renamed := deployment.DeepCopy()
+	renamed.SetName("other")
+	renamed.SetNamespace("kube-system")
+	renamed.SetAPIVersion("v1")
+	renamed.SetKind("Pod")
+	assert.Equal(t, []string{
+		`apiVersion changed from "apps/v1" to "v1"`,
+		`kind changed from "Deployment" to "Pod"`,
+		`name changed from "web" to "other"`,
+		`namespace changed from "default" to "kube-system"`,
+	}, CheckIdentity(expected, "web", "default", renamed))
+}
+
+func Test_CheckSecurity(t *testing.T) {
+	privileged := true
+	base := newDeployment("nginx:1.0")
+	base.Spec.Template.Spec.Containers[0].SecurityContext = &corev1.SecurityContext{
+		Capabilities: &corev1.Capabilities{Add: []corev1.Capability{"NET_BIND_SERVICE"}},
+	}
+
+	// Settings the origin already had are not escalations
+	same := base.DeepCopy()
+	same.Spec.Template.Spec.Containers[0].Image = "nginx:1.1"
+	assert.Empty(t, CheckSecurity(toPrevious(t, base), toPrevious(t, same)))
+
+	escalated := base.DeepCopy()
+	escalated.Spec.Template.Spec.HostNetwork = true
+	escalated.Spec.Template.Spec.ServiceAccountName = "admin"
+	escalated.Spec.Template.Spec.Volumes = []corev1.Volume{{Name: "root",
+		VolumeSource: corev1.VolumeSource{HostPath: &corev1.HostPathVolumeSource{Path: "/"}}}}
+	escalated.Spec.Template.Spec.Containers[0].SecurityContext = &corev1.SecurityContext{
+		Privileged: &privileged,
+		Capabilities: &corev1.Capabilities{
+			Add: []corev1.Capability{"NET_BIND_SERVICE", "SYS_ADMIN"},
+		},
+	}
+	assert.Equal(t, []string{
+		`container "web" adds capability SYS_ADMIN`,
+		`container "web" sets privileged`,
+		`hostNetwork enabled`,
+		`hostPath volume "root" added`,
+		`serviceAccountName changed from "" to "admin"`,
+	}, CheckSecurity(toPrevious(t, base), toPrevious(t, escalated)))
+
+	// Without a base every escalation counts as added
+	assert.Equal(t, []string{`container "web" adds capability NET_BIND_SERVICE`},
+		CheckSecurity(nil, toPrevious(t, base)))
+}
+
+func Test_CheckSchema(t *testing.T) {
+	schemas := openapitest.NewEmbeddedFileClient()
+
+	violations, err := CheckSchema(schemas, toPrevious(t, newDeployment("nginx:1.1")))
+	require.NoError(t, err)
+	assert.Empty(t, violations)
+
+	// The converter may stop at the first invalid field, they are checked one at a time
+	wrongType := toPrevious(t, newDeployment("nginx:1.1"))
+	require.NoError(t, unstructured.SetNestedField(wrongType.Object, "three", "spec", "replicas"))
+	violations, err = CheckSchema(schemas, wrongType)
+	require.NoError(t, err)
+	require.Len(t, violations, 1)
+	assert.Contains(t, violations[0], ".spec.replicas")
+
+	invalid := toPrevious(t, newDeployment("nginx:1.1"))
+	require.NoError(t, unstructured.SetNestedField(invalid.Object, true, "spec", "bogus"))
+	violations, err = CheckSchema(schemas, invalid)
+	require.NoError(t, err)
+	require.Len(t, violations, 1)
+	assert.Contains(t, violations[0], ".spec.bogus")
+
+	unserved := invalid.DeepCopy()
+	unserved.SetAPIVersion("apps/v1beta9")
+	violations, err = CheckSchema(schemas, unserved)
+	require.NoError(t, err)
+	assert.Equal(t, []string{`apiVersion "apps/v1beta9" is not served by the cluster`}, violations)
+}
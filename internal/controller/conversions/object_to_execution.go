@@ -4,152 +4,245 @@ import (
 	"buf.build/gen/go/k8sgpt-ai/k8sgpt/grpc/go/schema/v1/schemav1grpc"
 	schemav1 "buf.build/gen/go/k8sgpt-ai/k8sgpt/protocolbuffers/go/schema/v1"
 	"context"
+	"errors"
 	"fmt"
 	"github.com/go-logr/logr"
 	corev1alpha1 "github.com/k8sgpt-ai/k8sgpt-operator/api/v1alpha1"
 	"github.com/k8sgpt-ai/k8sgpt-operator/internal/controller/util"
 	"github.com/k8sgpt-ai/k8sgpt-operator/internal/prompts"
-	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
-	ctrl "sigs.k8s.io/controller-runtime"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/yaml"
 )
 
 type ObjectExecutionConfig struct {
-	Ctx         context.Context
-	Rc          client.Client
-	Obj         client.Object
-	Mutation    corev1alpha1.Mutation
+	Ctx context.Context
+	Rc  client.Client
+	Obj client.Object
+	// Mutation is updated in place by the executors, the caller persists its status
+	Mutation    *corev1alpha1.Mutation
 	QueryClient schemav1grpc.ServerQueryServiceClient
 	Backend     string
 	Log         logr.Logger
+	// ForceApply takes over fields owned by other field managers when a fix is applied
+	ForceApply bool
+	// Policies are the remediation policies a target must satisfy before it is applied
+	Policies []corev1alpha1.RemediationPolicy
+	// Scope is the remediation scope a target must be in before it is planned or applied
+	Scope *RemediationScope
+	// Prompts renders the queries sent to the backend
+	Prompts prompts.Templates
+	// PromptData returns the variables of the prompt templates, it is only called when the backend is queried
+	PromptData func() prompts.Data
+}
+
+// RecordQuery stores the template version and hashes of a backend query on the mutation,
+// the next history entry picks them up
+func RecordQuery(mutation *corev1alpha1.Mutation, templateVersion string, prompt string, response string) {
+	mutation.Status.TemplateVersion = templateVersion
+	mutation.Status.PromptHash = util.Hash(prompt)
+	mutation.Status.ResponseHash = util.Hash(response)
 }
 
 // The purpose of this file is to give explicit execution steps depending on the resource type
-// E.g., A static pod can only be deleted/created, where as a deployment can be patched
+// E.g., A static pod can only be deleted/created, where as a deployment is server-side applied
 // the supported types within this file must style in alignment with the to_eligible_resources.go file.
+// Executors report whether the target is in place; they may take several calls to get there.
 
-func staticPodExecution(config ObjectExecutionConfig) (ctrl.Result, error) {
+func staticPodExecution(config ObjectExecutionConfig) (bool, error) {
 	// check if the object exists first
+	live := &unstructured.Unstructured{}
+	live.SetGroupVersionKind(config.Obj.GetObjectKind().GroupVersionKind())
 	if err := config.Rc.Get(config.Ctx, client.ObjectKey{Name: config.Obj.GetName(),
-		Namespace: config.Obj.GetNamespace()}, config.Obj); err != nil {
+		Namespace: config.Obj.GetNamespace()}, live); err != nil {
+		if !apierrors.IsNotFound(err) {
+			config.Log.Error(err, "unable to get object", "object", config.Obj.GetName())
+			return false, err
+		}
 		// If the object doesn't exist at this point, we should create it based on the targetConfiguration
-		if err := config.Rc.Create(config.Ctx, config.Obj); err != nil {
+		if err := config.Rc.Create(config.Ctx, config.Obj, client.FieldOwner(RemediationFieldManager)); err != nil {
 			config.Log.Error(err, "unable to create object", "object", config.Obj.GetName())
-			return ctrl.Result{RequeueAfter: util.ErrorRequeueTime}, err
+			return false, err
 		}
 		config.Log.Info("Successfully updated object", "object", config.Obj.GetName())
-		config.Mutation.Status.Phase = corev1alpha1.AutoRemediationPhaseCompleted
-		config.Mutation.Status.Message = "Completed"
-		if err := config.Rc.Update(config.Ctx, &config.Mutation); err != nil {
-			config.Log.Error(err, "unable to update mutation status")
-			return ctrl.Result{RequeueAfter: util.ErrorRequeueTime}, err
-		}
-		return ctrl.Result{RequeueAfter: util.ErrorRequeueTime}, err
-	} else {
-		// Let's check if there is a deletion timestamp
-		if config.Obj.GetDeletionTimestamp() != nil {
-			config.Log.Info("Object has a deletion timestamp, it is being deleted", "object", config.Obj.GetName())
-			return ctrl.Result{RequeueAfter: util.ErrorRequeueTime}, nil
-		} else {
-			// Delete the object
-			if err := config.Rc.Delete(config.Ctx, config.Obj); err != nil {
-				config.Log.Error(err, "unable to delete object", "object", config.Obj.GetName())
-				return ctrl.Result{RequeueAfter: util.ErrorRequeueTime}, err
-			}
-		}
+		return true, nil
 	}
-	return ctrl.Result{}, nil
+	// Let's check if there is a deletion timestamp
+	if live.GetDeletionTimestamp() != nil {
+		config.Log.Info("Object has a deletion timestamp, it is being deleted", "object", config.Obj.GetName())
+		return false, nil
+	}
+	// Keep the pod as it was so that it can be restored on rollback
+	if err := snapshot(&config, live); err != nil {
+		config.Log.Error(err, "unable to snapshot object", "object", config.Obj.GetName())
+		return false, err
+	}
+	// Delete the object
+	if err := config.Rc.Delete(config.Ctx, live); err != nil {
+		config.Log.Error(err, "unable to delete object", "object", config.Obj.GetName())
+		return false, err
+	}
+	return false, nil
 }
 
-func deploymentExecution(config ObjectExecutionConfig, deployment appsv1.Deployment) (ctrl.Result, error) {
-	// deployment to yaml
-	yamlData, err := yaml.Marshal(deployment)
+// resolveWorkload asks the backend to carry the target configuration of the mutation over to
+// the workload that controls the pod, e.g. its Deployment, returning the workload to write
+func resolveWorkload(config ObjectExecutionConfig, workload *unstructured.Unstructured) (client.Object, error) {
+	yamlData, err := yaml.Marshal(workload.Object)
 	if err != nil {
-		config.Log.Error(err, "unable to marshal deployment to yaml", "deployment", deployment.GetName())
-		return ctrl.Result{RequeueAfter: util.ErrorRequeueTime}, err
+		config.Log.Error(err, "unable to marshal workload to yaml", "kind", workload.GetKind(), "workload", workload.GetName())
+		return nil, err
+	}
+	var data prompts.Data
+	if config.PromptData != nil {
+		data = config.PromptData()
+	}
+	data.Origin = string(yamlData)
+	data.Target = config.Mutation.Spec.TargetConfiguration
+	rawQuery, templateVersion, err := config.Prompts.Render(config.Ctx, prompts.StepDeployment,
+		config.Mutation.Spec.ResourceRef.Kind, data)
+	if err != nil {
+		config.Log.Error(err, "unable to render prompt", "kind", workload.GetKind(), "workload", workload.GetName())
+		return nil, err
 	}
-	rawQuery := fmt.Sprintf(prompts.Deployment_prompt, config.Mutation.Spec.TargetConfiguration, yamlData)
 	response, err := config.QueryClient.Query(context.Background(), &schemav1.QueryRequest{
 		Backend: config.Backend,
 		Query:   rawQuery,
 	})
 	if err != nil {
-		config.Log.Error(err, "unable to query server", "deployment", deployment.GetName())
-		return ctrl.Result{RequeueAfter: util.ErrorRequeueTime}, err
+		config.Log.Error(err, "unable to query server", "kind", workload.GetKind(), "workload", workload.GetName())
+		return nil, err
 	}
+	RecordQuery(config.Mutation, templateVersion, rawQuery, response.Response)
 
-	// Parse the response into a deployment
-	var newDeployment appsv1.Deployment
-	if err := yaml.Unmarshal([]byte(response.Response), &newDeployment); err != nil {
-		config.Log.Error(err, "unable to unmarshal response to deployment", "deployment", deployment.GetName())
-		return ctrl.Result{RequeueAfter: util.ErrorRequeueTime}, err
-	}
-	// Update the new deployment
-	if err := config.Rc.Update(config.Ctx, &newDeployment); err != nil {
-		config.Log.Error(err, "unable to updated the deployment", "deployment", deployment.GetName())
-		return ctrl.Result{RequeueAfter: util.ErrorRequeueTime}, err
-	}
-	config.Log.Info("Successfully updated deployment", "deployment", deployment.GetName())
-	config.Mutation.Status.Phase = corev1alpha1.AutoRemediationPhaseCompleted
-	config.Mutation.Status.Message = "Completed"
-	if err := config.Rc.Update(config.Ctx, &config.Mutation); err != nil {
-		config.Log.Error(err, "unable to update mutation status")
-		return ctrl.Result{RequeueAfter: util.ErrorRequeueTime}, err
-	}
-	return ctrl.Result{RequeueAfter: util.SuccessfulRequeueTime}, nil
+	// Parse the response into a workload of the same kind
+	content := map[string]interface{}{}
+	if err := yaml.Unmarshal([]byte(response.Response), &content); err != nil {
+		config.Log.Error(err, "unable to unmarshal response to workload", "kind", workload.GetKind(), "workload", workload.GetName())
+		return nil, &GuardrailError{Violations: []string{fmt.Sprintf("%s does not parse: %v", workload.GetKind(), err)}}
+	}
+	newWorkload := &unstructured.Unstructured{Object: content}
+	// Fill in what the backend left out, anything it changed is refused
+	expected := workload.GroupVersionKind()
+	if newWorkload.GetAPIVersion() == "" && newWorkload.GetKind() == "" {
+		newWorkload.SetGroupVersionKind(expected)
+	}
+	if newWorkload.GetName() == "" {
+		newWorkload.SetName(workload.GetName())
+	}
+	if newWorkload.GetNamespace() == "" {
+		newWorkload.SetNamespace(workload.GetNamespace())
+	}
+	if violations := CheckIdentity(expected, workload.GetName(), workload.GetNamespace(), newWorkload); len(violations) > 0 {
+		return nil, &GuardrailError{Violations: violations}
+	}
+	return newWorkload, nil
 }
 
-func ResourceToExecution(config ObjectExecutionConfig) (ctrl.Result, error) {
-	config.Log.Info("ResourceToExecution", "kind", config.Obj.GetObjectKind().GroupVersionKind().Kind)
-	switch config.Obj.GetObjectKind().GroupVersionKind().Kind {
+// podWorkload returns the top level controller of a pod, nil for a pod without a controller.
+// The workload recorded on the mutation is used when it is known, otherwise the owner chain of
+// the pod is followed. Pods whose workload has no executor, e.g. a ReplicaSet without a
+// Deployment, are unsupported.
+func podWorkload(config ObjectExecutionConfig, pod *corev1.Pod) (*unstructured.Unstructured, error) {
+	var ref corev1.ObjectReference
+	if config.Mutation != nil {
+		ref = config.Mutation.Spec.Workload
+	}
+	if ref.Name == "" || ref.Kind == "Pod" {
+		chain, err := util.OwnerChain(config.Ctx, config.Rc, pod)
+		if err != nil {
+			config.Log.Error(err, "unable to resolve owner chain", "pod", pod.Name)
+			return nil, err
+		}
+		if len(chain) == 0 {
+			return nil, nil
+		}
+		ref = chain[len(chain)-1]
+	}
+	if _, ok := Executors[ref.Kind]; !ok || ref.Kind == "Pod" {
+		return nil, fmt.Errorf("%w %s controlling pod %s", ErrUnsupportedKind, ref.Kind, pod.Name)
+	}
+	gv, err := schema.ParseGroupVersion(ref.APIVersion)
+	if err != nil {
+		return nil, err
+	}
+	workload := &unstructured.Unstructured{}
+	workload.SetGroupVersionKind(gv.WithKind(ref.Kind))
+	if err := config.Rc.Get(config.Ctx, client.ObjectKey{Namespace: pod.Namespace, Name: ref.Name}, workload); err != nil {
+		config.Log.Error(err, "unable to get workload", "kind", ref.Kind, "workload", ref.Name)
+		return nil, err
+	}
+	return workload, nil
+}
+
+// ErrUnsupportedKind is returned for kinds without an executor
+var ErrUnsupportedKind = errors.New("no executor for kind")
+
+// ResolveTarget computes the object that executing the mutation writes. Pods controlled by a
+// workload are remediated through the top level workload, e.g. their Deployment, StatefulSet
+// or CronJob, which the backend derives from the target configuration; pods without a
+// controller are recreated from the target configuration itself.
+func ResolveTarget(config ObjectExecutionConfig) (client.Object, error) {
+	kind := config.Obj.GetObjectKind().GroupVersionKind().Kind
+	switch kind {
 
 	case "Pod":
 		var pod corev1.Pod
-		err := config.Rc.Get(context.Background(),
+		err := config.Rc.Get(config.Ctx,
 			client.ObjectKey{Name: config.Obj.GetName(),
 				Namespace: config.Obj.GetNamespace()}, &pod)
+		if apierrors.IsNotFound(err) {
+			// A static pod that has already been deleted for recreation
+			return config.Obj, nil
+		}
 		if err != nil {
-			config.Log.Error(err, "unable to get pod", "pod", pod.GetName())
-			return ctrl.Result{RequeueAfter: util.ErrorRequeueTime}, err
+			config.Log.Error(err, "unable to get pod", "pod", config.Obj.GetName())
+			return nil, err
 		}
-		// TODO: Maybe defer this to a deployment result to fix
-		if len(pod.OwnerReferences) > 0 {
-			// Fetch the owner replica set
-			var rs appsv1.ReplicaSet
-			err := config.Rc.Get(context.Background(),
-				client.ObjectKey{Name: pod.OwnerReferences[0].Name,
-					Namespace: pod.GetNamespace()}, &rs)
-			if err != nil {
-				config.Log.Error(err, "unable to get replica set",
-					"replica set", pod.OwnerReferences[0].Name)
-				return ctrl.Result{RequeueAfter: util.ErrorRequeueTime}, err
-			}
-			// Get deployment
-			var deployment appsv1.Deployment
-			err = config.Rc.Get(context.Background(),
-				client.ObjectKey{Name: rs.OwnerReferences[0].Name,
-					Namespace: rs.GetNamespace()}, &deployment)
-			if err != nil {
-				config.Log.Error(err, "unable to get deployment", "deployment", rs.OwnerReferences[0].Name)
-				return ctrl.Result{RequeueAfter: util.ErrorRequeueTime}, err
-			}
-			return deploymentExecution(config, deployment)
-
+		workload, err := podWorkload(config, &pod)
+		if err != nil {
+			return nil, err
 		}
-		return staticPodExecution(config)
+		if workload == nil {
+			return config.Obj, nil
+		}
+		return resolveWorkload(config, workload)
 
 	case "Deployment":
-		var deployment appsv1.Deployment
-		err := config.Rc.Get(context.Background(),
+		deployment := &unstructured.Unstructured{}
+		deployment.SetGroupVersionKind(config.Obj.GetObjectKind().GroupVersionKind())
+		err := config.Rc.Get(config.Ctx,
 			client.ObjectKey{Name: config.Obj.GetName(),
-				Namespace: config.Obj.GetNamespace()}, &deployment)
+				Namespace: config.Obj.GetNamespace()}, deployment)
 		if err != nil {
-			config.Log.Error(err, "unable to get deployment", "deployment", deployment.GetName())
-			return ctrl.Result{RequeueAfter: util.ErrorRequeueTime}, err
+			config.Log.Error(err, "unable to get deployment", "deployment", config.Obj.GetName())
+			return nil, err
 		}
-		return deploymentExecution(config, deployment)
+		return resolveWorkload(config, deployment)
+	}
+	// Other kinds are written from the target configuration as it is
+	if _, ok := Executors[kind]; ok {
+		return config.Obj, nil
+	}
+	return nil, fmt.Errorf("%w %s", ErrUnsupportedKind, kind)
+}
+
+// ExecuteTarget writes a target returned by ResolveTarget with the executor of its kind. It
+// reports whether the target is in place, recording when it was applied on the mutation.
+func ExecuteTarget(config ObjectExecutionConfig, target client.Object) (bool, error) {
+	kind := target.GetObjectKind().GroupVersionKind().Kind
+	execute, ok := Executors[kind]
+	if !ok {
+		return false, fmt.Errorf("%w %s", ErrUnsupportedKind, kind)
+	}
+	done, err := execute(config, target)
+	if done {
+		appliedAt := metav1.Now()
+		config.Mutation.Status.AppliedAt = &appliedAt
 	}
-	return ctrl.Result{}, nil
+	return done, err
 }
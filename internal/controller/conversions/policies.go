@@ -0,0 +1,132 @@
+package conversions
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/ext"
+	corev1alpha1 "github.com/k8sgpt-ai/k8sgpt-operator/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// policyCostLimit bounds the evaluation cost of a single policy so that a policy iterating over
+// large objects cannot stall the controller
+const policyCostLimit = 1000000
+
+// policyEnv declares the variables and functions policies are evaluated with. quantityValue
+// converts a resource quantity such as "512Mi" to a double so that limits can be compared.
+var policyEnv = sync.OnceValues(func() (*cel.Env, error) {
+	return cel.NewEnv(
+		cel.Variable("oldObject", cel.DynType),
+		cel.Variable("newObject", cel.DynType),
+		ext.Strings(),
+		cel.Function("quantityValue",
+			cel.Overload("quantityValue_string", []*cel.Type{cel.StringType}, cel.DoubleType,
+				cel.UnaryBinding(quantityValue))),
+	)
+})
+
+func quantityValue(value ref.Val) ref.Val {
+	str, ok := value.(types.String)
+	if !ok {
+		return types.MaybeNoSuchOverloadErr(value)
+	}
+	quantity, err := resource.ParseQuantity(string(str))
+	if err != nil {
+		return types.WrapErr(err)
+	}
+	return types.Double(quantity.AsApproximateFloat64())
+}
+
+// CompilePolicy compiles the expression of a remediation policy, it must return a bool
+func CompilePolicy(policy corev1alpha1.RemediationPolicy) (cel.Program, error) {
+	env, err := policyEnv()
+	if err != nil {
+		return nil, err
+	}
+	ast, issues := env.Compile(policy.Expression)
+	if issues.Err() != nil {
+		return nil, issues.Err()
+	}
+	if output := ast.OutputType(); !output.IsExactType(cel.BoolType) && !output.IsExactType(cel.DynType) {
+		return nil, fmt.Errorf("expression returns %s, not bool", output)
+	}
+	return env.Program(ast, cel.CostLimit(policyCostLimit))
+}
+
+// EvaluatePolicies returns the policies the change from oldObject to newObject violates. A nil
+// oldObject is null in the expressions. Policies that do not compile, fail to evaluate or do
+// not return a bool are violations, so that a broken policy never lets a fix through.
+func EvaluatePolicies(policies []corev1alpha1.RemediationPolicy,
+	oldObject, newObject *unstructured.Unstructured) []corev1alpha1.PolicyViolation {
+	activation := map[string]interface{}{
+		"oldObject": objectContent(oldObject),
+		"newObject": objectContent(newObject),
+	}
+	var violations []corev1alpha1.PolicyViolation
+	for _, policy := range policies {
+		violation := corev1alpha1.PolicyViolation{Policy: policy.Name}
+		program, err := CompilePolicy(policy)
+		if err != nil {
+			violation.Message = fmt.Sprintf("policy does not compile: %v", err)
+			violations = append(violations, violation)
+			continue
+		}
+		out, _, err := program.Eval(activation)
+		if err != nil {
+			violation.Message = fmt.Sprintf("policy could not be evaluated: %v", err)
+			violations = append(violations, violation)
+			continue
+		}
+		allowed, ok := out.Value().(bool)
+		switch {
+		case !ok:
+			violation.Message = fmt.Sprintf("policy returned %v, not a bool", out.Value())
+		case allowed:
+			continue
+		case policy.Message != "":
+			violation.Message = policy.Message
+		default:
+			violation.Message = fmt.Sprintf("failed expression: %s", policy.Expression)
+		}
+		violations = append(violations, violation)
+	}
+	return violations
+}
+
+// CheckPolicies evaluates the remediation policies of the config against the live object and
+// the resolved target. When the object does not exist the origin configuration is the old
+// object, as for the guardrails. Executors that recreate an object delete it once the policies
+// passed, they are not evaluated again while it is recreated.
+func CheckPolicies(config ObjectExecutionConfig, target client.Object) ([]corev1alpha1.PolicyViolation, error) {
+	if len(config.Policies) == 0 {
+		return nil, nil
+	}
+	obj, err := ToApplyObject(target)
+	if err != nil {
+		return nil, err
+	}
+	live, err := liveObject(config, obj)
+	if err != nil {
+		return nil, err
+	}
+	if live == nil {
+		if config.Mutation.Status.PreviousConfiguration != "" {
+			return nil, nil
+		}
+		live = applyBase(config.Mutation.Spec.OriginConfiguration, nil, obj)
+	}
+	return EvaluatePolicies(config.Policies, live, obj), nil
+}
+
+func objectContent(obj *unstructured.Unstructured) interface{} {
+	if obj == nil {
+		return nil
+	}
+	return obj.Object
+}
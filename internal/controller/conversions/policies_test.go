@@ -0,0 +1,116 @@
+package conversions
+
+import (
+	"testing"
+
+	corev1alpha1 "github.com/k8sgpt-ai/k8sgpt-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var platformPolicies = []corev1alpha1.RemediationPolicy{
+	{
+		Name:       "corp-registry",
+		Expression: `newObject.spec.template.spec.containers.all(c, c.image.startsWith("registry.corp/"))`,
+		Message:    "images must come from registry.corp",
+	},
+	{
+		Name: "keep-replicas",
+		Expression: `oldObject == null || !has(newObject.spec.replicas) ||
+			newObject.spec.replicas >= oldObject.spec.replicas`,
+	},
+	{
+		Name: "memory-limit",
+		Expression: `oldObject == null || newObject.spec.template.spec.containers.all(c,
+			!has(c.resources.limits) || !has(c.resources.limits.memory) ||
+			oldObject.spec.template.spec.containers.exists(o, o.name == c.name && has(o.resources.limits) &&
+				has(o.resources.limits.memory) &&
+				quantityValue(c.resources.limits.memory) <= 2.0 * quantityValue(o.resources.limits.memory)))`,
+	},
+}
+
+func Test_EvaluatePolicies(t *testing.T) {
+	live := newDeployment("registry.corp/nginx:1.0")
+	replicas := int32(3)
+	live.Spec.Replicas = &replicas
+	live.Spec.Template.Spec.Containers[0].Resources.Limits = corev1.ResourceList{
+		corev1.ResourceMemory: resource.MustParse("256Mi"),
+	}
+
+	// The fix changes the image within the registry and doubles the memory limit
+	fixed := live.DeepCopy()
+	fixed.Spec.Template.Spec.Containers[0].Image = "registry.corp/nginx:1.1"
+	fixed.Spec.Template.Spec.Containers[0].Resources.Limits[corev1.ResourceMemory] = resource.MustParse("512Mi")
+	assert.Empty(t, EvaluatePolicies(platformPolicies, toPrevious(t, live), toPrevious(t, fixed)))
+
+	// The fix pulls from docker hub and scales the deployment down
+	violating := live.DeepCopy()
+	violating.Spec.Template.Spec.Containers[0].Image = "nginx:1.1"
+	fewer := int32(1)
+	violating.Spec.Replicas = &fewer
+	violating.Spec.Template.Spec.Containers[0].Resources.Limits[corev1.ResourceMemory] = resource.MustParse("1Gi")
+	assert.Equal(t, []corev1alpha1.PolicyViolation{
+		{Policy: "corp-registry", Message: "images must come from registry.corp"},
+		{Policy: "keep-replicas", Message: "failed expression: " + platformPolicies[1].Expression},
+		{Policy: "memory-limit", Message: "failed expression: " + platformPolicies[2].Expression},
+	}, EvaluatePolicies(platformPolicies, toPrevious(t, live), toPrevious(t, violating)))
+
+	// Creating an object has no old object
+	assert.Empty(t, EvaluatePolicies(platformPolicies[1:2], nil, toPrevious(t, fixed)))
+}
+
+func Test_EvaluatePoliciesBrokenPolicies(t *testing.T) {
+	broken := []corev1alpha1.RemediationPolicy{
+		{Name: "syntax", Expression: "newObject.spec.("},
+		{Name: "not-bool", Expression: `"yes"`},
+		{Name: "missing-field", Expression: "newObject.spec.paused"},
+	}
+	target := toPrevious(t, newDeployment("nginx:1.1"))
+	violations := EvaluatePolicies(broken, target, target)
+	require.Len(t, violations, 3)
+	assert.Contains(t, violations[0].Message, "policy does not compile")
+	assert.Contains(t, violations[1].Message, "policy does not compile")
+	assert.Contains(t, violations[2].Message, "policy could not be evaluated")
+}
+
+func Test_CheckPoliciesRecreatedObject(t *testing.T) {
+	job := &batchv1.Job{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "batch/v1", Kind: "Job"},
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "migrate"},
+		Spec:       batchv1.JobSpec{Template: corev1.PodTemplateSpec{Spec: webPodSpec("registry.corp/migrate:broken")}},
+	}
+	c := fake.NewClientBuilder().WithScheme(newRollbackScheme(t)).WithObjects(job.DeepCopy()).Build()
+	config, mutation := executorConfig(c)
+	config.Policies = []corev1alpha1.RemediationPolicy{{
+		Name:       "same-registry",
+		Expression: `oldObject.spec.template.spec.containers[0].image.split(":")[0] == newObject.spec.template.spec.containers[0].image.split(":")[0]`,
+	}}
+	target := targetOf(t, job, setImage("registry.corp/migrate:1.0"))
+
+	violations, err := CheckPolicies(config, target)
+	require.NoError(t, err)
+	assert.Empty(t, violations)
+
+	// The job is deleted on the first pass, the policies are not evaluated again while it is recreated
+	done, err := ExecuteTarget(config, target)
+	require.NoError(t, err)
+	assert.False(t, done)
+	violations, err = CheckPolicies(config, target)
+	require.NoError(t, err)
+	assert.Empty(t, violations)
+
+	// Without a snapshot the origin configuration is the old object
+	mutation.Status.PreviousConfiguration = ""
+	origin, err := ToManifest(toPrevious(t, job))
+	require.NoError(t, err)
+	mutation.Spec.OriginConfiguration = origin
+	violations, err = CheckPolicies(config, targetOf(t, job, setImage("nginx:1.0")))
+	require.NoError(t, err)
+	assert.Equal(t, []corev1alpha1.PolicyViolation{{Policy: "same-registry",
+		Message: "failed expression: " + config.Policies[0].Expression}}, violations)
+}
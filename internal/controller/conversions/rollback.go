@@ -0,0 +1,301 @@
+package conversions
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"slices"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/structured-merge-diff/v4/fieldpath"
+	"sigs.k8s.io/structured-merge-diff/v4/value"
+)
+
+const (
+	// RollbackReasonProgressDeadlineExceeded means the rollout of the mutated deployment stalled
+	RollbackReasonProgressDeadlineExceeded = "ProgressDeadlineExceeded"
+	// RollbackReasonCrashLoop means more containers are crash looping than before the mutation
+	RollbackReasonCrashLoop = "CrashLoopBackOff"
+	// RollbackReasonPendingDeadlineExceeded means the result was still present after the pending deadline
+	RollbackReasonPendingDeadlineExceeded = "PendingDeadlineExceeded"
+)
+
+// snapshot records the live object before it is mutated so it can be rolled back
+func snapshot(config *ObjectExecutionConfig, live client.Object) error {
+	if live.GetObjectKind().GroupVersionKind().Empty() {
+		gvk, err := apiutil.GVKForObject(live, config.Rc.Scheme())
+		if err != nil {
+			return err
+		}
+		live.GetObjectKind().SetGroupVersionKind(gvk)
+	}
+	obj, err := ToApplyObject(live)
+	if err != nil {
+		return err
+	}
+	manifest, err := ToManifest(obj)
+	if err != nil {
+		return err
+	}
+	crashLoops, err := CrashLoopingContainers(config.Ctx, config.Rc, obj)
+	if err != nil {
+		return err
+	}
+	stalled, err := rolloutStalled(config.Ctx, config.Rc, obj)
+	if err != nil {
+		return err
+	}
+	config.Mutation.Status.PreviousConfiguration = manifest
+	config.Mutation.Status.CrashLoopsBeforeApply = crashLoops
+	config.Mutation.Status.RolloutStalledBeforeApply = stalled
+	return nil
+}
+
+// CrashLoopingContainers counts the containers in CrashLoopBackOff of a pod, or of the pods
+// selected by a deployment, stateful set, daemon set or job
+func CrashLoopingContainers(ctx context.Context, c client.Client, obj *unstructured.Unstructured) (int, error) {
+	var pods []corev1.Pod
+	switch obj.GetKind() {
+	case "Pod":
+		var pod corev1.Pod
+		if err := c.Get(ctx, client.ObjectKeyFromObject(obj), &pod); err != nil {
+			return 0, client.IgnoreNotFound(err)
+		}
+		pods = append(pods, pod)
+	case "Deployment", "StatefulSet", "DaemonSet", "Job":
+		live := &unstructured.Unstructured{}
+		live.SetGroupVersionKind(obj.GroupVersionKind())
+		if err := c.Get(ctx, client.ObjectKeyFromObject(obj), live); err != nil {
+			return 0, client.IgnoreNotFound(err)
+		}
+		content, found, err := unstructured.NestedMap(live.Object, "spec", "selector")
+		if err != nil || !found {
+			return 0, err
+		}
+		var labelSelector metav1.LabelSelector
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(content, &labelSelector); err != nil {
+			return 0, err
+		}
+		selector, err := metav1.LabelSelectorAsSelector(&labelSelector)
+		if err != nil {
+			return 0, err
+		}
+		var list corev1.PodList
+		if err := c.List(ctx, &list, client.InNamespace(live.GetNamespace()),
+			client.MatchingLabelsSelector{Selector: selector}); err != nil {
+			return 0, err
+		}
+		pods = list.Items
+	}
+	count := 0
+	for _, pod := range pods {
+		for _, status := range pod.Status.ContainerStatuses {
+			if status.State.Waiting != nil && status.State.Waiting.Reason == "CrashLoopBackOff" {
+				count++
+			}
+		}
+	}
+	return count, nil
+}
+
+// rolloutStalled reports whether a deployment exceeded its progress deadline on its current
+// generation. The condition is ignored until the deployment controller has observed the
+// generation, before that it describes the previous rollout.
+func rolloutStalled(ctx context.Context, c client.Client, obj *unstructured.Unstructured) (bool, error) {
+	if obj.GetKind() != "Deployment" {
+		return false, nil
+	}
+	var deployment appsv1.Deployment
+	if err := c.Get(ctx, client.ObjectKeyFromObject(obj), &deployment); err != nil {
+		return false, client.IgnoreNotFound(err)
+	}
+	if deployment.Status.ObservedGeneration < deployment.Generation {
+		return false, nil
+	}
+	for _, condition := range deployment.Status.Conditions {
+		if condition.Type == appsv1.DeploymentProgressing && condition.Status == corev1.ConditionFalse &&
+			condition.Reason == RollbackReasonProgressDeadlineExceeded {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// RolloutFailure returns why the mutated object is worse off than before the mutation, or an
+// empty string while it is healthy. A rollout that had stalled before the mutation is not a
+// failure of the mutation.
+func RolloutFailure(ctx context.Context, c client.Client, previous *unstructured.Unstructured, crashLoopsBefore int,
+	stalledBefore bool) (string, error) {
+	stalled, err := rolloutStalled(ctx, c, previous)
+	if err != nil {
+		return "", err
+	}
+	if stalled && !stalledBefore {
+		return RollbackReasonProgressDeadlineExceeded, nil
+	}
+	crashLoops, err := CrashLoopingContainers(ctx, c, previous)
+	if err != nil {
+		return "", err
+	}
+	if crashLoops > crashLoopsBefore {
+		return RollbackReasonCrashLoop, nil
+	}
+	return "", nil
+}
+
+// Rollback restores the previous configuration of a mutated object. Objects updated in place get
+// back the previous values of the fields the remediation field manager applied, fields written by
+// others since, such as the replicas set by an autoscaler, are kept. Pods and jobs cannot be
+// updated in place, so they are deleted and recreated over two calls; done reports whether the
+// previous configuration is in place.
+func Rollback(ctx context.Context, c client.Client, previous *unstructured.Unstructured) (bool, error) {
+	live := &unstructured.Unstructured{}
+	live.SetGroupVersionKind(previous.GroupVersionKind())
+	err := c.Get(ctx, client.ObjectKeyFromObject(previous), live)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return false, err
+	}
+
+	if slices.Contains(recreatedKinds, previous.GetKind()) {
+		if apierrors.IsNotFound(err) {
+			return true, c.Create(ctx, recreatable(previous))
+		}
+		if live.GetDeletionTimestamp() == nil {
+			return false, c.Delete(ctx, live, client.PropagationPolicy(metav1.DeletePropagationBackground))
+		}
+		return false, nil
+	}
+
+	if apierrors.IsNotFound(err) {
+		return false, fmt.Errorf("%s %s/%s no longer exists", previous.GetKind(),
+			previous.GetNamespace(), previous.GetName())
+	}
+	fields, err := appliedFields(live, previous)
+	if err != nil {
+		return false, err
+	}
+	obj := &unstructured.Unstructured{Object: fields}
+	obj.SetGroupVersionKind(previous.GroupVersionKind())
+	obj.SetNamespace(previous.GetNamespace())
+	obj.SetName(previous.GetName())
+	if _, err := Apply(ctx, c, obj, true); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// appliedFields returns the fields of previous at the paths the remediation field manager owns
+// on the live object. Applying them leaves out the fields the fix added, which releases them.
+func appliedFields(live, previous *unstructured.Unstructured) (map[string]interface{}, error) {
+	for _, entry := range live.GetManagedFields() {
+		if entry.Manager != RemediationFieldManager || entry.Operation != metav1.ManagedFieldsOperationApply ||
+			entry.Subresource != "" || entry.FieldsV1 == nil {
+			continue
+		}
+		owned := &fieldpath.Set{}
+		if err := owned.FromJSON(bytes.NewReader(entry.FieldsV1.Raw)); err != nil {
+			return nil, fmt.Errorf("managed fields of %s: %w", live.GetName(), err)
+		}
+		return pickFields(previous.Object, owned), nil
+	}
+	return map[string]interface{}{}, nil
+}
+
+// pickFields returns the fields of content in the set. A field the set has no children for is
+// taken as a whole.
+func pickFields(content map[string]interface{}, set *fieldpath.Set) map[string]interface{} {
+	picked := map[string]interface{}{}
+	for field, value := range content {
+		pe := fieldpath.PathElement{FieldName: &field}
+		children, ok := set.Children.Get(pe)
+		if !ok {
+			if set.Members.Has(pe) {
+				picked[field] = value
+			}
+			continue
+		}
+		switch value := value.(type) {
+		case map[string]interface{}:
+			value = pickFields(value, children)
+			if len(value) > 0 || set.Members.Has(pe) {
+				picked[field] = value
+			}
+		case []interface{}:
+			value = pickElements(value, children)
+			if len(value) > 0 || set.Members.Has(pe) {
+				picked[field] = value
+			}
+		default:
+			picked[field] = value
+		}
+	}
+	return picked
+}
+
+// pickElements returns the elements of a list in the set, elements of lists of named objects
+// keep their key fields
+func pickElements(list []interface{}, set *fieldpath.Set) []interface{} {
+	var picked []interface{}
+	for i, element := range list {
+		pe, ok := elementOf(set, i, element)
+		if !ok {
+			continue
+		}
+		fields, isMap := element.(map[string]interface{})
+		if children, ok := set.Children.Get(pe); ok && isMap {
+			owned := pickFields(fields, children)
+			if pe.Key != nil {
+				for _, key := range *pe.Key {
+					owned[key.Name] = fields[key.Name]
+				}
+			}
+			element = owned
+		}
+		picked = append(picked, element)
+	}
+	return picked
+}
+
+// elementOf returns the path element of the set matching a list element
+func elementOf(set *fieldpath.Set, index int, element interface{}) (fieldpath.PathElement, bool) {
+	var match *fieldpath.PathElement
+	visit := func(pe fieldpath.PathElement) {
+		if match == nil && elementMatches(pe, index, element) {
+			match = &pe
+		}
+	}
+	set.Children.Iterate(visit)
+	set.Members.Iterate(visit)
+	if match == nil {
+		return fieldpath.PathElement{}, false
+	}
+	return *match, true
+}
+
+func elementMatches(pe fieldpath.PathElement, index int, element interface{}) bool {
+	switch {
+	case pe.Key != nil:
+		fields, ok := element.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for _, key := range *pe.Key {
+			if !value.Equals(key.Value, value.NewValueInterface(fields[key.Name])) {
+				return false
+			}
+		}
+		return true
+	case pe.Value != nil:
+		return value.Equals(*pe.Value, value.NewValueInterface(element))
+	case pe.Index != nil:
+		return *pe.Index == index
+	}
+	return false
+}
@@ -0,0 +1,169 @@
+package conversions
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func newRollbackScheme(t *testing.T) *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	return scheme
+}
+
+func newDeployment(image string) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web"},
+		Spec: appsv1.DeploymentSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "web"}},
+				Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "web", Image: image}}},
+			},
+		},
+	}
+}
+
+func crashLoopingPod(name string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name, Labels: map[string]string{"app": "web"}},
+		Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{
+			Name:  "web",
+			State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}},
+		}}},
+	}
+}
+
+func toPrevious(t *testing.T, obj client.Object) *unstructured.Unstructured {
+	previous, err := ToApplyObject(obj)
+	require.NoError(t, err)
+	return previous
+}
+
+func Test_RolloutFailure(t *testing.T) {
+	ctx := context.Background()
+	stalled := newDeployment("nginx:broken")
+	stalled.Generation = 2
+	stalled.Status.ObservedGeneration = 2
+	stalled.Status.Conditions = []appsv1.DeploymentCondition{{
+		Type:   appsv1.DeploymentProgressing,
+		Status: corev1.ConditionFalse,
+		Reason: RollbackReasonProgressDeadlineExceeded,
+	}}
+	c := fake.NewClientBuilder().WithScheme(newRollbackScheme(t)).WithObjects(stalled).Build()
+	reason, err := RolloutFailure(ctx, c, toPrevious(t, newDeployment("nginx:1.0")), 0, false)
+	require.NoError(t, err)
+	assert.Equal(t, RollbackReasonProgressDeadlineExceeded, reason)
+	reason, err = RolloutFailure(ctx, c, toPrevious(t, newDeployment("nginx:1.0")), 0, true)
+	require.NoError(t, err)
+	assert.Empty(t, reason, "the rollout had stalled before the mutation")
+
+	// The condition describes the previous rollout until the mutated generation is observed
+	unobserved := stalled.DeepCopy()
+	unobserved.Generation = 3
+	c = fake.NewClientBuilder().WithScheme(newRollbackScheme(t)).WithObjects(unobserved).Build()
+	reason, err = RolloutFailure(ctx, c, toPrevious(t, newDeployment("nginx:1.0")), 0, false)
+	require.NoError(t, err)
+	assert.Empty(t, reason)
+
+	c = fake.NewClientBuilder().WithScheme(newRollbackScheme(t)).
+		WithObjects(newDeployment("nginx:broken"), crashLoopingPod("web-1"), crashLoopingPod("web-2")).Build()
+	reason, err = RolloutFailure(ctx, c, toPrevious(t, newDeployment("nginx:1.0")), 2, false)
+	require.NoError(t, err)
+	assert.Empty(t, reason, "no more crash loops than before the mutation")
+	reason, err = RolloutFailure(ctx, c, toPrevious(t, newDeployment("nginx:1.0")), 1, false)
+	require.NoError(t, err)
+	assert.Equal(t, RollbackReasonCrashLoop, reason)
+}
+
+func Test_RollbackRestoresAppliedFields(t *testing.T) {
+	ctx := context.Background()
+	previous := newDeployment("nginx:1.0")
+	previous.Spec.Replicas = ptr.To(int32(3))
+
+	// The fix changed the image and added a variable, an autoscaler has scaled the deployment since
+	live := newDeployment("nginx:broken")
+	live.Spec.Replicas = ptr.To(int32(5))
+	live.Spec.Template.Spec.Containers[0].Env = []corev1.EnvVar{{Name: "DEBUG", Value: "1"}}
+	live.ManagedFields = []metav1.ManagedFieldsEntry{
+		{
+			Manager:    RemediationFieldManager,
+			Operation:  metav1.ManagedFieldsOperationApply,
+			APIVersion: "apps/v1",
+			FieldsType: "FieldsV1",
+			FieldsV1: &metav1.FieldsV1{Raw: []byte(`{"f:spec":{"f:template":{"f:spec":{"f:containers":` +
+				`{"k:{\"name\":\"web\"}":{".":{},"f:env":{"k:{\"name\":\"DEBUG\"}":{".":{},"f:name":{},` +
+				`"f:value":{}}},"f:image":{},"f:name":{}}}}}}}`)},
+		},
+		{
+			Manager:    "autoscaler",
+			Operation:  metav1.ManagedFieldsOperationUpdate,
+			APIVersion: "apps/v1",
+			FieldsType: "FieldsV1",
+			FieldsV1:   &metav1.FieldsV1{Raw: []byte(`{"f:spec":{"f:replicas":{}}}`)},
+		},
+	}
+
+	var applied *unstructured.Unstructured
+	c := fake.NewClientBuilder().WithScheme(newRollbackScheme(t)).WithObjects(live).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Patch: func(_ context.Context, _ client.WithWatch, obj client.Object, patch client.Patch,
+				opts ...client.PatchOption) error {
+				require.Equal(t, types.ApplyPatchType, patch.Type())
+				applied = obj.(*unstructured.Unstructured)
+				return nil
+			},
+		}).Build()
+
+	done, err := Rollback(ctx, c, toPrevious(t, previous))
+	require.NoError(t, err)
+	assert.True(t, done)
+
+	require.NotNil(t, applied)
+	assert.Equal(t, "Deployment", applied.GetKind())
+	assert.Equal(t, "web", applied.GetName())
+	_, found, _ := unstructured.NestedFieldNoCopy(applied.Object, "spec", "replicas")
+	assert.False(t, found, "the replicas are owned by the autoscaler")
+	containers, _, _ := unstructured.NestedSlice(applied.Object, "spec", "template", "spec", "containers")
+	assert.Equal(t, []interface{}{map[string]interface{}{"name": "web", "image": "nginx:1.0"}}, containers,
+		"the variable the fix added is left out, which removes it")
+}
+
+func Test_RollbackRecreatesPod(t *testing.T) {
+	ctx := context.Background()
+	broken := &corev1.Pod{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "static"},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "app:broken"}}},
+	}
+	c := fake.NewClientBuilder().WithScheme(newRollbackScheme(t)).WithObjects(broken.DeepCopy()).Build()
+	original := broken.DeepCopy()
+	original.Spec.Containers[0].Image = "app:1.0"
+
+	done, err := Rollback(ctx, c, toPrevious(t, original))
+	require.NoError(t, err)
+	assert.False(t, done, "the broken pod is deleted first")
+
+	done, err = Rollback(ctx, c, toPrevious(t, original))
+	require.NoError(t, err)
+	assert.True(t, done)
+
+	var pod corev1.Pod
+	require.NoError(t, c.Get(ctx, client.ObjectKey{Namespace: "default", Name: "static"}, &pod))
+	assert.Equal(t, "app:1.0", pod.Spec.Containers[0].Image)
+}
@@ -0,0 +1,101 @@
+package conversions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	corev1alpha1 "github.com/k8sgpt-ai/k8sgpt-operator/api/v1alpha1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ErrOutOfScope is returned for a target outside the remediation scope of its instance
+var ErrOutOfScope = errors.New("outside the remediation scope")
+
+// RemediationScope decides which objects auto remediation is allowed to touch.
+// It combines the kind allowlist, the namespace include/exclude lists, the label
+// selector and the per-object opt-out annotation of an AutoRemediation spec.
+type RemediationScope struct {
+	resources         []string
+	namespaces        []string
+	excludeNamespaces []string
+	selector          labels.Selector
+}
+
+func NewRemediationScope(autoRemediation corev1alpha1.AutoRemediation) (*RemediationScope, error) {
+	selector := labels.Everything()
+	if autoRemediation.LabelSelector != nil {
+		var err error
+		selector, err = metav1.LabelSelectorAsSelector(autoRemediation.LabelSelector)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &RemediationScope{
+		resources:         autoRemediation.Resources,
+		namespaces:        autoRemediation.Namespaces,
+		excludeNamespaces: autoRemediation.ExcludeNamespaces,
+		selector:          selector,
+	}, nil
+}
+
+// AllowsKind reports whether the kind is in the resources allowlist
+func (s *RemediationScope) AllowsKind(kind string) bool {
+	return slices.Contains(s.resources, kind)
+}
+
+// AllowsNamespace reports whether objects in the namespace may be remediated
+func (s *RemediationScope) AllowsNamespace(namespace string) bool {
+	if slices.Contains(s.excludeNamespaces, namespace) {
+		return false
+	}
+	return len(s.namespaces) == 0 || slices.Contains(s.namespaces, namespace)
+}
+
+// AllowsObject reports whether the object matches the label selector and has not opted out
+func (s *RemediationScope) AllowsObject(obj metav1.Object) bool {
+	if obj.GetAnnotations()[corev1alpha1.AutoRemediationAnnotation] == corev1alpha1.AutoRemediationDisabled {
+		return false
+	}
+	return s.selector.Matches(labels.Set(obj.GetLabels()))
+}
+
+// CheckTarget checks the object a mutation writes, which for owned pods is their workload rather
+// than the object of the result. The opt-out annotation and labels are read from the live object,
+// a target that does not exist yet, e.g. a pod being recreated, is checked as it will be written.
+func (s *RemediationScope) CheckTarget(ctx context.Context, c client.Reader, target client.Object) error {
+	gvk := target.GetObjectKind().GroupVersionKind()
+	if !s.AllowsKind(gvk.Kind) {
+		return fmt.Errorf("%w: kind %s is not in the remediated resources", ErrOutOfScope, gvk.Kind)
+	}
+	if !s.AllowsNamespace(target.GetNamespace()) {
+		return fmt.Errorf("%w: namespace %s is not remediated", ErrOutOfScope, target.GetNamespace())
+	}
+	live := &unstructured.Unstructured{}
+	live.SetGroupVersionKind(gvk)
+	var obj metav1.Object = target
+	if err := c.Get(ctx, client.ObjectKeyFromObject(target), live); err == nil {
+		obj = live
+	} else if !apierrors.IsNotFound(err) {
+		return err
+	}
+	if !s.AllowsObject(obj) {
+		return fmt.Errorf("%w: %s %s/%s opted out or does not match the label selector", ErrOutOfScope,
+			gvk.Kind, target.GetNamespace(), target.GetName())
+	}
+	return nil
+}
+
+// CheckScope checks a target returned by ResolveTarget against the remediation scope of the
+// config, targets are not checked when it has none
+func CheckScope(config ObjectExecutionConfig, target client.Object) error {
+	if config.Scope == nil {
+		return nil
+	}
+	return config.Scope.CheckTarget(config.Ctx, config.Rc, target)
+}
@@ -0,0 +1,135 @@
+package conversions
+
+import (
+	"context"
+	"testing"
+
+	corev1alpha1 "github.com/k8sgpt-ai/k8sgpt-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_RemediationScope(t *testing.T) {
+	scope, err := NewRemediationScope(corev1alpha1.AutoRemediation{
+		Resources:         []string{"Pod", "Service"},
+		Namespaces:        []string{"staging", "production"},
+		ExcludeNamespaces: []string{"production"},
+		LabelSelector: &metav1.LabelSelector{
+			MatchLabels: map[string]string{"team": "payments"},
+		},
+	})
+	require.NoError(t, err)
+
+	assert.True(t, scope.AllowsKind("Pod"))
+	assert.False(t, scope.AllowsKind("Deployment"))
+
+	assert.True(t, scope.AllowsNamespace("staging"))
+	assert.False(t, scope.AllowsNamespace("production"), "exclusions take precedence")
+	assert.False(t, scope.AllowsNamespace("default"))
+
+	assert.True(t, scope.AllowsObject(&metav1.ObjectMeta{Labels: map[string]string{"team": "payments"}}))
+	assert.False(t, scope.AllowsObject(&metav1.ObjectMeta{Labels: map[string]string{"team": "search"}}))
+	assert.False(t, scope.AllowsObject(&metav1.ObjectMeta{
+		Labels:      map[string]string{"team": "payments"},
+		Annotations: map[string]string{corev1alpha1.AutoRemediationAnnotation: corev1alpha1.AutoRemediationDisabled},
+	}))
+}
+
+func Test_RemediationScopeDefaultsToAllNamespacesAndLabels(t *testing.T) {
+	scope, err := NewRemediationScope(corev1alpha1.AutoRemediation{Resources: []string{"Pod"}})
+	require.NoError(t, err)
+
+	assert.True(t, scope.AllowsNamespace("anything"))
+	assert.True(t, scope.AllowsObject(&metav1.ObjectMeta{}))
+}
+
+func Test_RemediationScopeCheckTarget(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	deployment := &appsv1.Deployment{
+		TypeMeta: metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+		ObjectMeta: metav1.ObjectMeta{Namespace: "staging", Name: "web",
+			Annotations: map[string]string{corev1alpha1.AutoRemediationAnnotation: corev1alpha1.AutoRemediationDisabled}},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(deployment.DeepCopy()).Build()
+	scope, err := NewRemediationScope(corev1alpha1.AutoRemediation{Resources: []string{"Pod", "Deployment"}})
+	require.NoError(t, err)
+
+	// The annotation of the live object counts, not the one of the target
+	target := deployment.DeepCopy()
+	target.Annotations = nil
+	assert.ErrorIs(t, scope.CheckTarget(context.Background(), c, target), ErrOutOfScope)
+
+	// A target that does not exist yet is checked as it will be written
+	pod := &corev1.Pod{TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+		ObjectMeta: metav1.ObjectMeta{Namespace: "staging", Name: "web-0"}}
+	assert.NoError(t, scope.CheckTarget(context.Background(), c, pod))
+
+	scope, err = NewRemediationScope(corev1alpha1.AutoRemediation{Resources: []string{"Pod"}})
+	require.NoError(t, err)
+	target.Name = "api"
+	assert.ErrorIs(t, scope.CheckTarget(context.Background(), c, target), ErrOutOfScope)
+}
+
+func Test_ResultsToEligibleResourcesHonoursScope(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, corev1alpha1.AddToScheme(scheme))
+
+	newPod := func(namespace, name string, annotations map[string]string) *corev1.Pod {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, Annotations: annotations}}
+	}
+	newResult := func(kind, name string) corev1alpha1.Result {
+		return corev1alpha1.Result{
+			TypeMeta:   metav1.TypeMeta{APIVersion: corev1alpha1.GroupVersion.String(), Kind: "Result"},
+			ObjectMeta: metav1.ObjectMeta{Namespace: "k8sgpt", Name: kind + name},
+			Spec:       corev1alpha1.ResultSpec{Kind: kind, Name: name},
+		}
+	}
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		newPod("staging", "broken", nil),
+		newPod("staging", "opted-out", map[string]string{corev1alpha1.AutoRemediationAnnotation: corev1alpha1.AutoRemediationDisabled}),
+		newPod("production", "broken", nil),
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Namespace: "staging", Name: "broken"}},
+	).Build()
+
+	config := &corev1alpha1.K8sGPT{
+		Spec: corev1alpha1.K8sGPTSpec{
+			AI: &corev1alpha1.AISpec{
+				AutoRemediation: corev1alpha1.AutoRemediation{
+					Enabled:           true,
+					Resources:         []string{"Pod"},
+					ExcludeNamespaces: []string{"production"},
+				},
+			},
+		},
+	}
+	results := &corev1alpha1.ResultList{Items: []corev1alpha1.Result{
+		newResult("Pod", "staging/broken"),
+		newResult("Pod", "staging/opted-out"),
+		newResult("Pod", "production/broken"),
+		newResult("Service", "staging/broken"),
+	}}
+
+	eligible := ResultsToEligibleResources(config, c, scheme, ctrl.Log, results)
+
+	require.Len(t, eligible, 1)
+	assert.Equal(t, "Pod", eligible[0].ObjectRef.Kind)
+	assert.Equal(t, "staging", eligible[0].ObjectRef.Namespace)
+	assert.Equal(t, "broken", eligible[0].ObjectRef.Name)
+
+	// Results below the minimum severity are not remediated
+	config.Spec.AI.AutoRemediation.MinSeverity = corev1alpha1.SeverityHigh
+	results.Items[0].Spec.Severity = corev1alpha1.SeverityMedium
+	assert.Empty(t, ResultsToEligibleResources(config, c, scheme, ctrl.Log, results))
+	results.Items[0].Spec.Severity = corev1alpha1.SeverityCritical
+	assert.Len(t, ResultsToEligibleResources(config, c, scheme, ctrl.Log, results), 1)
+}
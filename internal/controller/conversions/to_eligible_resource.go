@@ -7,8 +7,10 @@ import (
 	corev1alpha1 "github.com/k8sgpt-ai/k8sgpt-operator/api/v1alpha1"
 	"github.com/k8sgpt-ai/k8sgpt-operator/internal/controller/types"
 	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/tools/reference"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -16,8 +18,6 @@ import (
 	"strings"
 )
 
-// TODO: how do we stop results colliding by modifying the same resources
-// e.g. pod and deployment in the same replica set
 var (
 	SupportedResources = map[string]func(*[]types.EligibleResource,
 		client.Client, *runtime.Scheme,
@@ -107,9 +107,40 @@ var (
 
 			return nil
 		},
+		"StatefulSet": eligibleObject(func() client.Object { return &appsv1.StatefulSet{} }),
+		"DaemonSet":   eligibleObject(func() client.Object { return &appsv1.DaemonSet{} }),
+		"Job":         eligibleObject(func() client.Object { return &batchv1.Job{} }),
+		"CronJob":     eligibleObject(func() client.Object { return &batchv1.CronJob{} }),
 	}
 )
 
+// eligibleObject returns a SupportedResources entry that fetches an object of the kind
+// newObject creates and records its configuration
+func eligibleObject(newObject func() client.Object) func(*[]types.EligibleResource,
+	client.Client, *runtime.Scheme, logr.Logger, *corev1.ObjectReference, string, string) error {
+	return func(eligibleResources *[]types.EligibleResource, c client.Client, scheme *runtime.Scheme,
+		logger logr.Logger, resultRef *corev1.ObjectReference, namespace string, name string) error {
+		obj := newObject()
+		if err := c.Get(context.Background(), client.ObjectKey{Namespace: namespace, Name: name}, obj); err != nil {
+			return err
+		}
+		objRef, err := reference.GetReference(scheme, obj)
+		if err != nil {
+			return err
+		}
+		// Strip out the stuff we don't need
+		obj.SetManagedFields(nil)
+		yamlData, err := yaml.Marshal(obj)
+		if err != nil {
+			return err
+		}
+		*eligibleResources = append(*eligibleResources, types.EligibleResource{ResultRef: *resultRef,
+			ObjectRef: *objRef, OriginConfiguration: string(yamlData),
+			GVK: objRef.GroupVersionKind().String()})
+		return nil
+	}
+}
+
 func ResultsToEligibleResources(config *corev1alpha1.K8sGPT,
 	rc client.Client, scheme *runtime.Scheme,
 	logger logr.Logger, items *corev1alpha1.ResultList) []types.EligibleResource {
@@ -119,15 +150,34 @@ func ResultsToEligibleResources(config *corev1alpha1.K8sGPT,
 	// for remediation
 	var eligibleResources = []types.EligibleResource{}
 
+	scope, err := NewRemediationScope(config.Spec.AI.AutoRemediation)
+	if err != nil {
+		logger.Error(err, "invalid auto remediation label selector")
+		return eligibleResources
+	}
+
 	for _, item := range items.Items {
+		if !item.Spec.Severity.AtLeast(config.Spec.AI.AutoRemediation.MinSeverity) {
+			logger.Info("Resource below the auto remediation minimum severity", "ResourceRef", item.Name,
+				"Severity", item.Spec.Severity)
+			continue
+		}
+		if !scope.AllowsKind(item.Spec.Kind) {
+			logger.Info("Resource kind not enabled for auto remediation", "ResourceRef", item.Name, "Kind", item.Spec.Kind)
+			continue
+		}
 		//demangle the name of the resource
 		names := strings.Split(item.Spec.Name, "/")
-		namespace := names[0]
-		name := names[1]
 		if len(names) != 2 {
 			logger.Error(fmt.Errorf("invalid resource name"), "unable to parse resource name", "ResourceRef", item.Name)
 			continue
 		}
+		namespace := names[0]
+		name := names[1]
+		if !scope.AllowsNamespace(namespace) {
+			logger.Info("Resource namespace not in auto remediation scope", "ResourceRef", item.Name, "Namespace", namespace)
+			continue
+		}
 		// create reference from the result
 		resultRef, err := reference.GetReference(scheme, &item)
 		if err != nil {
@@ -138,6 +188,12 @@ func ResultsToEligibleResources(config *corev1alpha1.K8sGPT,
 			err := supportedResource(&eligibleResources, rc, scheme, logger, resultRef, namespace, name)
 			if err != nil {
 				logger.Error(err, "unable to create eligible resource", "ResourceRef", item.Name)
+				continue
+			}
+			// The object is only known once fetched, drop it again if it is outside the label scope or opted out
+			if !objectInScope(scope, eligibleResources[len(eligibleResources)-1], logger) {
+				logger.Info("Resource excluded from auto remediation", "ResourceRef", item.Name)
+				eligibleResources = eligibleResources[:len(eligibleResources)-1]
 			}
 		} else {
 			logger.Info("Resource not supported", "ResourceRef", item.Name, "Kind", item.Spec.Kind)
@@ -145,3 +201,12 @@ func ResultsToEligibleResources(config *corev1alpha1.K8sGPT,
 	}
 	return eligibleResources
 }
+
+func objectInScope(scope *RemediationScope, resource types.EligibleResource, logger logr.Logger) bool {
+	var obj metav1.PartialObjectMetadata
+	if err := yaml.Unmarshal([]byte(resource.OriginConfiguration), &obj); err != nil {
+		logger.Error(err, "unable to decode eligible resource", "ResourceRef", resource.ObjectRef.Name)
+		return false
+	}
+	return scope.AllowsObject(&obj)
+}
@@ -16,23 +16,22 @@ package k8sgpt
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/go-logr/logr"
 	corev1alpha1 "github.com/k8sgpt-ai/k8sgpt-operator/api/v1alpha1"
+	metricspkg "github.com/k8sgpt-ai/k8sgpt-operator/pkg/metrics"
 	"github.com/k8sgpt-ai/k8sgpt-operator/pkg/resources"
+	"github.com/k8sgpt-ai/k8sgpt-operator/pkg/sinks"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
-var (
-	// allowBackendAIRequest a circuit breaker that switching on/off backend AI calls
-	allowBackendAIRequest = true
-	// analysisRetryCount is for the number of analysis failures
-	analysisRetryCount int
-)
-
 type AnalysisStep struct {
 	next                K8sGPT
 	enableResultLogging bool
@@ -49,31 +48,55 @@ type AnalysisLogStatement struct {
 func (step *AnalysisStep) execute(instance *K8sGPTInstance) (ctrl.Result, error) {
 	instance.logger.Info("starting AnalysisStep")
 
-	response, err := instance.kclient.ProcessAnalysis(*instance.k8sgptDeployment, instance.K8sgptConfig, allowBackendAIRequest)
+	start := time.Now()
+	var breaker *aiCircuitBreaker
+	allowAIRequest := true
+	if instance.K8sgptConfig.Spec.AI.Enabled {
+		breaker = newAICircuitBreaker(instance.K8sgptConfig)
+		allowAIRequest = breaker.allow(start)
+	} else {
+		instance.K8sgptConfig.Status.AIBackendCircuit = nil
+	}
+
+	response, err := instance.kclient.ProcessAnalysis(*instance.k8sgptDeployment, instance.K8sgptConfig, allowAIRequest)
 	if err != nil {
-		if instance.K8sgptConfig.Spec.AI.Enabled {
+		if breaker != nil && allowAIRequest {
 			step.incK8sgptNumberOfFailedBackendAICalls(instance)
-			step.handleAIFailureBackoff(instance)
+			step.handleAIFailure(instance, breaker, err)
 		}
+		instance.setCondition(corev1alpha1.ConditionAnalysisSucceeded, metav1.ConditionFalse, "AnalysisFailed", err.Error())
 		return instance.R.FinishReconcile(err, false, instance.K8sgptConfig.Name, instance.K8sgptConfig)
 	}
 	step.logger.Info("AnalysisStep response", "count", len(response.Results))
+	if breaker != nil && allowAIRequest {
+		breaker.recordSuccess(time.Now())
+	}
+	step.setAIBackendStatus(instance, breaker)
 
-	// reset analysisRetryCount
-	analysisRetryCount = 0
 	// Parse the k8sgpt-deployment response into a list of results
-	step.setk8sgptNumberOfResults(instance, response.Results)
+	step.setSeverityRulesCondition(instance)
+	rawResults := resources.MapResults(*instance.R.Integrations, response.Results, *instance.K8sgptConfig)
+	step.setk8sgptNumberOfResults(instance, rawResults)
 
-	rawResults, err := resources.MapResults(*instance.R.Integrations, response.Results, *instance.K8sgptConfig)
-	if err != nil {
+	// Results named before names included the kind and a hash are renamed once, before they
+	// would be taken for stale results
+	if renamed, err := resources.MigrateResultNames(instance.Ctx, instance.R.Client, *instance.K8sgptConfig); err != nil {
+		instance.setCondition(corev1alpha1.ConditionAnalysisSucceeded, metav1.ConditionFalse, "ResultsNotStored", err.Error())
 		return instance.R.FinishReconcile(err, false, instance.K8sgptConfig.Name, instance.K8sgptConfig)
+	} else if renamed > 0 {
+		step.logger.Info("Renamed results", "count", renamed)
 	}
 
+	// Results evicted for their age are not stored again, nor sent as new, until they change
+	step.leaveOutEvicted(rawResults, instance, metav1.Now())
+
 	// Prior to creating or updating any results we will delete any stale results that
 	// no longer are relevent, we can do this by using the resultSpec composed name against
-	// the custom resource name
+	// the custom resource name. Results over the retention cap are left out of rawResults and
+	// evicted here too.
 	err = step.cleanUpStaleResults(rawResults, instance)
 	if err != nil {
+		instance.setCondition(corev1alpha1.ConditionAnalysisSucceeded, metav1.ConditionFalse, "ResultsNotStored", err.Error())
 		return instance.R.FinishReconcile(err, false, instance.K8sgptConfig.Name, instance.K8sgptConfig)
 	}
 
@@ -81,8 +104,10 @@ func (step *AnalysisStep) execute(instance *K8sGPTInstance) (ctrl.Result, error)
 	// them as needed
 	err = step.processRawResults(rawResults, instance)
 	if err != nil {
+		instance.setCondition(corev1alpha1.ConditionAnalysisSucceeded, metav1.ConditionFalse, "ResultsNotStored", err.Error())
 		return instance.R.FinishReconcile(err, false, instance.K8sgptConfig.Name, instance.K8sgptConfig)
 	}
+	step.setAnalysisStatus(instance, rawResults, time.Since(start))
 
 	instance.logger.Info("ending AnalysisStep")
 
@@ -94,15 +119,63 @@ func (step *AnalysisStep) setNext(next K8sGPT) {
 	step.next = next
 }
 
-func (step *AnalysisStep) handleAIFailureBackoff(instance *K8sGPTInstance) {
-	if instance.K8sgptConfig.Spec.AI.BackOff.Enabled {
-		if analysisRetryCount > instance.K8sgptConfig.Spec.AI.BackOff.MaxRetries {
-			allowBackendAIRequest = false
-			instance.logger.Info(fmt.Sprintf("Disabled AI backend %s due to failures exceeding max retries\n", instance.K8sgptConfig.Spec.AI.Backend))
-			analysisRetryCount = 0
+func (step *AnalysisStep) setAIBackendStatus(instance *K8sGPTInstance, breaker *aiCircuitBreaker) {
+	step.setCircuitBreakerMetric(instance, breaker)
+	switch {
+	case breaker == nil:
+		instance.setCondition(corev1alpha1.ConditionAIBackendAvailable, metav1.ConditionUnknown, "Disabled", "AI explanations are disabled")
+	case breaker.status.State == corev1alpha1.CircuitBreakerOpen:
+		instance.setCondition(corev1alpha1.ConditionAIBackendAvailable, metav1.ConditionFalse, "CircuitOpen",
+			fmt.Sprintf("AI backend %s disabled after repeated failures until %s: %s", instance.K8sgptConfig.Spec.AI.Backend,
+				breaker.status.OpenedAt.Add(breaker.cooldown()).Format(time.RFC3339), breaker.status.Reason))
+	default:
+		instance.setCondition(corev1alpha1.ConditionAIBackendAvailable, metav1.ConditionTrue, "BackendResponding",
+			fmt.Sprintf("AI backend %s is responding", instance.K8sgptConfig.Spec.AI.Backend))
+	}
+}
+
+func (step *AnalysisStep) setAnalysisStatus(instance *K8sGPTInstance, rawResults map[string]corev1alpha1.Result, duration time.Duration) {
+	resultsByKind := make(map[string]int)
+	for _, result := range rawResults {
+		resultsByKind[result.Spec.Kind]++
+	}
+	status := &instance.K8sgptConfig.Status
+	// The timestamps are kept while the results do not change, so an analysis that finds the same
+	// results does not write the status
+	if status.LastAnalysisTime == nil || status.ResultCount != len(rawResults) ||
+		!equality.Semantic.DeepEqual(status.ResultsByKind, resultsByKind) {
+		now := metav1.Now()
+		status.LastAnalysisTime = &now
+		status.LastAnalysisDuration = &metav1.Duration{Duration: duration.Round(time.Millisecond)}
+	}
+	status.ResultCount = len(rawResults)
+	status.ResultsByKind = resultsByKind
+	instance.setCondition(corev1alpha1.ConditionAnalysisSucceeded, metav1.ConditionTrue, "AnalysisCompleted",
+		fmt.Sprintf("analysis found %d result(s)", len(rawResults)))
+}
+
+func (step *AnalysisStep) handleAIFailure(instance *K8sGPTInstance, breaker *aiCircuitBreaker, err error) {
+	if reason := breaker.recordFailure(time.Now(), err); reason != "" {
+		instance.logger.Info(fmt.Sprintf("Disabled AI backend %s for %s due to %s", instance.K8sgptConfig.Spec.AI.Backend, breaker.cooldown(), reason))
+		tripCounter := instance.R.MetricsBuilder.GetCounterVec("k8sgpt_ai_circuit_breaker_trips")
+		if tripCounter != nil {
+			tripCounter.WithLabelValues(instance.K8sgptConfig.Spec.AI.Backend, instance.K8sgptConfig.Namespace, instance.K8sgptConfig.Name, reason).Inc()
 		}
-		analysisRetryCount++
 	}
+	step.setCircuitBreakerMetric(instance, breaker)
+	instance.setCondition(corev1alpha1.ConditionAIBackendAvailable, metav1.ConditionFalse, "BackendRequestFailed", err.Error())
+}
+
+func (step *AnalysisStep) setCircuitBreakerMetric(instance *K8sGPTInstance, breaker *aiCircuitBreaker) {
+	stateGauge := instance.R.MetricsBuilder.GetGaugeVec("k8sgpt_ai_circuit_breaker_state")
+	if stateGauge == nil {
+		return
+	}
+	if breaker == nil {
+		stateGauge.DeleteLabelValues(instance.K8sgptConfig.Spec.AI.Backend, instance.K8sgptConfig.Namespace, instance.K8sgptConfig.Name)
+		return
+	}
+	stateGauge.WithLabelValues(instance.K8sgptConfig.Spec.AI.Backend, instance.K8sgptConfig.Namespace, instance.K8sgptConfig.Name).Set(breaker.stateValue())
 }
 
 func (step *AnalysisStep) incK8sgptNumberOfFailedBackendAICalls(instance *K8sGPTInstance) {
@@ -112,7 +185,16 @@ func (step *AnalysisStep) incK8sgptNumberOfFailedBackendAICalls(instance *K8sGPT
 	}
 }
 
-func (step *AnalysisStep) setk8sgptNumberOfResults(instance *K8sGPTInstance, results []corev1alpha1.ResultSpec) {
+func (step *AnalysisStep) setk8sgptNumberOfResults(instance *K8sGPTInstance, rawResults map[string]corev1alpha1.Result) {
+	var results []corev1alpha1.ResultSpec
+	resultsBySeverity := make(map[corev1alpha1.Severity]int)
+	for _, result := range rawResults {
+		if !result.Spec.Severity.AtLeast(metricsMinSeverity(instance)) {
+			continue
+		}
+		results = append(results, result.Spec)
+		resultsBySeverity[result.Spec.Severity]++
+	}
 	groupedResults := step.getResultsPerNamespace(results)
 	numberOfResultsGauge := instance.R.MetricsBuilder.GetGaugeVec("k8sgpt_number_of_results")
 	if numberOfResultsGauge != nil {
@@ -120,6 +202,26 @@ func (step *AnalysisStep) setk8sgptNumberOfResults(instance *K8sGPTInstance, res
 			numberOfResultsGauge.WithLabelValues(namespace, instance.K8sgptConfig.Name).Set(float64(count))
 		}
 	}
+	numberOfResultsBySeverity := instance.R.MetricsBuilder.GetGaugeVec("k8sgpt_number_of_results_by_severity")
+	if numberOfResultsBySeverity != nil {
+		for _, severity := range []corev1alpha1.Severity{corev1alpha1.SeverityInfo, corev1alpha1.SeverityLow,
+			corev1alpha1.SeverityMedium, corev1alpha1.SeverityHigh, corev1alpha1.SeverityCritical} {
+			if !severity.AtLeast(metricsMinSeverity(instance)) {
+				numberOfResultsBySeverity.DeleteLabelValues(string(severity), instance.K8sgptConfig.Name)
+				continue
+			}
+			numberOfResultsBySeverity.WithLabelValues(string(severity), instance.K8sgptConfig.Name).
+				Set(float64(resultsBySeverity[severity]))
+		}
+	}
+}
+
+// metricsMinSeverity is the lowest severity of the results counted in the result metrics
+func metricsMinSeverity(instance *K8sGPTInstance) corev1alpha1.Severity {
+	if instance.K8sgptConfig.Spec.Metrics == nil {
+		return ""
+	}
+	return instance.K8sgptConfig.Spec.Metrics.MinSeverity
 }
 
 func (step *AnalysisStep) getResultsPerNamespace(results []corev1alpha1.ResultSpec) map[string]int {
@@ -154,26 +256,160 @@ func (step *AnalysisStep) cleanUpStaleResults(rawResults map[string]corev1alpha1
 		return err
 	}
 
-	if len(resultList.Items) > 0 {
-		for _, result := range resultList.Items {
-			instance.logger.Info(fmt.Sprintf("checking if %s is still relevant", result.Name))
-			if _, ok := rawResults[result.Name]; !ok {
-				err := instance.R.Delete(instance.Ctx, &result)
-				if err != nil {
+	now := metav1.Now()
+	status := &instance.K8sgptConfig.Status
+	// The tombstones added below count towards the limit too
+	defer func() {
+		status.ResultTombstones = resources.PruneTombstones(status.ResultTombstones, now,
+			resources.TombstoneTTL(*instance.K8sgptConfig), resources.MaxTombstones(*instance.K8sgptConfig))
+	}()
+	evicted := step.capResults(rawResults, resultList.Items, instance, now)
+	var configured []configuredSink
+	sinksLoaded := false
+	for _, result := range resultList.Items {
+		instance.logger.Info(fmt.Sprintf("checking if %s is still relevant", result.Name))
+		if _, ok := rawResults[result.Name]; ok {
+			continue
+		}
+		if evicted[result.Name] {
+			// Evicted results are not resolved, the sinks are not told
+			if err := instance.R.Delete(instance.Ctx, &result); client.IgnoreNotFound(err) != nil {
+				return err
+			}
+			status.ResultTombstones = append(status.ResultTombstones,
+				resources.EvictionTombstone(result, resources.EvictedMaxResults, now))
+			countEviction(instance.R.MetricsBuilder, instance.K8sgptConfig, resources.EvictedMaxResults)
+			continue
+		}
+		if !sinksLoaded {
+			configured = initSinks(instance)
+			sinksLoaded = true
+		}
+		if result.Status.LifeCycle != string(resources.ResolvedResult) {
+			result.Status.LifeCycle = string(resources.ResolvedResult)
+			if err := instance.R.Status().Update(instance.Ctx, &result); err != nil {
+				return err
+			}
+		}
+		if failures := step.emitResolved(&result, configured, now); len(failures) > 0 {
+			err := errors.Join(failures...)
+			instance.logger.Error(err, "could not report resolved result", "name", result.Name)
+			instance.sinkFailures = append(instance.sinkFailures, failures...)
+			setSinkCondition(instance, configured, instance.sinkFailures)
+			// The result is kept and reported again to the sinks that missed it by the next analysis
+			// run, until it is older than the history of resolved results
+			if !resolvedExpired(result, instance.K8sgptConfig, now) {
+				if err := instance.R.Status().Update(instance.Ctx, &result); err != nil {
 					return err
 				}
-				numberOfResultsByType := instance.R.MetricsBuilder.GetGaugeVec("k8sgpt_number_of_results_by_type")
-				if numberOfResultsByType != nil {
-					resultObjectNamespace := step.getResultObjectNamespace(result.Spec)
-					numberOfResultsByType.WithLabelValues(resultObjectNamespace, result.Spec.Kind, result.Spec.Name, instance.K8sgptConfig.Name).Desc()
-				}
-
+				continue
 			}
+			instance.logger.Info("Deleting resolved result the sinks could not be told about", "name", result.Name)
+		}
+		err := instance.R.Delete(instance.Ctx, &result)
+		if err != nil {
+			return err
+		}
+		// Keep the history of the result in case it reappears
+		status.ResultTombstones = append(status.ResultTombstones, resources.Tombstone(result, now))
+		numberOfResultsByType := instance.R.MetricsBuilder.GetGaugeVec("k8sgpt_number_of_results_by_type")
+		if numberOfResultsByType != nil {
+			resultObjectNamespace := step.getResultObjectNamespace(result.Spec)
+			numberOfResultsByType.WithLabelValues(resultObjectNamespace, result.Spec.Kind, result.Spec.Name, instance.K8sgptConfig.Name).Desc()
 		}
 	}
 	return nil
 }
 
+// leaveOutEvicted leaves the results the retention evicted for their age and that did not change
+// since out of the raw results. Their tombstones are kept while analysis runs find them.
+func (step *AnalysisStep) leaveOutEvicted(rawResults map[string]corev1alpha1.Result, instance *K8sGPTInstance, now metav1.Time) {
+	tombstones := instance.K8sgptConfig.Status.ResultTombstones
+	for i := range tombstones {
+		result, ok := rawResults[tombstones[i].Name]
+		if !ok || !resources.EvictedUnchanged(tombstones[i], result.Spec) {
+			continue
+		}
+		delete(rawResults, result.Name)
+		tombstones[i].ResolvedAt = now
+		step.logger.Info("Left out result evicted for its age", "name", result.Name)
+	}
+}
+
+// resolvedExpired reports whether the result was last seen longer ago than the K8sGPT instance
+// keeps the history of resolved results
+func resolvedExpired(result corev1alpha1.Result, config *corev1alpha1.K8sGPT, now metav1.Time) bool {
+	lastSeen := result.CreationTimestamp
+	if result.Status.LastSeen != nil {
+		lastSeen = *result.Status.LastSeen
+	}
+	return now.Sub(lastSeen.Time) >= resources.TombstoneTTL(*config)
+}
+
+// capResults leaves the results over the maximum result count of the instance out of the raw
+// results and returns the stored results among them. Stored results count as found when they
+// were first found, so they are not replaced by new results of the same severity.
+func (step *AnalysisStep) capResults(rawResults map[string]corev1alpha1.Result, stored []corev1alpha1.Result,
+	instance *K8sGPTInstance, now metav1.Time) map[string]bool {
+	retention := instance.K8sgptConfig.Spec.Retention
+	if retention == nil || retention.MaxResults <= 0 || len(rawResults) <= retention.MaxResults {
+		return nil
+	}
+	storedByName := make(map[string]corev1alpha1.Result, len(stored))
+	for _, result := range stored {
+		storedByName[result.Name] = result
+	}
+	candidates := make([]corev1alpha1.Result, 0, len(rawResults))
+	for name, result := range rawResults {
+		candidate := *result.DeepCopy()
+		if existing, ok := storedByName[name]; ok {
+			candidate.CreationTimestamp = existing.CreationTimestamp
+			candidate.Status.FirstSeen = existing.Status.FirstSeen
+		} else {
+			candidate.CreationTimestamp = now
+		}
+		candidates = append(candidates, candidate)
+	}
+
+	evicted := map[string]bool{}
+	overflow := resources.OverflowResults(candidates, retention.MaxResults)
+	for _, result := range overflow {
+		delete(rawResults, result.Name)
+		if _, ok := storedByName[result.Name]; ok {
+			evicted[result.Name] = true
+		}
+	}
+	step.logger.Info("Left out results over the retention cap", "count", len(overflow), "max", retention.MaxResults)
+	return evicted
+}
+
+// countEviction counts a result the retention of the K8sGPT instance evicted
+func countEviction(metricsBuilder *metricspkg.MetricBuilder, config *corev1alpha1.K8sGPT, reason string) {
+	evictions := metricsBuilder.GetCounterVec("k8sgpt_number_of_evicted_results")
+	if evictions != nil {
+		evictions.WithLabelValues(config.Namespace, config.Name, reason).Inc()
+	}
+}
+
+// emitResolved tells the sinks that received the result and whose filters match that its
+// problem is resolved and how long it lasted, and returns the deliveries that failed
+func (step *AnalysisStep) emitResolved(result *corev1alpha1.Result, configured []configuredSink, now metav1.Time) []error {
+	var failures []error
+	for _, sink := range configured {
+		delivery := sinks.Delivery(result.Status, sink.Name)
+		received := delivery != nil &&
+			(delivery.Error == "" || delivery.LifeCycle != string(corev1alpha1.SinkLifeCycleCreated))
+		if !received || (delivery.LifeCycle == string(corev1alpha1.SinkLifeCycleResolved) && delivery.Error == "") ||
+			!sinks.Matches(sink.SinkSpec, result.Spec, corev1alpha1.SinkLifeCycleResolved) {
+			continue
+		}
+		if err := sink.deliver(result, corev1alpha1.SinkLifeCycleResolved, now); err != nil {
+			failures = append(failures, err)
+		}
+	}
+	return failures
+}
+
 func (step *AnalysisStep) processRawResults(rawResults map[string]corev1alpha1.Result, instance *K8sGPTInstance) error {
 
 	numberOfResultsByType := instance.R.MetricsBuilder.GetGaugeVec("k8sgpt_number_of_results_by_type")
@@ -181,7 +417,8 @@ func (step *AnalysisStep) processRawResults(rawResults map[string]corev1alpha1.R
 		numberOfResultsByType.Reset()
 	}
 	for _, result := range rawResults {
-		result, err := resources.CreateOrUpdateResult(instance.Ctx, instance.R.Client, result)
+		result, err := resources.CreateOrUpdateResult(instance.Ctx, instance.R.Client, result,
+			takeTombstone(&instance.K8sgptConfig.Status, result.Name))
 		if err != nil {
 			return err
 		}
@@ -206,9 +443,37 @@ func (step *AnalysisStep) processRawResults(rawResults map[string]corev1alpha1.R
 			}
 			step.logger.Info(string(jsonBytes))
 		}
+		if !result.Spec.Severity.AtLeast(metricsMinSeverity(instance)) {
+			continue
+		}
 		resultObjectNamespace := step.getResultObjectNamespace(result.Spec)
 		numberOfResultsByType.WithLabelValues(resultObjectNamespace, result.Spec.Kind, result.Spec.Name, instance.K8sgptConfig.Name).Inc()
 	}
 
 	return nil
 }
+
+// takeTombstone removes the tombstone of the result from the status and returns it, if there is one
+func takeTombstone(status *corev1alpha1.K8sGPTStatus, name string) *corev1alpha1.ResultTombstone {
+	for i, tombstone := range status.ResultTombstones {
+		if tombstone.Name == name {
+			status.ResultTombstones = append(status.ResultTombstones[:i], status.ResultTombstones[i+1:]...)
+			return &tombstone
+		}
+	}
+	return nil
+}
+
+// setSeverityRulesCondition reports the severity rules that are skipped for being invalid
+func (step *AnalysisStep) setSeverityRulesCondition(instance *K8sGPTInstance) {
+	var rules []corev1alpha1.SeverityRule
+	if instance.K8sgptConfig.Spec.Analysis != nil {
+		rules = instance.K8sgptConfig.Spec.Analysis.SeverityRules
+	}
+	if err := resources.ValidateSeverityRules(rules); err != nil {
+		step.logger.Info("Skipping invalid severity rules", "error", err.Error())
+		instance.setCondition(corev1alpha1.ConditionSeverityRulesValid, metav1.ConditionFalse, "InvalidRules", err.Error())
+		return
+	}
+	instance.setCondition(corev1alpha1.ConditionSeverityRulesValid, metav1.ConditionTrue, "Valid", "all severity rules are valid")
+}
@@ -14,10 +14,23 @@ limitations under the License.
 package k8sgpt
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/go-logr/logr"
 	corev1alpha1 "github.com/k8sgpt-ai/k8sgpt-operator/api/v1alpha1"
+	metricspkg "github.com/k8sgpt-ai/k8sgpt-operator/pkg/metrics"
+	"github.com/k8sgpt-ai/k8sgpt-operator/pkg/resources"
+	"github.com/k8sgpt-ai/k8sgpt-operator/pkg/sinks"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
 
 var _ = Describe("AnalysisStep", func() {
@@ -68,4 +81,122 @@ var _ = Describe("AnalysisStep", func() {
 			})
 		})
 	})
+
+	Describe("setAnalysisStatus", func() {
+		It("should keep the timestamps while the results do not change", func() {
+			instance := &K8sGPTInstance{K8sgptConfig: &corev1alpha1.K8sGPT{}}
+			results := map[string]corev1alpha1.Result{
+				"pod-web": {Spec: corev1alpha1.ResultSpec{Kind: "Pod", Name: "default/web"}},
+			}
+			step.setAnalysisStatus(instance, results, time.Second)
+			first := instance.K8sgptConfig.Status.DeepCopy()
+
+			step.setAnalysisStatus(instance, results, 2*time.Second)
+			Expect(instance.K8sgptConfig.Status).To(Equal(*first))
+
+			results["node-a"] = corev1alpha1.Result{Spec: corev1alpha1.ResultSpec{Kind: "Node", Name: "a"}}
+			step.setAnalysisStatus(instance, results, 2*time.Second)
+			Expect(instance.K8sgptConfig.Status.LastAnalysisDuration.Duration).To(Equal(2 * time.Second))
+			Expect(instance.K8sgptConfig.Status.ResultsByKind).To(HaveKeyWithValue("Node", 1))
+		})
+	})
+
+	Describe("leaveOutEvicted", func() {
+		It("should leave out the results evicted for their age until they change", func() {
+			evicted := corev1alpha1.Result{
+				ObjectMeta: metav1.ObjectMeta{Name: "pod-web"},
+				Spec: corev1alpha1.ResultSpec{Kind: "Pod", Name: "default/web",
+					Error: []corev1alpha1.Failure{{Text: "back-off restarting failed container"}}},
+			}
+			resolvedAt := metav1.NewTime(time.Now().Add(-time.Minute))
+			tombstone := resources.EvictionTombstone(evicted, resources.EvictedMaxAge, resolvedAt)
+			instance := &K8sGPTInstance{K8sgptConfig: &corev1alpha1.K8sGPT{
+				Status: corev1alpha1.K8sGPTStatus{ResultTombstones: []corev1alpha1.ResultTombstone{tombstone}},
+			}}
+
+			now := metav1.Now()
+			rawResults := map[string]corev1alpha1.Result{evicted.Name: evicted}
+			step.leaveOutEvicted(rawResults, instance, now)
+			Expect(rawResults).To(BeEmpty())
+			Expect(instance.K8sgptConfig.Status.ResultTombstones[0].ResolvedAt).To(Equal(now))
+
+			changed := *evicted.DeepCopy()
+			changed.Spec.Error = append(changed.Spec.Error, corev1alpha1.Failure{Text: "image not found"})
+			rawResults = map[string]corev1alpha1.Result{changed.Name: changed}
+			step.leaveOutEvicted(rawResults, instance, now)
+			Expect(rawResults).To(HaveKey(changed.Name))
+		})
+	})
+
+	Describe("setSeverityRulesCondition", func() {
+		It("should report the severity rules that are skipped", func() {
+			instance := &K8sGPTInstance{K8sgptConfig: &corev1alpha1.K8sGPT{Spec: corev1alpha1.K8sGPTSpec{
+				Analysis: &corev1alpha1.AnalysisConfig{SeverityRules: []corev1alpha1.SeverityRule{
+					{Kind: "Pod", Pattern: "[", Severity: corev1alpha1.SeverityLow}}},
+			}}}
+			step.setSeverityRulesCondition(instance)
+			condition := meta.FindStatusCondition(instance.K8sgptConfig.Status.Conditions, corev1alpha1.ConditionSeverityRulesValid)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Message).To(ContainSubstring("severity rule 0"))
+
+			instance.K8sgptConfig.Spec.Analysis.SeverityRules[0].Pattern = "back-off"
+			step.setSeverityRulesCondition(instance)
+			condition = meta.FindStatusCondition(instance.K8sgptConfig.Status.Conditions, corev1alpha1.ConditionSeverityRulesValid)
+			Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+		})
+	})
+
+	Describe("cleanUpStaleResults", func() {
+		It("should give up on resolved results the sinks keep failing on", func() {
+			failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}))
+			DeferCleanup(failing.Close)
+			resolved := func(name string, lastSeen time.Duration) *corev1alpha1.Result {
+				seen := metav1.NewTime(time.Now().Add(-lastSeen))
+				return &corev1alpha1.Result{
+					ObjectMeta: metav1.ObjectMeta{Namespace: "k8sgpt", Name: name, Labels: map[string]string{
+						"k8sgpts.k8sgpt.ai/name":      "k8sgpt",
+						"k8sgpts.k8sgpt.ai/namespace": "k8sgpt",
+					}},
+					Spec: corev1alpha1.ResultSpec{Kind: "Pod", Name: "default/" + name},
+					Status: corev1alpha1.ResultStatus{LastSeen: &seen, Deliveries: []corev1alpha1.SinkDelivery{
+						{Name: sinks.DefaultSinkName, LifeCycle: string(corev1alpha1.SinkLifeCycleCreated)},
+					}},
+				}
+			}
+			scheme := runtime.NewScheme()
+			Expect(corev1alpha1.AddToScheme(scheme)).To(Succeed())
+			c := fake.NewClientBuilder().WithScheme(scheme).WithStatusSubresource(&corev1alpha1.Result{}).
+				WithObjects(resolved("recent", time.Minute), resolved("expired", 2*resources.DefaultTombstoneTTL)).
+				Build()
+			instance := &K8sGPTInstance{
+				R: &K8sGPTReconciler{Client: c, SinkClient: sinks.NewClient(2 * time.Second),
+					MetricsBuilder: metricspkg.InitializeMetrics()},
+				Ctx:    context.Background(),
+				logger: logr.Discard(),
+				K8sgptConfig: &corev1alpha1.K8sGPT{
+					ObjectMeta: metav1.ObjectMeta{Namespace: "k8sgpt", Name: "k8sgpt"},
+					Spec: corev1alpha1.K8sGPTSpec{
+						Sink: &corev1alpha1.WebhookRef{Type: "slack", Endpoint: failing.URL},
+					},
+				},
+			}
+
+			Expect(step.cleanUpStaleResults(map[string]corev1alpha1.Result{}, instance)).To(Succeed())
+			var results corev1alpha1.ResultList
+			Expect(c.List(context.Background(), &results)).To(Succeed())
+			Expect(results.Items).To(HaveLen(1))
+			Expect(results.Items[0].Name).To(Equal("recent"))
+			Expect(results.Items[0].Status.LifeCycle).To(Equal(string(resources.ResolvedResult)))
+			Expect(instance.K8sgptConfig.Status.ResultTombstones).To(HaveLen(1))
+			Expect(instance.sinkFailures).To(HaveLen(2))
+
+			// The sink condition of the results delivered later in the reconcile keeps the failures
+			setSinkCondition(instance, initSinks(instance), instance.sinkFailures)
+			condition := meta.FindStatusCondition(instance.K8sgptConfig.Status.Conditions, corev1alpha1.ConditionSinkHealthy)
+			Expect(condition.Reason).To(Equal("EmitFailed"))
+		})
+	})
 })
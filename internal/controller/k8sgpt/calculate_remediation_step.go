@@ -1,10 +1,15 @@
 package k8sgpt
 
 import (
+	"slices"
+
 	"github.com/go-logr/logr"
 	corev1alpha1 "github.com/k8sgpt-ai/k8sgpt-operator/api/v1alpha1"
 	"github.com/k8sgpt-ai/k8sgpt-operator/internal/controller/conversions"
+	"github.com/k8sgpt-ai/k8sgpt-operator/internal/controller/mutation"
 	"github.com/k8sgpt-ai/k8sgpt-operator/internal/controller/util"
+	"github.com/k8sgpt-ai/k8sgpt-operator/pkg/resources"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -46,30 +51,51 @@ func (step *calculateRemediationStep) execute(instance *K8sGPTInstance) (ctrl.Re
 		latestResultList)
 
 	// Merge results if they are duplicates or from the same origin e.g., pods in a rs
-	eligibleResources := util.Deduplicate(preEligibleResources, step.logger)
+	eligibleResources := util.Deduplicate(instance.Ctx, instance.R.Client, preEligibleResources, step.logger)
 
 	step.logger.Info("eligibleResources", "count", len(eligibleResources))
 
+	var mutations corev1alpha1.MutationList
+	if err := instance.R.List(instance.Ctx, &mutations, client.InNamespace(instance.K8sgptConfig.Namespace)); err != nil {
+		return instance.R.FinishReconcile(err, false, instance.K8sgptConfig.Name, instance.K8sgptConfig)
+	}
+
 	// Create mutations for eligible resources
 	for _, eligibleResource := range eligibleResources {
-		mutation := corev1alpha1.Mutation{
+		// Only one active mutation targets a workload, the results raised while it is active join it
+		if active := activeMutation(mutations.Items, eligibleResource.Workload); active != nil {
+			if err := attachResults(instance, active, eligibleResource.ResultRefs); err != nil {
+				return instance.R.FinishReconcile(err, false, active.Name, instance.K8sgptConfig)
+			}
+			continue
+		}
+		// Mutations named after a result before it was renamed still reference it
+		if referencesResult(mutations.Items, eligibleResource.ResultRef) {
+			continue
+		}
+		newMutation := corev1alpha1.Mutation{
 			ObjectMeta: metav1.ObjectMeta{
 				Name:      eligibleResource.ResultRef.Name,
 				Namespace: instance.K8sgptConfig.Namespace,
+				// The mutation controller resolves the owning K8sGPT instance from these labels
+				Labels: map[string]string{
+					"k8sgpts.k8sgpt.ai/name":      instance.K8sgptConfig.Name,
+					"k8sgpts.k8sgpt.ai/namespace": instance.K8sgptConfig.Namespace,
+				},
+				OwnerReferences: []metav1.OwnerReference{resources.OwnerReference(*instance.K8sgptConfig)},
 			},
 			Spec: corev1alpha1.MutationSpec{
 				ResourceRef:         eligibleResource.ObjectRef,
 				ResourceGVK:         eligibleResource.GVK,
 				ResultRef:           eligibleResource.ResultRef,
+				ResultRefs:          eligibleResource.ResultRefs,
+				Workload:            eligibleResource.Workload,
 				OriginConfiguration: eligibleResource.OriginConfiguration,
 				TargetConfiguration: "",
 			},
-			Status: corev1alpha1.MutationStatus{
-				Phase:   corev1alpha1.AutoRemediationPhaseNotStarted,
-				Message: "Not Started",
-			},
+			// The status subresource is not written on create, new mutations start in the NotStarted phase
 		}
-		mutation.Finalizers = append(mutation.Finalizers, mutationFinalizer)
+		newMutation.Finalizers = append(newMutation.Finalizers, mutationFinalizer)
 		// Check if the mutation exists, else create it
 		mutationKey := client.ObjectKey{Namespace: instance.K8sgptConfig.Namespace, Name: eligibleResource.ResultRef.Name}
 		var existingMutation corev1alpha1.Mutation
@@ -77,7 +103,7 @@ func (step *calculateRemediationStep) execute(instance *K8sGPTInstance) (ctrl.Re
 			if client.IgnoreNotFound(err) != nil {
 				return instance.R.FinishReconcile(err, false, eligibleResource.ResultRef.Name, instance.K8sgptConfig)
 			}
-			if err := instance.R.Create(instance.Ctx, &mutation); err != nil {
+			if err := instance.R.Create(instance.Ctx, &newMutation); err != nil {
 				return instance.R.FinishReconcile(err, false, eligibleResource.ResultRef.Name, instance.K8sgptConfig)
 			}
 		} else {
@@ -92,6 +118,52 @@ func (step *calculateRemediationStep) execute(instance *K8sGPTInstance) (ctrl.Re
 	return instance.R.FinishReconcile(nil, false, instance.K8sgptConfig.Name, instance.K8sgptConfig)
 }
 
+// activeMutation returns the mutation of the workload that is not finished yet, if any
+func activeMutation(mutations []corev1alpha1.Mutation, workload corev1.ObjectReference) *corev1alpha1.Mutation {
+	for i := range mutations {
+		if mutations[i].Spec.Workload.Name == "" || mutation.IsTerminal(mutations[i].Status.Phase) {
+			continue
+		}
+		if util.SameWorkload(mutations[i].Spec.Workload, workload) {
+			return &mutations[i]
+		}
+	}
+	return nil
+}
+
+// referencesResult reports whether one of the mutations was raised for the result
+func referencesResult(mutations []corev1alpha1.Mutation, resultRef corev1.ObjectReference) bool {
+	sameResult := func(ref corev1.ObjectReference) bool {
+		return ref.Namespace == resultRef.Namespace && ref.Name == resultRef.Name
+	}
+	for _, m := range mutations {
+		if sameResult(m.Spec.ResultRef) || slices.ContainsFunc(m.Spec.ResultRefs, sameResult) {
+			return true
+		}
+	}
+	return false
+}
+
+// attachResults adds the results the mutation does not reference yet to it
+func attachResults(instance *K8sGPTInstance, existing *corev1alpha1.Mutation, resultRefs []corev1.ObjectReference) error {
+	patch := client.MergeFrom(existing.DeepCopy())
+	attached := false
+	for _, resultRef := range resultRefs {
+		if !slices.ContainsFunc(existing.Spec.ResultRefs, func(ref corev1.ObjectReference) bool {
+			return ref.Namespace == resultRef.Namespace && ref.Name == resultRef.Name
+		}) {
+			existing.Spec.ResultRefs = append(existing.Spec.ResultRefs, resultRef)
+			attached = true
+		}
+	}
+	if !attached {
+		return nil
+	}
+	instance.logger.Info("attaching results to active mutation", "mutation", existing.Name,
+		"results", len(existing.Spec.ResultRefs))
+	return instance.R.Patch(instance.Ctx, existing, patch)
+}
+
 func (step *calculateRemediationStep) setNext(next K8sGPT) {
 	step.next = next
 }
@@ -0,0 +1,133 @@
+/*
+Copyright 2023 The K8sGPT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package k8sgpt
+
+import (
+	"time"
+
+	corev1alpha1 "github.com/k8sgpt-ai/k8sgpt-operator/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// DefaultAICooldown is used when the BackOff cooldown is unset or invalid
+	DefaultAICooldown = 5 * time.Minute
+
+	circuitTripFailuresExceeded = "failures_exceeded"
+	circuitTripProbeFailed      = "probe_failed"
+)
+
+// aiCircuitBreaker decides whether AI explanations are requested for a single K8sGPT instance.
+// Its state lives in the instance status so that it survives restarts and is visible to users.
+type aiCircuitBreaker struct {
+	backOff *corev1alpha1.BackOff
+	status  *corev1alpha1.CircuitBreakerStatus
+}
+
+func newAICircuitBreaker(k8sgpt *corev1alpha1.K8sGPT) *aiCircuitBreaker {
+	if k8sgpt.Status.AIBackendCircuit == nil {
+		k8sgpt.Status.AIBackendCircuit = &corev1alpha1.CircuitBreakerStatus{State: corev1alpha1.CircuitBreakerClosed}
+	}
+	return &aiCircuitBreaker{
+		backOff: k8sgpt.Spec.AI.BackOff,
+		status:  k8sgpt.Status.AIBackendCircuit,
+	}
+}
+
+func (cb *aiCircuitBreaker) enabled() bool {
+	return cb.backOff != nil && cb.backOff.Enabled
+}
+
+func (cb *aiCircuitBreaker) cooldown() time.Duration {
+	if cb.backOff == nil || cb.backOff.Cooldown == "" {
+		return DefaultAICooldown
+	}
+	cooldown, err := time.ParseDuration(cb.backOff.Cooldown)
+	if err != nil {
+		return DefaultAICooldown
+	}
+	return cooldown
+}
+
+func (cb *aiCircuitBreaker) transition(state corev1alpha1.CircuitBreakerState, now time.Time) {
+	if cb.status.State == state {
+		return
+	}
+	transitionTime := metav1.NewTime(now)
+	cb.status.State = state
+	cb.status.LastTransitionTime = &transitionTime
+	if state == corev1alpha1.CircuitBreakerOpen {
+		cb.status.OpenedAt = &transitionTime
+	}
+}
+
+// allow reports whether the next analysis may request AI explanations.
+// Once the cooldown of an open circuit has elapsed the circuit moves to half-open
+// and the next analysis is used as the probe.
+func (cb *aiCircuitBreaker) allow(now time.Time) bool {
+	if !cb.enabled() {
+		cb.transition(corev1alpha1.CircuitBreakerClosed, now)
+		return true
+	}
+	switch cb.status.State {
+	case corev1alpha1.CircuitBreakerOpen:
+		if cb.status.OpenedAt != nil && now.Sub(cb.status.OpenedAt.Time) < cb.cooldown() {
+			return false
+		}
+		cb.transition(corev1alpha1.CircuitBreakerHalfOpen, now)
+		return true
+	default:
+		return true
+	}
+}
+
+// recordSuccess closes the circuit after an analysis that requested AI explanations succeeded
+func (cb *aiCircuitBreaker) recordSuccess(now time.Time) {
+	cb.status.ConsecutiveFailures = 0
+	cb.status.Reason = ""
+	cb.transition(corev1alpha1.CircuitBreakerClosed, now)
+}
+
+// recordFailure counts a failed analysis that requested AI explanations. It returns the
+// trip reason when the failure opened the circuit, or an empty string otherwise.
+func (cb *aiCircuitBreaker) recordFailure(now time.Time, err error) string {
+	cb.status.ConsecutiveFailures++
+	if !cb.enabled() {
+		return ""
+	}
+	switch {
+	case cb.status.State == corev1alpha1.CircuitBreakerHalfOpen:
+		cb.status.Reason = err.Error()
+		cb.transition(corev1alpha1.CircuitBreakerOpen, now)
+		return circuitTripProbeFailed
+	case cb.status.State == corev1alpha1.CircuitBreakerClosed && cb.status.ConsecutiveFailures > cb.backOff.MaxRetries:
+		cb.status.Reason = err.Error()
+		cb.transition(corev1alpha1.CircuitBreakerOpen, now)
+		return circuitTripFailuresExceeded
+	}
+	return ""
+}
+
+// stateValue maps the circuit state onto the value exported by the state gauge
+func (cb *aiCircuitBreaker) stateValue() float64 {
+	switch cb.status.State {
+	case corev1alpha1.CircuitBreakerHalfOpen:
+		return 1
+	case corev1alpha1.CircuitBreakerOpen:
+		return 2
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2023 The K8sGPT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package k8sgpt
+
+import (
+	"errors"
+	"time"
+
+	corev1alpha1 "github.com/k8sgpt-ai/k8sgpt-operator/api/v1alpha1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("aiCircuitBreaker", func() {
+	var (
+		k8sgpt  *corev1alpha1.K8sGPT
+		breaker *aiCircuitBreaker
+		now     time.Time
+		failure = errors.New("backend unavailable")
+	)
+
+	BeforeEach(func() {
+		k8sgpt = &corev1alpha1.K8sGPT{
+			Spec: corev1alpha1.K8sGPTSpec{
+				AI: &corev1alpha1.AISpec{
+					Enabled: true,
+					BackOff: &corev1alpha1.BackOff{Enabled: true, MaxRetries: 2, Cooldown: "1m"},
+				},
+			},
+		}
+		breaker = newAICircuitBreaker(k8sgpt)
+		now = time.Now()
+	})
+
+	tripOpen := func() {
+		for i := 0; i < 3; i++ {
+			Expect(breaker.allow(now)).To(BeTrue())
+			breaker.recordFailure(now, failure)
+		}
+		Expect(breaker.status.State).To(Equal(corev1alpha1.CircuitBreakerOpen))
+	}
+
+	It("should start closed and store its state in the instance status", func() {
+		Expect(k8sgpt.Status.AIBackendCircuit).NotTo(BeNil())
+		Expect(k8sgpt.Status.AIBackendCircuit.State).To(Equal(corev1alpha1.CircuitBreakerClosed))
+		Expect(breaker.allow(now)).To(BeTrue())
+	})
+
+	It("should open once failures exceed max retries", func() {
+		Expect(breaker.recordFailure(now, failure)).To(BeEmpty())
+		Expect(breaker.recordFailure(now, failure)).To(BeEmpty())
+		Expect(breaker.recordFailure(now, failure)).To(Equal(circuitTripFailuresExceeded))
+		Expect(breaker.status.Reason).To(Equal(failure.Error()))
+		Expect(breaker.allow(now.Add(30 * time.Second))).To(BeFalse())
+	})
+
+	It("should half-open for a probe after the cooldown and close on success", func() {
+		tripOpen()
+		Expect(breaker.allow(now.Add(time.Minute))).To(BeTrue())
+		Expect(breaker.status.State).To(Equal(corev1alpha1.CircuitBreakerHalfOpen))
+		breaker.recordSuccess(now.Add(time.Minute))
+		Expect(breaker.status.State).To(Equal(corev1alpha1.CircuitBreakerClosed))
+		Expect(breaker.status.ConsecutiveFailures).To(BeZero())
+	})
+
+	It("should reopen when the probe fails", func() {
+		tripOpen()
+		probeTime := now.Add(2 * time.Minute)
+		Expect(breaker.allow(probeTime)).To(BeTrue())
+		Expect(breaker.recordFailure(probeTime, failure)).To(Equal(circuitTripProbeFailed))
+		Expect(breaker.status.State).To(Equal(corev1alpha1.CircuitBreakerOpen))
+		Expect(breaker.status.OpenedAt.Time).To(BeTemporally("~", probeTime, time.Second))
+		Expect(breaker.allow(probeTime.Add(30 * time.Second))).To(BeFalse())
+	})
+
+	It("should never open when back off is disabled", func() {
+		k8sgpt.Spec.AI.BackOff.Enabled = false
+		for i := 0; i < 10; i++ {
+			Expect(breaker.recordFailure(now, failure)).To(BeEmpty())
+		}
+		Expect(breaker.allow(now)).To(BeTrue())
+		Expect(breaker.status.State).To(Equal(corev1alpha1.CircuitBreakerClosed))
+	})
+
+	It("should accept any duration as cooldown", func() {
+		k8sgpt.Spec.AI.BackOff.Cooldown = "1m30s"
+		Expect(breaker.cooldown()).To(Equal(90 * time.Second))
+	})
+
+	It("should fall back to the default cooldown when unset", func() {
+		k8sgpt.Spec.AI.BackOff.Cooldown = ""
+		Expect(breaker.cooldown()).To(Equal(DefaultAICooldown))
+	})
+})
@@ -15,11 +15,13 @@ limitations under the License.
 package k8sgpt
 
 import (
+	"fmt"
 	"os"
 
 	corev1alpha1 "github.com/k8sgpt-ai/k8sgpt-operator/api/v1alpha1"
 	"github.com/k8sgpt-ai/k8sgpt-operator/pkg/resources"
 	v1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -42,10 +44,12 @@ func (step *ConfigureStep) execute(instance *K8sGPTInstance) (ctrl.Result, error
 	// Check and see if the instance is new or has a K8sGPT deployment in flight
 	instance.k8sgptDeployment, err = step.getDeployment(instance)
 	if err != nil {
+		instance.setCondition(corev1alpha1.ConditionServerAvailable, metav1.ConditionFalse, "DeploymentSyncFailed", err.Error())
 		return instance.R.FinishReconcile(err, false, instance.K8sgptConfig.Name, instance.K8sgptConfig)
 	}
 
 	instance.hasReadyReplicas = instance.k8sgptDeployment.Status.AvailableReplicas != 0
+	step.setServerStatus(instance)
 
 	if !instance.hasReadyReplicas && os.Getenv("LOCAL_MODE") == "" {
 		instance.logger.Info("k8sgpt server not running, waiting next sync")
@@ -61,10 +65,24 @@ func (step *ConfigureStep) setNext(next K8sGPT) {
 	step.next = next
 }
 
+func (step *ConfigureStep) setServerStatus(instance *K8sGPTInstance) {
+	if containers := instance.k8sgptDeployment.Spec.Template.Spec.Containers; len(containers) > 0 {
+		instance.K8sgptConfig.Status.ServerImage = containers[0].Image
+	}
+	if instance.hasReadyReplicas {
+		instance.setCondition(corev1alpha1.ConditionServerAvailable, metav1.ConditionTrue, "ReplicasAvailable",
+			fmt.Sprintf("%d replica(s) available", instance.k8sgptDeployment.Status.AvailableReplicas))
+		return
+	}
+	instance.setCondition(corev1alpha1.ConditionServerAvailable, metav1.ConditionFalse, "NoReplicasAvailable",
+		"k8sgpt server deployment has no available replicas")
+}
+
 func (step *ConfigureStep) configureBackoff(instance *K8sGPTInstance) error {
 	instance.K8sgptConfig.Spec.AI.BackOff = &corev1alpha1.BackOff{
 		Enabled:    false,
 		MaxRetries: 5,
+		Cooldown:   "5m",
 	}
 	return instance.R.Update(instance.Ctx, instance.K8sgptConfig)
 }
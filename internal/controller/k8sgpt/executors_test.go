@@ -0,0 +1,294 @@
+/*
+Copyright 2023 The K8sGPT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package k8sgpt
+
+import (
+	"github.com/go-logr/logr"
+	corev1alpha1 "github.com/k8sgpt-ai/k8sgpt-operator/api/v1alpha1"
+	"github.com/k8sgpt-ai/k8sgpt-operator/internal/controller/conversions"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// The remediation executors apply fixes with server-side apply, they run against the API server
+var _ = Describe("Remediation executors", Label("integration"), func() {
+	executorConfig := func() (conversions.ObjectExecutionConfig, *corev1alpha1.Mutation) {
+		mutation := &corev1alpha1.Mutation{}
+		return conversions.ObjectExecutionConfig{Ctx: ctx, Rc: k8sClient, Log: logr.Discard(), Mutation: mutation}, mutation
+	}
+
+	// targetOf mimics a target configuration returned by the backend for the object
+	targetOf := func(obj client.Object, edit func(spec map[string]interface{})) *unstructured.Unstructured {
+		target, err := conversions.ToApplyObject(obj)
+		Expect(err).NotTo(HaveOccurred())
+		spec, _, _ := unstructured.NestedMap(target.Object, "spec")
+		edit(spec)
+		Expect(unstructured.SetNestedMap(target.Object, spec, "spec")).To(Succeed())
+		return target
+	}
+
+	setImage := func(image string) func(spec map[string]interface{}) {
+		return func(spec map[string]interface{}) {
+			containers, _, _ := unstructured.NestedSlice(spec, "template", "spec", "containers")
+			containers[0].(map[string]interface{})["image"] = image
+			_ = unstructured.SetNestedSlice(spec, containers, "template", "spec", "containers")
+		}
+	}
+
+	podTemplate := func(app, image string) corev1.PodTemplateSpec {
+		return corev1.PodTemplateSpec{
+			ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": app}},
+			Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: app, Image: image}}},
+		}
+	}
+
+	It("should fix a Service and keep its allocated cluster IP", func() {
+		service := &corev1.Service{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Service"},
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "executor-web"},
+			Spec: corev1.ServiceSpec{
+				Selector: map[string]string{"app": "wbe"},
+				Ports:    []corev1.ServicePort{{Port: 80, TargetPort: intstr.FromInt32(8080)}},
+			},
+		}
+		Expect(k8sClient.Create(ctx, service.DeepCopy())).To(Succeed())
+		var live corev1.Service
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(service), &live)).To(Succeed())
+
+		// The backend does not know the allocated cluster IP
+		target := targetOf(service, func(spec map[string]interface{}) {
+			spec["selector"] = map[string]interface{}{"app": "web"}
+			delete(spec, "clusterIP")
+			delete(spec, "clusterIPs")
+		})
+		config, mutation := executorConfig()
+		Expect(conversions.ExecuteTarget(config, target)).To(BeTrue())
+		Expect(mutation.Status.PreviousConfiguration).To(ContainSubstring("wbe"))
+
+		var updated corev1.Service
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(service), &updated)).To(Succeed())
+		Expect(updated.Spec.Selector).To(Equal(map[string]string{"app": "web"}))
+		Expect(updated.Spec.ClusterIP).To(Equal(live.Spec.ClusterIP))
+	})
+
+	It("should fix the backend of an Ingress", func() {
+		backend := func(service string) *networkingv1.IngressBackend {
+			return &networkingv1.IngressBackend{Service: &networkingv1.IngressServiceBackend{
+				Name: service, Port: networkingv1.ServiceBackendPort{Number: 80}}}
+		}
+		ingress := &networkingv1.Ingress{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "networking.k8s.io/v1", Kind: "Ingress"},
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "executor-web"},
+			Spec:       networkingv1.IngressSpec{DefaultBackend: backend("wbe")},
+		}
+		Expect(k8sClient.Create(ctx, ingress.DeepCopy())).To(Succeed())
+
+		fixed := ingress.DeepCopy()
+		fixed.Spec.DefaultBackend = backend("web")
+		config, _ := executorConfig()
+		Expect(conversions.ExecuteTarget(config, targetOf(fixed, func(map[string]interface{}) {}))).To(BeTrue())
+
+		var updated networkingv1.Ingress
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(ingress), &updated)).To(Succeed())
+		Expect(updated.Spec.DefaultBackend.Service.Name).To(Equal("web"))
+	})
+
+	It("should refuse to change the immutable fields of a StatefulSet", func() {
+		statefulSet := &appsv1.StatefulSet{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "StatefulSet"},
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "executor-db"},
+			Spec: appsv1.StatefulSetSpec{
+				ServiceName: "db",
+				Selector:    &metav1.LabelSelector{MatchLabels: map[string]string{"app": "db"}},
+				Template:    podTemplate("db", "postgres:broken"),
+			},
+		}
+		Expect(k8sClient.Create(ctx, statefulSet.DeepCopy())).To(Succeed())
+
+		// Changing the service name is refused and leaves the stateful set as it was
+		config, mutation := executorConfig()
+		_, err := conversions.ExecuteTarget(config, targetOf(statefulSet, func(spec map[string]interface{}) {
+			spec["serviceName"] = "database"
+			setImage("postgres:16")(spec)
+		}))
+		Expect(err).To(MatchError(conversions.ErrImmutableField))
+		Expect(mutation.Status.PreviousConfiguration).To(BeEmpty())
+
+		Expect(conversions.ExecuteTarget(config, targetOf(statefulSet, setImage("postgres:16")))).To(BeTrue())
+		var updated appsv1.StatefulSet
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(statefulSet), &updated)).To(Succeed())
+		Expect(updated.Spec.Template.Spec.Containers[0].Image).To(Equal("postgres:16"))
+		Expect(updated.Spec.ServiceName).To(Equal("db"))
+	})
+
+	It("should refuse to change the selector of a DaemonSet", func() {
+		daemonSet := &appsv1.DaemonSet{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "DaemonSet"},
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "executor-agent"},
+			Spec: appsv1.DaemonSetSpec{
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "agent"}},
+				Template: podTemplate("agent", "agent:broken"),
+			},
+		}
+		Expect(k8sClient.Create(ctx, daemonSet.DeepCopy())).To(Succeed())
+
+		config, _ := executorConfig()
+		_, err := conversions.ExecuteTarget(config, targetOf(daemonSet, func(spec map[string]interface{}) {
+			spec["selector"] = map[string]interface{}{"matchLabels": map[string]interface{}{"app": "other"}}
+		}))
+		Expect(err).To(MatchError(conversions.ErrImmutableField))
+
+		Expect(conversions.ExecuteTarget(config, targetOf(daemonSet, setImage("agent:1.0")))).To(BeTrue())
+		var updated appsv1.DaemonSet
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(daemonSet), &updated)).To(Succeed())
+		Expect(updated.Spec.Template.Spec.Containers[0].Image).To(Equal("agent:1.0"))
+	})
+
+	It("should recreate a Job and roll it back", func() {
+		job := &batchv1.Job{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "batch/v1", Kind: "Job"},
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "executor-migrate"},
+			Spec: batchv1.JobSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+				RestartPolicy: corev1.RestartPolicyNever,
+				Containers:    []corev1.Container{{Name: "migrate", Image: "migrate:broken"}},
+			}}},
+		}
+		Expect(k8sClient.Create(ctx, job.DeepCopy())).To(Succeed())
+		var live batchv1.Job
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(job), &live)).To(Succeed())
+		live.TypeMeta = job.TypeMeta
+
+		// The backend echoes the generated selector and labels of the live job
+		target := targetOf(&live, setImage("migrate:1.0"))
+		config, mutation := executorConfig()
+
+		// The job is deleted first, then created from the target
+		Expect(conversions.ExecuteTarget(config, target)).To(BeFalse())
+		Expect(mutation.Status.PreviousConfiguration).To(ContainSubstring("migrate:broken"))
+		err := k8sClient.Get(ctx, client.ObjectKeyFromObject(job), &batchv1.Job{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+		Expect(conversions.ExecuteTarget(config, target)).To(BeTrue())
+		var recreated batchv1.Job
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(job), &recreated)).To(Succeed())
+		Expect(recreated.Spec.Template.Spec.Containers[0].Image).To(Equal("migrate:1.0"))
+
+		// Rolling back recreates the job as it was
+		previous, err := conversions.FromManifest(mutation.Status.PreviousConfiguration)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(conversions.Rollback(ctx, k8sClient, previous)).To(BeFalse())
+		Expect(conversions.Rollback(ctx, k8sClient, previous)).To(BeTrue())
+		var restored batchv1.Job
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(job), &restored)).To(Succeed())
+		Expect(restored.Spec.Template.Spec.Containers[0].Image).To(Equal("migrate:broken"))
+	})
+
+	It("should fix the job template of a CronJob", func() {
+		cronJob := &batchv1.CronJob{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "batch/v1", Kind: "CronJob"},
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "executor-backup"},
+			Spec: batchv1.CronJobSpec{
+				Schedule: "0 * * * *",
+				JobTemplate: batchv1.JobTemplateSpec{Spec: batchv1.JobSpec{Template: corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{
+						RestartPolicy: corev1.RestartPolicyNever,
+						Containers:    []corev1.Container{{Name: "backup", Image: "backup:broken"}},
+					},
+				}}},
+			},
+		}
+		Expect(k8sClient.Create(ctx, cronJob.DeepCopy())).To(Succeed())
+
+		fixed := cronJob.DeepCopy()
+		fixed.Spec.JobTemplate.Spec.Template.Spec.Containers[0].Image = "backup:1.0"
+		config, _ := executorConfig()
+		Expect(conversions.ExecuteTarget(config, targetOf(fixed, func(map[string]interface{}) {}))).To(BeTrue())
+
+		var updated batchv1.CronJob
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(cronJob), &updated)).To(Succeed())
+		Expect(updated.Spec.JobTemplate.Spec.Template.Spec.Containers[0].Image).To(Equal("backup:1.0"))
+		Expect(updated.Spec.Schedule).To(Equal("0 * * * *"))
+	})
+
+	It("should report the fields owned by other field managers", func() {
+		deployment := &appsv1.Deployment{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "executor-web"},
+			Spec: appsv1.DeploymentSpec{
+				Replicas: ptr.To(int32(3)),
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+				Template: podTemplate("web", "nginx:1.0"),
+			},
+		}
+		// The replicas of the deployment are owned by an autoscaler
+		Expect(k8sClient.Create(ctx, deployment.DeepCopy(), client.FieldOwner("autoscaler"))).To(Succeed())
+		fixed := deployment.DeepCopy()
+		fixed.Spec.Replicas = ptr.To(int32(1))
+		fixed.Spec.Template.Spec.Containers[0].Image = "nginx:1.1"
+
+		config, mutation := executorConfig()
+		done, err := conversions.ExecuteTarget(config, fixed)
+		Expect(err).To(MatchError(conversions.ErrApplyConflict))
+		Expect(done).To(BeFalse())
+		Expect(mutation.Status.Conflicts).To(ContainElement(HavePrefix(".spec.replicas")))
+
+		config.ForceApply = true
+		Expect(conversions.ExecuteTarget(config, fixed)).To(BeTrue())
+		Expect(mutation.Status.Conflicts).To(BeEmpty())
+		var updated appsv1.Deployment
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(deployment), &updated)).To(Succeed())
+		Expect(updated.Spec.Template.Spec.Containers[0].Image).To(Equal("nginx:1.1"))
+		Expect(*updated.Spec.Replicas).To(Equal(int32(1)))
+	})
+
+	It("should roll back only the fields it applied", func() {
+		deployment := &appsv1.Deployment{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "executor-api"},
+			Spec: appsv1.DeploymentSpec{
+				Replicas: ptr.To(int32(3)),
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "api"}},
+				Template: podTemplate("api", "api:broken"),
+			},
+		}
+		Expect(k8sClient.Create(ctx, deployment.DeepCopy(), client.FieldOwner("autoscaler"))).To(Succeed())
+		config, mutation := executorConfig()
+		config.ForceApply = true
+		Expect(conversions.ExecuteTarget(config, targetOf(deployment, setImage("api:1.0")))).To(BeTrue())
+
+		// The autoscaler scales the fixed deployment
+		var scaled appsv1.Deployment
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(deployment), &scaled)).To(Succeed())
+		scaled.Spec.Replicas = ptr.To(int32(5))
+		Expect(k8sClient.Update(ctx, &scaled, client.FieldOwner("autoscaler"))).To(Succeed())
+
+		previous, err := conversions.FromManifest(mutation.Status.PreviousConfiguration)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(conversions.Rollback(ctx, k8sClient, previous)).To(BeTrue())
+		var restored appsv1.Deployment
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(deployment), &restored)).To(Succeed())
+		Expect(restored.Spec.Template.Spec.Containers[0].Image).To(Equal("api:broken"))
+		Expect(*restored.Spec.Replicas).To(Equal(int32(5)))
+	})
+})
@@ -15,18 +15,27 @@ limitations under the License.
 package k8sgpt
 
 import (
+	corev1alpha1 "github.com/k8sgpt-ai/k8sgpt-operator/api/v1alpha1"
+	"github.com/k8sgpt-ai/k8sgpt-operator/internal/controller/mutation"
+	"github.com/k8sgpt-ai/k8sgpt-operator/internal/controller/shared"
 	"github.com/k8sgpt-ai/k8sgpt-operator/pkg/resources"
 	"github.com/k8sgpt-ai/k8sgpt-operator/pkg/utils"
+	corev1 "k8s.io/api/core/v1"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 )
 
 const (
 	FinalizerName = "k8sgpt.ai/finalizer"
+	// eventReasonRollbackAbandoned is recorded on the targets of applied mutations deleted with
+	// their instance
+	eventReasonRollbackAbandoned = "RollbackAbandoned"
 )
 
 type FinalizerStep struct {
-	next K8sGPT
+	next           K8sGPT
+	ClientRegistry *shared.ClientRegistry
 }
 
 func (step *FinalizerStep) execute(instance *K8sGPTInstance) (ctrl.Result, error) {
@@ -41,6 +50,11 @@ func (step *FinalizerStep) execute(instance *K8sGPTInstance) (ctrl.Result, error
 			if err != nil {
 				return instance.R.FinishReconcile(err, false, instance.K8sgptConfig.Name, instance.K8sgptConfig)
 			}
+			// Results and mutations in other namespaces are not garbage collected with the instance
+			if err := step.teardown(instance); err != nil {
+				return instance.R.FinishReconcile(err, false, instance.K8sgptConfig.Name, instance.K8sgptConfig)
+			}
+			step.ClientRegistry.Remove(instance.req.NamespacedName)
 			controllerutil.RemoveFinalizer(instance.K8sgptConfig, FinalizerName)
 			if err := instance.R.Update(instance.Ctx, instance.K8sgptConfig); err != nil {
 				return instance.R.FinishReconcile(err, false, instance.K8sgptConfig.Name, instance.K8sgptConfig)
@@ -65,6 +79,96 @@ func (step *FinalizerStep) execute(instance *K8sGPTInstance) (ctrl.Result, error
 
 }
 
+// teardown deletes the results and mutations of the instance. Mutations that were not applied
+// yet are aborted, and the finalizers of all of them are released, as the mutation controller
+// stops working on them once the instance is gone. Applied changes whose rollout is still
+// watched are left in place, an Event on their target records that they are no longer rolled back.
+func (step *FinalizerStep) teardown(instance *K8sGPTInstance) error {
+	var results corev1alpha1.ResultList
+	if err := instance.R.List(instance.Ctx, &results, client.MatchingLabels{
+		"k8sgpts.k8sgpt.ai/name":      instance.K8sgptConfig.Name,
+		"k8sgpts.k8sgpt.ai/namespace": instance.K8sgptConfig.Namespace,
+	}); err != nil {
+		return err
+	}
+	resultKeys := make(map[client.ObjectKey]bool, len(results.Items))
+	for _, result := range results.Items {
+		resultKeys[client.ObjectKeyFromObject(&result)] = true
+	}
+
+	var all corev1alpha1.MutationList
+	if err := instance.R.List(instance.Ctx, &all); err != nil {
+		return err
+	}
+	var mutations corev1alpha1.MutationList
+	for _, m := range all.Items {
+		if ownsMutation(instance.K8sgptConfig, m, resultKeys) {
+			mutations.Items = append(mutations.Items, m)
+		}
+	}
+	for i := range mutations.Items {
+		m := &mutations.Items[i]
+		// Pending mutations the blast radius limits hold were not applied, they are cancelled
+		applied := m.Status.Phase == corev1alpha1.AutoRemediationPhaseCompleted ||
+			(m.Status.Phase == corev1alpha1.AutoRemediationPending && m.Status.HeldBy == "")
+		if applied {
+			abandonRollback(instance, m)
+		}
+		if mutation.Cancel(m, "Cancelled: the K8sGPT instance was deleted") {
+			if err := instance.R.Status().Update(instance.Ctx, m); client.IgnoreNotFound(err) != nil {
+				return err
+			}
+		}
+		if controllerutil.RemoveFinalizer(m, mutationFinalizer) {
+			if err := instance.R.Update(instance.Ctx, m); client.IgnoreNotFound(err) != nil {
+				return err
+			}
+		}
+		if err := instance.R.Delete(instance.Ctx, m); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+	}
+
+	for i := range results.Items {
+		if err := instance.R.Delete(instance.Ctx, &results.Items[i]); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+	}
+	instance.logger.Info("Deleted results and mutations of the instance", "results", len(results.Items),
+		"mutations", len(mutations.Items))
+	return nil
+}
+
+// ownsMutation reports whether a mutation belongs to the instance. Mutations created before they
+// were labelled and owned by their instance are matched by the result they remediate.
+func ownsMutation(k8sgpt *corev1alpha1.K8sGPT, m corev1alpha1.Mutation, resultKeys map[client.ObjectKey]bool) bool {
+	if name, labelled := m.Labels["k8sgpts.k8sgpt.ai/name"]; labelled {
+		return name == k8sgpt.Name && m.Labels["k8sgpts.k8sgpt.ai/namespace"] == k8sgpt.Namespace
+	}
+	for _, ownerReference := range m.OwnerReferences {
+		if ownerReference.Kind == "K8sGPT" && ownerReference.UID == k8sgpt.UID {
+			return true
+		}
+	}
+	return resultKeys[client.ObjectKey{Namespace: m.Spec.ResultRef.Namespace, Name: m.Spec.ResultRef.Name}]
+}
+
+// abandonRollback records on the target of an applied mutation that its change is no longer
+// rolled back when the rollout fails
+func abandonRollback(instance *K8sGPTInstance, m *corev1alpha1.Mutation) {
+	instance.logger.Info("Abandoning the rollback of mutation", "mutation", m.Name, "phase", m.Status.Phase)
+	if instance.R.Recorder == nil {
+		return
+	}
+	target := m.Spec.Workload
+	if target.Name == "" {
+		target = m.Spec.ResourceRef
+	}
+	instance.R.Recorder.Eventf(&target, corev1.EventTypeWarning, eventReasonRollbackAbandoned,
+		"Mutation %s/%s was deleted with K8sGPT %s/%s, its change is kept and no longer rolled back if the rollout fails",
+		m.Namespace, m.Name, instance.K8sgptConfig.Namespace, instance.K8sgptConfig.Name)
+}
+
 func (step *FinalizerStep) setNext(next K8sGPT) {
 	step.next = next
 }
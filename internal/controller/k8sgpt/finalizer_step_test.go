@@ -0,0 +1,128 @@
+/*
+Copyright 2023 The K8sGPT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package k8sgpt
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	corev1alpha1 "github.com/k8sgpt-ai/k8sgpt-operator/api/v1alpha1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("FinalizerStep", func() {
+	Describe("teardown", func() {
+		var (
+			instance *K8sGPTInstance
+			c        client.Client
+			recorder *record.FakeRecorder
+		)
+
+		owned := func(namespace, name string) metav1.ObjectMeta {
+			return metav1.ObjectMeta{Namespace: namespace, Name: name, Labels: map[string]string{
+				"k8sgpts.k8sgpt.ai/name":      "k8sgpt",
+				"k8sgpts.k8sgpt.ai/namespace": "k8sgpt",
+			}}
+		}
+		mutationIn := func(name string, phase corev1alpha1.AutoRemediationPhase) *corev1alpha1.Mutation {
+			meta := owned("k8sgpt", name)
+			meta.Finalizers = []string{mutationFinalizer}
+			return &corev1alpha1.Mutation{ObjectMeta: meta, Status: corev1alpha1.MutationStatus{Phase: phase}}
+		}
+
+		BeforeEach(func() {
+			scheme := runtime.NewScheme()
+			Expect(corev1alpha1.AddToScheme(scheme)).To(Succeed())
+			other := &corev1alpha1.Result{ObjectMeta: metav1.ObjectMeta{Namespace: "k8sgpt", Name: "other",
+				Labels: map[string]string{"k8sgpts.k8sgpt.ai/name": "other", "k8sgpts.k8sgpt.ai/namespace": "k8sgpt"}}}
+			// The pending mutation held by the blast radius limits was not applied
+			held := mutationIn("held", corev1alpha1.AutoRemediationPending)
+			held.Status.HeldBy = "outside maintenance windows"
+			// The change of the pending mutation is watched for a failed rollout
+			pending := mutationIn("pending", corev1alpha1.AutoRemediationPending)
+			pending.Spec.Workload = corev1.ObjectReference{APIVersion: "apps/v1", Kind: "Deployment",
+				Namespace: "default", Name: "web"}
+			// Mutations created before they were labelled belong to the instance of their result
+			legacy := &corev1alpha1.Mutation{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "k8sgpt", Name: "legacy", Finalizers: []string{mutationFinalizer}},
+				Spec:       corev1alpha1.MutationSpec{ResultRef: corev1.ObjectReference{Namespace: "k8sgpt", Name: "pod-web"}},
+			}
+			foreign := &corev1alpha1.Mutation{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "k8sgpt", Name: "foreign", Finalizers: []string{mutationFinalizer}},
+				Spec:       corev1alpha1.MutationSpec{ResultRef: corev1.ObjectReference{Namespace: "k8sgpt", Name: "other"}},
+			}
+			adopted := &corev1alpha1.Mutation{ObjectMeta: metav1.ObjectMeta{Namespace: "k8sgpt", Name: "adopted",
+				OwnerReferences: []metav1.OwnerReference{{APIVersion: "core.k8sgpt.ai/v1alpha1", Kind: "K8sGPT",
+					Name: "k8sgpt", UID: "k8sgpt-uid"}}}}
+			c = fake.NewClientBuilder().WithScheme(scheme).
+				WithStatusSubresource(&corev1alpha1.Mutation{}).
+				WithObjects(
+					&corev1alpha1.Result{ObjectMeta: owned("k8sgpt", "pod-web")},
+					&corev1alpha1.Result{ObjectMeta: owned("monitoring", "pod-db")},
+					other,
+					held,
+					pending,
+					legacy,
+					foreign,
+					adopted,
+				).Build()
+			recorder = record.NewFakeRecorder(10)
+			instance = &K8sGPTInstance{
+				R:      &K8sGPTReconciler{Client: c, Recorder: recorder},
+				Ctx:    context.Background(),
+				logger: logr.Discard(),
+				K8sgptConfig: &corev1alpha1.K8sGPT{
+					ObjectMeta: metav1.ObjectMeta{Namespace: "k8sgpt", Name: "k8sgpt", UID: "k8sgpt-uid"},
+				},
+			}
+		})
+
+		It("should delete the results and mutations of the instance in every namespace", func() {
+			Expect((&FinalizerStep{}).teardown(instance)).To(Succeed())
+
+			var results corev1alpha1.ResultList
+			Expect(c.List(context.Background(), &results)).To(Succeed())
+			Expect(results.Items).To(HaveLen(1))
+			Expect(results.Items[0].Name).To(Equal("other"))
+
+			var mutations corev1alpha1.MutationList
+			Expect(c.List(context.Background(), &mutations)).To(Succeed())
+			Expect(mutations.Items).To(HaveLen(1))
+			Expect(mutations.Items[0].Name).To(Equal("foreign"))
+		})
+
+		It("should delete the unlabelled mutations of the instance", func() {
+			Expect((&FinalizerStep{}).teardown(instance)).To(Succeed())
+			for _, name := range []string{"legacy", "adopted"} {
+				err := c.Get(context.Background(), client.ObjectKey{Namespace: "k8sgpt", Name: name}, &corev1alpha1.Mutation{})
+				Expect(apierrors.IsNotFound(err)).To(BeTrue(), name)
+			}
+		})
+
+		It("should record that applied changes are no longer rolled back", func() {
+			Expect((&FinalizerStep{}).teardown(instance)).To(Succeed())
+			Expect(recorder.Events).To(HaveLen(1))
+			Expect(<-recorder.Events).To(And(HavePrefix("Warning RollbackAbandoned"),
+				ContainSubstring("Mutation k8sgpt/pending")))
+		})
+	})
+})
@@ -18,7 +18,7 @@ import (
 	"context"
 	"time"
 
-	"github.com/k8sgpt-ai/k8sgpt-operator/internal/controller/types"
+	"github.com/k8sgpt-ai/k8sgpt-operator/internal/controller/shared"
 
 	"github.com/go-logr/logr"
 	corev1alpha1 "github.com/k8sgpt-ai/k8sgpt-operator/api/v1alpha1"
@@ -26,10 +26,13 @@ import (
 	metricspkg "github.com/k8sgpt-ai/k8sgpt-operator/pkg/metrics"
 	v1 "k8s.io/api/apps/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 
 	kclient "github.com/k8sgpt-ai/k8sgpt-operator/pkg/client"
 	"github.com/k8sgpt-ai/k8sgpt-operator/pkg/integrations"
@@ -61,7 +64,10 @@ type K8sGPTReconciler struct {
 	SinkClient          *sinks.Client
 	MetricsBuilder      *metricspkg.MetricBuilder
 	EnableResultLogging bool
-	Signal              chan types.InterControllerSignal
+	// ClientRegistry shares the K8sGPT server connection of each instance with the mutation controller
+	ClientRegistry *shared.ClientRegistry
+	// Recorder publishes Events about the objects of an instance, it may be nil
+	Recorder record.EventRecorder
 }
 
 type K8sGPTInstance struct {
@@ -73,6 +79,8 @@ type K8sGPTInstance struct {
 	logger           logr.Logger
 	kclient          *kclient.Client
 	hasReadyReplicas bool
+	// sinkFailures are the deliveries to sinks that failed during this reconcile
+	sinkFailures []error
 }
 
 type K8sGPT interface {
@@ -97,12 +105,14 @@ func (r *K8sGPTReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 	}
 
 	initStep := InitStep{}
-	finalizerStep := FinalizerStep{}
 	configureStep := ConfigureStep{}
 	preAnalysisStep := PreAnalysisStep{
-		// This passes the channel into the pre-analysis step to flag when connection is ready
-		// This in turn is passed to the mutation controller
-		Signal: r.Signal,
+		// The pre-analysis step registers the connection once it is ready
+		// so the mutation controller can resolve it for this instance
+		ClientRegistry: r.ClientRegistry,
+	}
+	finalizerStep := FinalizerStep{
+		ClientRegistry: r.ClientRegistry,
 	}
 	analysisStep := AnalysisStep{
 		enableResultLogging: r.EnableResultLogging,
@@ -119,8 +129,14 @@ func (r *K8sGPTReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 	analysisStep.setNext(&resultStatusStep)
 	resultStatusStep.setNext(&calculateRemediationStep)
 
-	return initStep.execute(&instance)
+	result, err := initStep.execute(&instance)
+
+	// Every step records its conditions on the in-memory object, persist them in one write
+	if statusErr := instance.updateStatus(err); statusErr != nil {
+		instance.logger.Error(statusErr, "unable to update K8sGPT status")
+	}
 
+	return result, err
 }
 
 // SetupWithManager sets up the controller with the Manager.
@@ -129,21 +145,30 @@ func (r *K8sGPTReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	k8sgptReconcileErrorCount := r.MetricsBuilder.GetCounterVec("k8sgpt_reconcile_error_count")
 	k8sgptNumberOfResults := r.MetricsBuilder.GetGaugeVec("k8sgpt_number_of_results")
 	k8sgptNumberOfResultsByType := r.MetricsBuilder.GetGaugeVec("k8sgpt_number_of_results_by_type")
+	k8sgptNumberOfResultsBySeverity := r.MetricsBuilder.GetGaugeVec("k8sgpt_number_of_results_by_severity")
 	k8sgptNumberOfBackendAICalls := r.MetricsBuilder.GetCounterVec("k8sgpt_number_of_backend_ai_calls")
 	k8sgptNumberOfFailedBackendAICalls := r.MetricsBuilder.GetCounterVec("k8sgpt_number_of_failed_backend_ai_calls")
+	k8sgptAICircuitBreakerState := r.MetricsBuilder.GetGaugeVec("k8sgpt_ai_circuit_breaker_state")
+	k8sgptAICircuitBreakerTrips := r.MetricsBuilder.GetCounterVec("k8sgpt_ai_circuit_breaker_trips")
+	k8sgptNumberOfEvictedResults := r.MetricsBuilder.GetCounterVec("k8sgpt_number_of_evicted_results")
 
 	// Register the metrics
 	metrics.Registry.MustRegister(
 		k8sgptReconcileErrorCount,
 		k8sgptNumberOfResults,
 		k8sgptNumberOfResultsByType,
+		k8sgptNumberOfResultsBySeverity,
 		k8sgptNumberOfBackendAICalls,
 		k8sgptNumberOfFailedBackendAICalls,
+		k8sgptAICircuitBreakerState,
+		k8sgptAICircuitBreakerTrips,
+		k8sgptNumberOfEvictedResults,
 	)
 
-	// Setup the controller
+	// Setup the controller. Status writes do not change the generation, they would otherwise
+	// reconcile the instance again right away instead of after the analysis interval.
 	c := ctrl.NewControllerManagedBy(mgr).
-		For(&corev1alpha1.K8sGPT{}).
+		For(&corev1alpha1.K8sGPT{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
 		Complete(r)
 
 	return c
@@ -18,14 +18,17 @@ import (
 	"fmt"
 	"strings"
 
+	corev1alpha1 "github.com/k8sgpt-ai/k8sgpt-operator/api/v1alpha1"
+	"github.com/k8sgpt-ai/k8sgpt-operator/internal/controller/shared"
 	"github.com/k8sgpt-ai/k8sgpt-operator/internal/controller/types"
 	Kclient "github.com/k8sgpt-ai/k8sgpt-operator/pkg/client"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	ctrl "sigs.k8s.io/controller-runtime"
 )
 
 type PreAnalysisStep struct {
-	next   K8sGPT
-	Signal chan types.InterControllerSignal
+	next           K8sGPT
+	ClientRegistry *shared.ClientRegistry
 }
 
 func (step *PreAnalysisStep) execute(instance *K8sGPTInstance) (ctrl.Result, error) {
@@ -48,27 +51,28 @@ func (step *PreAnalysisStep) execute(instance *K8sGPTInstance) (ctrl.Result, err
 	// If the deployment is active, we will query it directly for sis data
 	address, err := Kclient.GenerateAddress(instance.Ctx, instance.R.Client, instance.K8sgptConfig)
 	if err != nil {
+		instance.setCondition(corev1alpha1.ConditionServerAvailable, metav1.ConditionFalse, "ConnectionFailed", err.Error())
 		return instance.R.FinishReconcile(err, false, instance.K8sgptConfig.Name, instance.K8sgptConfig)
 	}
 
 	instance.logger.Info("K8sGPT address: " + address)
 
-	instance.kclient, err = Kclient.NewClient(address)
+	instance.kclient, err = step.getClient(instance, address)
 	if err != nil {
+		instance.setCondition(corev1alpha1.ConditionServerAvailable, metav1.ConditionFalse, "ConnectionFailed", err.Error())
 		return instance.R.FinishReconcile(err, false, instance.K8sgptConfig.Name, instance.K8sgptConfig)
 	}
+	instance.setCondition(corev1alpha1.ConditionServerAvailable, metav1.ConditionTrue, "Connected", "connected to k8sgpt server at "+address)
 
 	instance.logger.Info("K8sGPT client: " + fmt.Sprintf("%v", instance.kclient))
-	instance.logger.Info("Sending signal to configure step")
 
-	if instance.K8sgptConfig.Spec.AI.AutoRemediation.Enabled {
-		step.Signal <- types.InterControllerSignal{
-			K8sGPTClient: instance.kclient,
-			Backend:      instance.K8sgptConfig.Spec.AI.Backend,
-			K8sGPT:       instance.K8sgptConfig,
-		}
-	}
-	instance.logger.Info("Signal sent to configure step")
+	// Hand the connection over to the mutation controller
+	step.ClientRegistry.Register(instance.req.NamespacedName, types.InterControllerSignal{
+		K8sGPTClient: instance.kclient,
+		Backend:      instance.K8sgptConfig.Spec.AI.Backend,
+		K8sGPT:       instance.K8sgptConfig.DeepCopy(),
+	})
+	instance.logger.Info("Registered K8sGPT client for mutation controller")
 
 	instance.logger.Info("Adding remote cache")
 	// This will need a refactor in future...
@@ -96,6 +100,15 @@ func (step *PreAnalysisStep) setNext(next K8sGPT) {
 	step.next = next
 }
 
+// getClient reuses the connection registered by a previous reconcile while the server address is unchanged
+func (step *PreAnalysisStep) getClient(instance *K8sGPTInstance, address string) (*Kclient.Client, error) {
+	if signal, ok := step.ClientRegistry.Get(instance.req.NamespacedName); ok && signal.K8sGPTClient != nil &&
+		signal.K8sGPTClient.Conn.Target() == address {
+		return signal.K8sGPTClient, nil
+	}
+	return Kclient.NewClient(address)
+}
+
 func (step *PreAnalysisStep) addRemoteCache(instance *K8sGPTInstance) error {
 	if instance.K8sgptConfig.Spec.RemoteCache != nil || instance.K8sgptConfig.Spec.CustomAnalyzers != nil {
 		return instance.kclient.AddConfig(instance.K8sgptConfig)
@@ -120,6 +133,8 @@ func (step *PreAnalysisStep) updateDeploymentImage(instance *K8sGPTInstance) (ct
 	if err != nil {
 		return instance.R.FinishReconcile(err, false, instance.K8sgptConfig.Name, instance.K8sgptConfig)
 	}
+	instance.K8sgptConfig.Status.ServerImage = instance.k8sgptDeployment.Spec.Template.Spec.Containers[0].Image
+	instance.setCondition(corev1alpha1.ConditionServerAvailable, metav1.ConditionFalse, "Upgrading", "rolling out "+instance.K8sgptConfig.Status.ServerImage)
 
 	return instance.R.FinishReconcile(nil, false, instance.K8sgptConfig.Name, instance.K8sgptConfig)
 }
@@ -15,12 +15,16 @@ limitations under the License.
 package k8sgpt
 
 import (
+	"errors"
 	"fmt"
+	"slices"
+	"strings"
 
 	corev1alpha1 "github.com/k8sgpt-ai/k8sgpt-operator/api/v1alpha1"
 	"github.com/k8sgpt-ai/k8sgpt-operator/pkg/resources"
 	"github.com/k8sgpt-ai/k8sgpt-operator/pkg/sinks"
 	kcorev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -44,15 +48,14 @@ func (step *ResultStatusStep) execute(instance *K8sGPTInstance) (ctrl.Result, er
 		return instance.R.FinishReconcile(nil, false, instance.K8sgptConfig.Name, instance.K8sgptConfig)
 	}
 
-	sinkEnabled, sinkType, err := step.initSinkType(instance)
-	if err != nil {
-		return instance.R.FinishReconcile(err, false, instance.K8sgptConfig.Name, instance.K8sgptConfig)
-	}
-
-	err = step.processLatestResults(instance, sinkEnabled, sinkType, latestResultList)
+	configured := initSinks(instance)
+	failures, err := step.processLatestResults(instance, configured, latestResultList)
 	if err != nil {
 		return instance.R.FinishReconcile(err, false, instance.K8sgptConfig.Name, instance.K8sgptConfig)
 	}
+	// The resolved results the analysis reported to the sinks count too
+	instance.sinkFailures = append(instance.sinkFailures, failures...)
+	setSinkCondition(instance, configured, instance.sinkFailures)
 
 	instance.logger.Info("ending ResultStatusStep")
 
@@ -63,56 +66,136 @@ func (step *ResultStatusStep) setNext(next K8sGPT) {
 	step.next = next
 }
 
-func (step *ResultStatusStep) initSinkType(instance *K8sGPTInstance) (bool, sinks.ISink, error) {
-	sinkEnabled := instance.K8sgptConfig.Spec.Sink != nil && instance.K8sgptConfig.Spec.Sink.Type != "" && (instance.K8sgptConfig.Spec.Sink.Endpoint != "" || instance.K8sgptConfig.Spec.Sink.Secret != nil)
-	var sinkType sinks.ISink
+// configuredSink is a sink of the instance, err is why it could not be configured
+type configuredSink struct {
+	corev1alpha1.SinkSpec
+	sink sinks.ISink
+	err  error
+}
 
-	if sinkEnabled {
+// initSinks returns the configured sinks of the instance
+func initSinks(instance *K8sGPTInstance) []configuredSink {
+	var configured []configuredSink
+	for _, spec := range sinks.Configured(*instance.K8sgptConfig) {
 		var sinkSecretValue string
-
-		if instance.K8sgptConfig.Spec.Sink.Secret != nil {
+		if spec.Secret != nil {
 			secret := &kcorev1.Secret{}
 			secretNamespacedName := types.NamespacedName{
 				Namespace: instance.req.Namespace,
-				Name:      instance.K8sgptConfig.Spec.Sink.Secret.Name,
+				Name:      spec.Secret.Name,
 			}
 			if err := instance.R.Get(instance.Ctx, secretNamespacedName, secret); err != nil {
-
-				return sinkEnabled, sinkType, fmt.Errorf("could not find sink secret: %w", err)
+				configured = append(configured, configuredSink{SinkSpec: spec,
+					err: fmt.Errorf("could not find sink secret: %w", err)})
+				continue
 			}
+			sinkSecretValue = string(secret.Data[spec.Secret.Key])
+		}
+		sink, err := sinks.NewSink(spec.Type)
+		if err != nil {
+			configured = append(configured, configuredSink{SinkSpec: spec, err: err})
+			continue
+		}
+		sink.Configure(sinks.SinkConfig(*instance.K8sgptConfig, spec), *instance.R.SinkClient, sinkSecretValue)
+		configured = append(configured, configuredSink{SinkSpec: spec, sink: sink})
+	}
+	return configured
+}
 
-			sinkSecretValue = string(secret.Data[instance.K8sgptConfig.Spec.Sink.Secret.Key])
+// deliver sends the change of the result to the sink and records the delivery on the result
+func (s configuredSink) deliver(result *corev1alpha1.Result, lifeCycle corev1alpha1.SinkLifeCycle, now metav1.Time) error {
+	err := s.err
+	if err == nil {
+		if lifeCycle == corev1alpha1.SinkLifeCycleResolved {
+			err = s.sink.EmitResolved(result.Spec, resources.ResolvedAfter(*result, now))
+		} else {
+			err = s.sink.Emit(result.Spec)
 		}
-		sinkType = sinks.NewSink(instance.K8sgptConfig.Spec.Sink.Type)
-		sinkType.Configure(*instance.K8sgptConfig, *instance.R.SinkClient, sinkSecretValue)
 	}
+	sinks.RecordDelivery(&result.Status, s.Name, lifeCycle, now, err)
+	if err != nil {
+		return fmt.Errorf("%s sink: %w", s.Name, err)
+	}
+	return nil
+}
 
-	return sinkEnabled, sinkType, nil
+// setSinkCondition reports the deliveries of the reconcile in the SinkHealthy condition
+func setSinkCondition(instance *K8sGPTInstance, configured []configuredSink, failures []error) {
+	if len(configured) == 0 {
+		instance.setCondition(corev1alpha1.ConditionSinkHealthy, metav1.ConditionUnknown, "NotConfigured", "no sink is configured")
+		return
+	}
+	if len(failures) > 0 {
+		reason := "EmitFailed"
+		if slices.ContainsFunc(configured, func(s configuredSink) bool { return s.err != nil }) {
+			reason = "ConfigurationFailed"
+		}
+		instance.setCondition(corev1alpha1.ConditionSinkHealthy, metav1.ConditionFalse, reason, errors.Join(failures...).Error())
+		return
+	}
+	var names []string
+	for _, s := range configured {
+		names = append(names, s.Name)
+	}
+	instance.setCondition(corev1alpha1.ConditionSinkHealthy, metav1.ConditionTrue, "Delivered",
+		fmt.Sprintf("results delivered to sinks %s", strings.Join(names, ", ")))
+}
 
+// pendingChange returns the change of the result the sink has not received yet, if any. Sinks
+// receive new and updated results, results they never received, and deliveries that failed.
+func pendingChange(result corev1alpha1.Result, name string) (corev1alpha1.SinkLifeCycle, bool) {
+	delivery := sinks.Delivery(result.Status, name)
+	switch {
+	case result.Status.LifeCycle == string(resources.CreatedResult) || result.Status.LifeCycle == string(resources.UpdatedResult):
+		return corev1alpha1.SinkLifeCycle(result.Status.LifeCycle), true
+	case delivery == nil:
+		return corev1alpha1.SinkLifeCycleCreated, true
+	case delivery.Error != "":
+		return corev1alpha1.SinkLifeCycle(delivery.LifeCycle), true
+	}
+	return "", false
 }
 
-func (step *ResultStatusStep) processLatestResults(instance *K8sGPTInstance, sinkEnabled bool, sinkType sinks.ISink, latestResultList *corev1alpha1.ResultList) error {
+// processLatestResults sends the results to every sink whose filters match them and returns the
+// deliveries that failed, they are retried by the next reconcile
+func (step *ResultStatusStep) processLatestResults(instance *K8sGPTInstance, configured []configuredSink,
+	latestResultList *corev1alpha1.ResultList) ([]error, error) {
+	var failures []error
+	now := metav1.Now()
 	for _, result := range latestResultList.Items {
 		var res corev1alpha1.Result
 		if err := instance.R.Get(instance.Ctx, client.ObjectKey{Namespace: result.Namespace, Name: result.Name}, &res); err != nil {
-			return err
+			return failures, err
 		}
 
-		if sinkEnabled {
-			if res.Status.LifeCycle != string(resources.NoOpResult) || res.Status.Webhook == "" {
-				if err := sinkType.Emit(res.Spec); err != nil {
-					return err
-				}
-				res.Status.Webhook = instance.K8sgptConfig.Spec.Sink.Endpoint
+		if res.Status.LifeCycle == string(resources.ResolvedResult) {
+			// Resolved results are reported and deleted by the analysis
+			continue
+		}
+		for _, sink := range configured {
+			lifeCycle, pending := pendingChange(res, sink.Name)
+			if !pending || !sinks.Matches(sink.SinkSpec, res.Spec, lifeCycle) {
+				continue
 			}
-		} else {
-			// Remove the Webhook status from results
-			res.Status.Webhook = ""
+			if err := sink.deliver(&res, lifeCycle, now); err != nil {
+				failures = append(failures, err)
+			}
+		}
+		// Deliveries to sinks that were removed are forgotten, the results are sent again if they
+		// are added back
+		res.Status.Deliveries = slices.DeleteFunc(res.Status.Deliveries, func(delivery corev1alpha1.SinkDelivery) bool {
+			return !slices.ContainsFunc(configured, func(s configuredSink) bool { return s.Name == delivery.Name })
+		})
+		// Webhook keeps the endpoint of spec.sink for the results it received
+		res.Status.Webhook = ""
+		if delivery := sinks.Delivery(res.Status, sinks.DefaultSinkName); delivery != nil && delivery.Error == "" &&
+			instance.K8sgptConfig.Spec.Sink != nil {
+			res.Status.Webhook = instance.K8sgptConfig.Spec.Sink.Endpoint
 		}
 		if err := instance.R.Status().Update(instance.Ctx, &res); err != nil {
-			return err
+			return failures, err
 		}
 	}
 
-	return nil
+	return failures, nil
 }
@@ -0,0 +1,154 @@
+/*
+Copyright 2023 The K8sGPT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package k8sgpt
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1alpha1 "github.com/k8sgpt-ai/k8sgpt-operator/api/v1alpha1"
+	"github.com/k8sgpt-ai/k8sgpt-operator/pkg/resources"
+	"github.com/k8sgpt-ai/k8sgpt-operator/pkg/sinks"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("ResultStatusStep", func() {
+	Describe("processLatestResults", func() {
+		var (
+			instance       *K8sGPTInstance
+			c              client.Client
+			everything     *httptest.Server
+			critical       *httptest.Server
+			everythingHits atomic.Int32
+			criticalHits   atomic.Int32
+			criticalStatus atomic.Int32
+		)
+
+		result := func(name string, severity corev1alpha1.Severity) *corev1alpha1.Result {
+			return &corev1alpha1.Result{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "k8sgpt", Name: name, Labels: map[string]string{
+					"k8sgpts.k8sgpt.ai/name":      "k8sgpt",
+					"k8sgpts.k8sgpt.ai/namespace": "k8sgpt",
+				}},
+				Spec:   corev1alpha1.ResultSpec{Kind: "Pod", Name: "default/" + name, Severity: severity},
+				Status: corev1alpha1.ResultStatus{LifeCycle: string(resources.CreatedResult)},
+			}
+		}
+
+		BeforeEach(func() {
+			everythingHits.Store(0)
+			criticalHits.Store(0)
+			criticalStatus.Store(http.StatusOK)
+			everything = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				everythingHits.Add(1)
+			}))
+			critical = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				criticalHits.Add(1)
+				w.WriteHeader(int(criticalStatus.Load()))
+			}))
+			DeferCleanup(everything.Close)
+			DeferCleanup(critical.Close)
+
+			scheme := runtime.NewScheme()
+			Expect(corev1alpha1.AddToScheme(scheme)).To(Succeed())
+			c = fake.NewClientBuilder().WithScheme(scheme).WithStatusSubresource(&corev1alpha1.Result{}).
+				WithObjects(result("web", corev1alpha1.SeverityCritical), result("batch", corev1alpha1.SeverityLow)).
+				Build()
+			instance = &K8sGPTInstance{
+				R:      &K8sGPTReconciler{Client: c, SinkClient: sinks.NewClient(2 * time.Second)},
+				Ctx:    context.Background(),
+				logger: logr.Discard(),
+				K8sgptConfig: &corev1alpha1.K8sGPT{
+					ObjectMeta: metav1.ObjectMeta{Namespace: "k8sgpt", Name: "k8sgpt"},
+					Spec: corev1alpha1.K8sGPTSpec{
+						Sink: &corev1alpha1.WebhookRef{Type: "slack", Endpoint: everything.URL},
+						Sinks: []corev1alpha1.SinkSpec{{
+							Name: "oncall",
+							WebhookRef: corev1alpha1.WebhookRef{Type: "mattermost", Endpoint: critical.URL,
+								MinSeverity: corev1alpha1.SeverityCritical},
+						}},
+					},
+				},
+			}
+		})
+
+		process := func() []error {
+			latest, err := EmitIfNotHistorical(instance)
+			Expect(err).NotTo(HaveOccurred())
+			failures, err := (&ResultStatusStep{}).processLatestResults(instance, initSinks(instance), latest)
+			Expect(err).NotTo(HaveOccurred())
+			return failures
+		}
+		stored := func(name string) corev1alpha1.Result {
+			var res corev1alpha1.Result
+			Expect(c.Get(context.Background(), client.ObjectKey{Namespace: "k8sgpt", Name: name}, &res)).To(Succeed())
+			return res
+		}
+
+		It("should send every result to the sinks whose filters match it", func() {
+			Expect(process()).To(BeEmpty())
+			Expect(everythingHits.Load()).To(BeEquivalentTo(2))
+			Expect(criticalHits.Load()).To(BeEquivalentTo(1))
+
+			web := stored("web")
+			Expect(web.Status.Deliveries).To(HaveLen(2))
+			Expect(web.Status.Webhook).To(Equal(everything.URL))
+			Expect(stored("batch").Status.Deliveries).To(HaveLen(1))
+		})
+
+		It("should retry only the deliveries that failed", func() {
+			criticalStatus.Store(http.StatusServiceUnavailable)
+			Expect(process()).To(HaveLen(1))
+			Expect(sinks.Delivery(stored("web").Status, "oncall").Error).NotTo(BeEmpty())
+
+			// The results are historical on the next reconcile, only the failed delivery is sent again
+			for _, name := range []string{"web", "batch"} {
+				res := stored(name)
+				res.Status.LifeCycle = string(resources.NoOpResult)
+				Expect(c.Status().Update(context.Background(), &res)).To(Succeed())
+			}
+			criticalStatus.Store(http.StatusOK)
+			Expect(process()).To(BeEmpty())
+			Expect(everythingHits.Load()).To(BeEquivalentTo(2))
+			Expect(criticalHits.Load()).To(BeEquivalentTo(2))
+			Expect(sinks.Delivery(stored("web").Status, "oncall").Error).To(BeEmpty())
+		})
+
+		It("should not fall back to another sink for an unknown type", func() {
+			instance.K8sgptConfig.Spec.Sinks[0].Type = "unknown"
+			configured := initSinks(instance)
+			latest, err := EmitIfNotHistorical(instance)
+			Expect(err).NotTo(HaveOccurred())
+			failures, err := (&ResultStatusStep{}).processLatestResults(instance, configured, latest)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(failures).To(HaveLen(1))
+			Expect(criticalHits.Load()).To(BeZero())
+			Expect(sinks.Delivery(stored("web").Status, "oncall").Error).To(ContainSubstring("unknown sink type"))
+
+			setSinkCondition(instance, configured, failures)
+			condition := meta.FindStatusCondition(instance.K8sgptConfig.Status.Conditions, corev1alpha1.ConditionSinkHealthy)
+			Expect(condition.Reason).To(Equal("ConfigurationFailed"))
+		})
+	})
+})
@@ -0,0 +1,143 @@
+/*
+Copyright 2023 The K8sGPT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package k8sgpt
+
+import (
+	"context"
+	"time"
+
+	corev1alpha1 "github.com/k8sgpt-ai/k8sgpt-operator/api/v1alpha1"
+	metricspkg "github.com/k8sgpt-ai/k8sgpt-operator/pkg/metrics"
+	"github.com/k8sgpt-ai/k8sgpt-operator/pkg/resources"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// ResultGCInterval is the time between two runs of the result garbage collector
+const ResultGCInterval = 5 * time.Minute
+
+var _ manager.LeaderElectionRunnable = (*ResultGarbageCollector)(nil)
+
+// ResultGarbageCollector enforces the retention of the K8sGPT instances on their results between
+// analysis runs, e.g. after the retention changed or while the analysis fails
+type ResultGarbageCollector struct {
+	client.Client
+	MetricsBuilder *metricspkg.MetricBuilder
+	// Interval is the time between two runs, ResultGCInterval when unset
+	Interval time.Duration
+}
+
+// Start runs the garbage collector until the context is done
+func (gc *ResultGarbageCollector) Start(ctx context.Context) error {
+	interval := gc.Interval
+	if interval <= 0 {
+		interval = ResultGCInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := gc.Collect(ctx); err != nil {
+				log.FromContext(ctx).Error(err, "Result garbage collection failed")
+			}
+		}
+	}
+}
+
+// NeedLeaderElection runs the garbage collector on the leader only
+func (gc *ResultGarbageCollector) NeedLeaderElection() bool {
+	return true
+}
+
+// Collect evicts the results the retention of their K8sGPT instance does not keep and truncates
+// the details longer than it allows
+func (gc *ResultGarbageCollector) Collect(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("result-gc")
+	var configs corev1alpha1.K8sGPTList
+	if err := gc.List(ctx, &configs); err != nil {
+		return err
+	}
+
+	now := metav1.Now()
+	for i := range configs.Items {
+		config := &configs.Items[i]
+		if config.Spec.Retention == nil {
+			continue
+		}
+		var results corev1alpha1.ResultList
+		if err := gc.List(ctx, &results, client.InNamespace(config.Namespace), client.MatchingLabels{
+			"k8sgpts.k8sgpt.ai/name":      config.Name,
+			"k8sgpts.k8sgpt.ai/namespace": config.Namespace,
+		}); err != nil {
+			return err
+		}
+
+		evicted := map[string]bool{}
+		var tombstones []corev1alpha1.ResultTombstone
+		for _, eviction := range resources.RetainResults(results.Items, *config.Spec.Retention, now) {
+			if err := gc.Delete(ctx, &eviction.Result); client.IgnoreNotFound(err) != nil {
+				return err
+			}
+			evicted[eviction.Result.Name] = true
+			tombstones = append(tombstones, resources.EvictionTombstone(eviction.Result, eviction.Reason, now))
+			countEviction(gc.MetricsBuilder, config, eviction.Reason)
+			logger.Info("Evicted result", "name", eviction.Result.Name, "reason", eviction.Reason)
+		}
+		if err := gc.addTombstones(ctx, config, tombstones, now); err != nil {
+			return err
+		}
+
+		for _, result := range results.Items {
+			if evicted[result.Name] || !resources.TruncateDetails(&result.Spec, config.Spec.Retention.MaxDetailsLength) {
+				continue
+			}
+			// The next analysis run truncates the details the same way, they are not a change
+			metav1.SetMetaDataAnnotation(&result.ObjectMeta, resources.ContentHashAnnotation, resources.ContentHash(result.Spec))
+			if err := gc.Update(ctx, &result); client.IgnoreNotFound(err) != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// addTombstones keeps the history of the evicted results on the status of the K8sGPT instance, so
+// that the next analysis run does not take them for new results
+func (gc *ResultGarbageCollector) addTombstones(ctx context.Context, config *corev1alpha1.K8sGPT,
+	tombstones []corev1alpha1.ResultTombstone, now metav1.Time) error {
+	if len(tombstones) == 0 {
+		return nil
+	}
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var latest corev1alpha1.K8sGPT
+		if err := gc.Get(ctx, client.ObjectKeyFromObject(config), &latest); err != nil {
+			return err
+		}
+		for _, tombstone := range tombstones {
+			takeTombstone(&latest.Status, tombstone.Name)
+			latest.Status.ResultTombstones = append(latest.Status.ResultTombstones, tombstone)
+		}
+		latest.Status.ResultTombstones = resources.PruneTombstones(latest.Status.ResultTombstones, now,
+			resources.TombstoneTTL(latest), resources.MaxTombstones(latest))
+		return gc.Status().Update(ctx, &latest)
+	})
+	return client.IgnoreNotFound(err)
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2023 The K8sGPT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package k8sgpt
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	corev1alpha1 "github.com/k8sgpt-ai/k8sgpt-operator/api/v1alpha1"
+	metricspkg "github.com/k8sgpt-ai/k8sgpt-operator/pkg/metrics"
+	"github.com/k8sgpt-ai/k8sgpt-operator/pkg/resources"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("ResultGarbageCollector", func() {
+	var (
+		gc  *ResultGarbageCollector
+		ctx context.Context
+	)
+
+	result := func(name string, severity corev1alpha1.Severity, lastSeen time.Duration, details string) *corev1alpha1.Result {
+		seen := metav1.NewTime(time.Now().Add(-lastSeen))
+		return &corev1alpha1.Result{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "k8sgpt", Name: name, Labels: map[string]string{
+				"k8sgpts.k8sgpt.ai/name":      "k8sgpt",
+				"k8sgpts.k8sgpt.ai/namespace": "k8sgpt",
+			}},
+			Spec:   corev1alpha1.ResultSpec{Severity: severity, Details: details},
+			Status: corev1alpha1.ResultStatus{FirstSeen: &seen, LastSeen: &seen},
+		}
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		scheme := runtime.NewScheme()
+		Expect(corev1alpha1.AddToScheme(scheme)).To(Succeed())
+		config := &corev1alpha1.K8sGPT{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "k8sgpt", Name: "k8sgpt"},
+			Spec: corev1alpha1.K8sGPTSpec{Retention: &corev1alpha1.RetentionConfig{
+				MaxResults:       2,
+				MaxAge:           "1h",
+				MaxDetailsLength: 20,
+			}},
+		}
+		gc = &ResultGarbageCollector{
+			Client: fake.NewClientBuilder().WithScheme(scheme).WithStatusSubresource(config).WithObjects(config,
+				result("critical", corev1alpha1.SeverityCritical, 0, strings.Repeat("x", 40)),
+				result("medium", corev1alpha1.SeverityMedium, 0, "short"),
+				result("low", corev1alpha1.SeverityLow, 0, "short"),
+				result("stale", corev1alpha1.SeverityHigh, 2*time.Hour, "short"),
+			).Build(),
+			MetricsBuilder: metricspkg.NewMetricBuilder(),
+		}
+	})
+
+	Describe("Collect", func() {
+		It("should evict the results the retention does not keep", func() {
+			Expect(gc.Collect(ctx)).To(Succeed())
+
+			var results corev1alpha1.ResultList
+			Expect(gc.List(ctx, &results)).To(Succeed())
+			var names []string
+			for _, result := range results.Items {
+				names = append(names, result.Name)
+			}
+			Expect(names).To(ConsistOf("critical", "medium"))
+		})
+
+		It("should keep the history of the evicted results", func() {
+			Expect(gc.Collect(ctx)).To(Succeed())
+
+			var config corev1alpha1.K8sGPT
+			Expect(gc.Get(ctx, client.ObjectKey{Namespace: "k8sgpt", Name: "k8sgpt"}, &config)).To(Succeed())
+			evicted := map[string]string{}
+			for _, tombstone := range config.Status.ResultTombstones {
+				evicted[tombstone.Name] = tombstone.Evicted
+			}
+			Expect(evicted).To(Equal(map[string]string{
+				"stale": resources.EvictedMaxAge,
+				"low":   resources.EvictedMaxResults,
+			}))
+		})
+
+		It("should truncate long details and record their content hash", func() {
+			Expect(gc.Collect(ctx)).To(Succeed())
+
+			var critical corev1alpha1.Result
+			Expect(gc.Get(ctx, client.ObjectKey{Namespace: "k8sgpt", Name: "critical"}, &critical)).To(Succeed())
+			Expect(len(critical.Spec.Details)).To(BeNumerically("<=", 20))
+			Expect(critical.Annotations[resources.ContentHashAnnotation]).To(Equal(resources.ContentHash(critical.Spec)))
+		})
+	})
+})
@@ -0,0 +1,82 @@
+/*
+Copyright 2023 The K8sGPT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package k8sgpt
+
+import (
+	corev1alpha1 "github.com/k8sgpt-ai/k8sgpt-operator/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// setCondition records a condition on the in-memory status of the instance.
+// The status is written back to the API server once at the end of the reconcile.
+func (instance *K8sGPTInstance) setCondition(conditionType string, status metav1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(&instance.K8sgptConfig.Status.Conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		ObservedGeneration: instance.K8sgptConfig.Generation,
+		Reason:             reason,
+		Message:            message,
+	})
+}
+
+// readyCondition derives the Ready condition from the server and analysis conditions
+// and the outcome of the reconcile. AI backend and sink health degrade the instance
+// but do not make it unready.
+func readyCondition(status *corev1alpha1.K8sGPTStatus, reconcileErr error) (metav1.ConditionStatus, string, string) {
+	for _, conditionType := range []string{corev1alpha1.ConditionServerAvailable, corev1alpha1.ConditionAnalysisSucceeded} {
+		condition := meta.FindStatusCondition(status.Conditions, conditionType)
+		if condition == nil {
+			return metav1.ConditionUnknown, "Pending", conditionType + " has not been reported yet"
+		}
+		if condition.Status != metav1.ConditionTrue {
+			return metav1.ConditionFalse, condition.Reason, condition.Message
+		}
+	}
+	if reconcileErr != nil {
+		return metav1.ConditionFalse, "ReconcileError", reconcileErr.Error()
+	}
+	return metav1.ConditionTrue, "Ready", "k8sgpt is analysing the cluster"
+}
+
+// updateStatus persists the status collected by the steps during this reconcile
+func (instance *K8sGPTInstance) updateStatus(reconcileErr error) error {
+	if instance.K8sgptConfig == nil || !instance.K8sgptConfig.DeletionTimestamp.IsZero() {
+		return nil
+	}
+
+	instance.K8sgptConfig.Status.ObservedGeneration = instance.K8sgptConfig.Generation
+	instance.K8sgptConfig.Status.LastError = ""
+	if reconcileErr != nil {
+		instance.K8sgptConfig.Status.LastError = reconcileErr.Error()
+	}
+	status, reason, message := readyCondition(&instance.K8sgptConfig.Status, reconcileErr)
+	instance.setCondition(corev1alpha1.ConditionReady, status, reason, message)
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		latest := &corev1alpha1.K8sGPT{}
+		if err := instance.R.Get(instance.Ctx, instance.req.NamespacedName, latest); err != nil {
+			return client.IgnoreNotFound(err)
+		}
+		if equality.Semantic.DeepEqual(latest.Status, instance.K8sgptConfig.Status) {
+			return nil
+		}
+		latest.Status = instance.K8sgptConfig.Status
+		return instance.R.Status().Update(instance.Ctx, latest)
+	})
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2023 The K8sGPT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package k8sgpt
+
+import (
+	"errors"
+
+	corev1alpha1 "github.com/k8sgpt-ai/k8sgpt-operator/api/v1alpha1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Status", func() {
+	var status *corev1alpha1.K8sGPTStatus
+
+	BeforeEach(func() {
+		status = &corev1alpha1.K8sGPTStatus{
+			Conditions: []metav1.Condition{
+				{Type: corev1alpha1.ConditionServerAvailable, Status: metav1.ConditionTrue, Reason: "Connected"},
+				{Type: corev1alpha1.ConditionAnalysisSucceeded, Status: metav1.ConditionTrue, Reason: "AnalysisCompleted"},
+			},
+		}
+	})
+
+	Describe("readyCondition", func() {
+		Context("when the server is available and analysis succeeded", func() {
+			It("should be ready", func() {
+				conditionStatus, reason, _ := readyCondition(status, nil)
+				Expect(conditionStatus).To(Equal(metav1.ConditionTrue))
+				Expect(reason).To(Equal("Ready"))
+			})
+		})
+
+		Context("when the server is not available", func() {
+			It("should report the server condition reason", func() {
+				status.Conditions[0].Status = metav1.ConditionFalse
+				status.Conditions[0].Reason = "NoReplicasAvailable"
+				conditionStatus, reason, _ := readyCondition(status, nil)
+				Expect(conditionStatus).To(Equal(metav1.ConditionFalse))
+				Expect(reason).To(Equal("NoReplicasAvailable"))
+			})
+		})
+
+		Context("when analysis has not run yet", func() {
+			It("should be unknown", func() {
+				status.Conditions = status.Conditions[:1]
+				conditionStatus, _, _ := readyCondition(status, nil)
+				Expect(conditionStatus).To(Equal(metav1.ConditionUnknown))
+			})
+		})
+
+		Context("when a later step failed", func() {
+			It("should not be ready", func() {
+				conditionStatus, reason, message := readyCondition(status, errors.New("sink unreachable"))
+				Expect(conditionStatus).To(Equal(metav1.ConditionFalse))
+				Expect(reason).To(Equal("ReconcileError"))
+				Expect(message).To(Equal("sink unreachable"))
+			})
+		})
+	})
+})
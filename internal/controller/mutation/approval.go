@@ -0,0 +1,280 @@
+/*
+Copyright 2023 K8sGPT Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mutation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	corev1alpha1 "github.com/k8sgpt-ai/k8sgpt-operator/api/v1alpha1"
+	"github.com/k8sgpt-ai/k8sgpt-operator/internal/controller/conversions"
+	"github.com/k8sgpt-ai/k8sgpt-operator/internal/controller/util"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// approveVerb is the verb a reviewer needs on a mutation for their decision to be honored
+const approveVerb = "approve"
+
+// approvalPolicyLabel marks the admission policy that checks the reviewer of decisions and its
+// binding, config/approval ships them
+const approvalPolicyLabel = "k8sgpt.ai/mutation-approval"
+
+// reviewerDecision is a decision on a mutation and the reviewer it names
+type reviewerDecision struct {
+	Decision corev1alpha1.ApprovalDecision
+	Reviewer string
+	// DecidedAt is when the API server recorded the decision, nil when it cannot tell
+	DecidedAt *metav1.Time
+}
+
+// requiresApproval reports whether mutations of the K8sGPT instance wait for a reviewer
+func requiresApproval(k8sgpt *corev1alpha1.K8sGPT) bool {
+	return k8sgpt != nil && k8sgpt.Spec.AI != nil &&
+		k8sgpt.Spec.AI.AutoRemediation.ApprovalMode == corev1alpha1.ApprovalModeManual
+}
+
+// approvalDecision returns the reviewer decision on a mutation. The spec field takes precedence
+// over the annotations. The decision is dated by the managed fields entry that last wrote it.
+func approvalDecision(mutation corev1alpha1.Mutation) reviewerDecision {
+	if approval := mutation.Spec.Approval; approval != nil && approval.Decision != "" {
+		return reviewerDecision{Decision: approval.Decision, Reviewer: approval.Reviewer,
+			DecidedAt: writtenAt(mutation, "f:spec", "f:approval")}
+	}
+	var decision corev1alpha1.ApprovalDecision
+	switch strings.ToLower(mutation.GetAnnotations()[corev1alpha1.ApprovalAnnotation]) {
+	case "approved":
+		decision = corev1alpha1.ApprovalDecisionApproved
+	case "rejected":
+		decision = corev1alpha1.ApprovalDecisionRejected
+	default:
+		return reviewerDecision{}
+	}
+	return reviewerDecision{Decision: decision, Reviewer: mutation.GetAnnotations()[corev1alpha1.ReviewerAnnotation],
+		DecidedAt: writtenAt(mutation, "f:metadata", "f:annotations", "f:"+corev1alpha1.ApprovalAnnotation)}
+}
+
+// writtenAt returns when the field at the path of the mutation, not of its status, was last
+// written according to its managed fields
+func writtenAt(mutation corev1alpha1.Mutation, path ...string) *metav1.Time {
+	var last *metav1.Time
+	for _, entry := range mutation.GetManagedFields() {
+		if entry.Subresource != "" || entry.FieldsV1 == nil || entry.Time == nil {
+			continue
+		}
+		var fields map[string]interface{}
+		if err := json.Unmarshal(entry.FieldsV1.Raw, &fields); err != nil {
+			continue
+		}
+		if _, found, _ := unstructured.NestedFieldNoCopy(fields, path...); found && (last == nil || last.Before(entry.Time)) {
+			last = entry.Time
+		}
+	}
+	return last
+}
+
+// mayApprove asks the API server whether the reviewer may use the approve verb on the mutation.
+// The review is made for the user alone, the operator does not know the groups of the reviewer.
+func (r *MutationReconciler) mayApprove(ctx context.Context, mutation *corev1alpha1.Mutation, reviewer string) (bool, error) {
+	review := &authorizationv1.SubjectAccessReview{Spec: authorizationv1.SubjectAccessReviewSpec{
+		User: reviewer,
+		ResourceAttributes: &authorizationv1.ResourceAttributes{
+			Namespace: mutation.Namespace,
+			Verb:      approveVerb,
+			Group:     corev1alpha1.GroupVersion.Group,
+			Resource:  "mutations",
+			Name:      mutation.Name,
+		},
+	}}
+	if err := r.Create(ctx, review); err != nil {
+		return false, err
+	}
+	return review.Status.Allowed, nil
+}
+
+// approvalPolicyInstalled reports whether the admission policy that checks the reviewer of
+// decisions is installed and bound to deny. It is found by its label, kustomize prefixes its name.
+func (r *MutationReconciler) approvalPolicyInstalled(ctx context.Context) (bool, error) {
+	var bindings admissionregistrationv1.ValidatingAdmissionPolicyBindingList
+	if err := r.List(ctx, &bindings, client.HasLabels{approvalPolicyLabel}); err != nil {
+		if meta.IsNoMatchError(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	for _, binding := range bindings.Items {
+		if !slices.Contains(binding.Spec.ValidationActions, admissionregistrationv1.Deny) {
+			continue
+		}
+		var policy admissionregistrationv1.ValidatingAdmissionPolicy
+		if err := r.Get(ctx, client.ObjectKey{Name: binding.Spec.PolicyName}, &policy); err != nil {
+			if client.IgnoreNotFound(err) != nil {
+				return false, err
+			}
+			continue
+		}
+		if _, ok := policy.Labels[approvalPolicyLabel]; ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// planMutation resolves the object the mutation will write, dry-run applies it and stores the
+// planned configuration, diff and validation errors for the reviewer
+func (r *MutationReconciler) planMutation(ctx context.Context, mutation *corev1alpha1.Mutation,
+	config conversions.ObjectExecutionConfig, budget int) (ctrl.Result, error) {
+	target, err := conversions.ResolveTarget(config)
+	if errors.Is(err, conversions.ErrUnsupportedKind) {
+		return r.failNow(ctx, mutation, corev1alpha1.MutationFailureUnsupportedKind, err)
+	}
+	var refused *conversions.GuardrailError
+	if errors.As(err, &refused) {
+		return r.refuse(ctx, mutation, refused)
+	}
+	if err != nil {
+		mutationControllerLog.Error(err, "unable to resolve mutation target", "mutation", mutation.Name)
+		return r.retryOrFail(ctx, mutation, budget, corev1alpha1.MutationFailureResolveFailed, err)
+	}
+	// Targets that would be refused on apply are not offered for review
+	if err := conversions.CheckScope(config, target); err != nil {
+		return r.outOfScope(ctx, mutation, budget, err)
+	}
+	if err := conversions.Guardrails(config, target, r.Schemas); err != nil {
+		if errors.As(err, &refused) {
+			return r.refuse(ctx, mutation, refused)
+		}
+		mutationControllerLog.Error(err, "unable to check mutation guardrails", "mutation", mutation.Name)
+		return r.retryOrFail(ctx, mutation, budget, corev1alpha1.MutationFailureApplyFailed, err)
+	}
+	violations, err := conversions.CheckPolicies(config, target)
+	if err != nil {
+		mutationControllerLog.Error(err, "unable to evaluate remediation policies", "mutation", mutation.Name)
+		return r.retryOrFail(ctx, mutation, budget, corev1alpha1.MutationFailureApplyFailed, err)
+	}
+	if len(violations) > 0 {
+		return r.block(ctx, mutation, violations)
+	}
+	planned, err := conversions.ToApplyObject(target)
+	if err != nil {
+		mutationControllerLog.Error(err, "unable to convert mutation target", "mutation", mutation.Name)
+		return r.failNow(ctx, mutation, corev1alpha1.MutationFailureInvalidTarget, err)
+	}
+	manifest, err := conversions.ToManifest(planned)
+	if err != nil {
+		mutationControllerLog.Error(err, "unable to render mutation target", "mutation", mutation.Name)
+		return r.failNow(ctx, mutation, corev1alpha1.MutationFailureInvalidTarget, err)
+	}
+	diff, validationErrors, err := conversions.DryRunApply(ctx, r.Client, planned,
+		mutation.Spec.OriginConfiguration, config.ForceApply)
+	if err != nil {
+		mutationControllerLog.Error(err, "unable to dry-run mutation", "mutation", mutation.Name)
+		return r.retryOrFail(ctx, mutation, budget, corev1alpha1.MutationFailureApplyFailed, err)
+	}
+
+	plannedAt := metav1.Now()
+	mutation.Status.PlannedConfiguration = manifest
+	mutation.Status.PlannedAt = &plannedAt
+	mutation.Status.Diff = diff
+	mutation.Status.ValidationErrors = validationErrors
+	message := "Awaiting approval"
+	if len(validationErrors) > 0 {
+		message = fmt.Sprintf("Awaiting approval, dry-run reported %d validation errors", len(validationErrors))
+	}
+	mutationControllerLog.Info("Mutation planned, awaiting approval", "mutation", mutation.Name)
+	return r.moveTo(ctx, mutation, corev1alpha1.AutoRemediationAwaitingApproval, message,
+		ctrl.Result{RequeueAfter: util.PendingRequeueTime})
+}
+
+// reviewMutation moves a planned mutation on once a reviewer has approved or rejected it,
+// approved mutations are subject to the blast radius limits of the instance. Decisions are
+// ignored while the approval admission policy is not installed, since nothing then checks that
+// the reviewer made them, and so are decisions that name no reviewer, that were made before the
+// plan or by a reviewer who may not approve mutations.
+func (r *MutationReconciler) reviewMutation(ctx context.Context, mutation *corev1alpha1.Mutation,
+	k8sgpt *corev1alpha1.K8sGPT) (ctrl.Result, error) {
+	review := approvalDecision(*mutation)
+	if review.Decision == "" {
+		mutationControllerLog.Info("Mutation is awaiting approval", "mutation", mutation.Name)
+		return ctrl.Result{RequeueAfter: util.PendingRequeueTime}, nil
+	}
+	installed, err := r.approvalPolicyInstalled(ctx)
+	if err != nil {
+		mutationControllerLog.Error(err, "unable to look up the approval admission policy", "mutation", mutation.Name)
+		return ctrl.Result{RequeueAfter: util.ErrorRequeueTime}, err
+	}
+	if !installed {
+		return r.ignoreDecision(ctx, mutation, "the approval admission policy is not installed")
+	}
+	if review.Reviewer == "" {
+		return r.ignoreDecision(ctx, mutation, "the decision names no reviewer")
+	}
+	plannedAt := mutation.Status.PlannedAt
+	if plannedAt != nil && (review.DecidedAt == nil || review.DecidedAt.Before(plannedAt)) {
+		return r.ignoreDecision(ctx, mutation, "the decision was made before the current plan")
+	}
+	allowed, err := r.mayApprove(ctx, mutation, review.Reviewer)
+	if err != nil {
+		mutationControllerLog.Error(err, "unable to review approver", "mutation", mutation.Name)
+		return ctrl.Result{RequeueAfter: util.ErrorRequeueTime}, err
+	}
+	if !allowed {
+		return r.ignoreDecision(ctx, mutation, fmt.Sprintf("%s may not approve mutations", review.Reviewer))
+	}
+
+	var to corev1alpha1.AutoRemediationPhase
+	var message string
+	switch review.Decision {
+	case corev1alpha1.ApprovalDecisionApproved:
+		to, message = corev1alpha1.AutoRemediationPhaseInProgress, fmt.Sprintf("Approved by %s", review.Reviewer)
+	default:
+		to, message = corev1alpha1.AutoRemediationAborted, fmt.Sprintf("Rejected by %s", review.Reviewer)
+	}
+	mutation.Status.Approval = &corev1alpha1.ApprovalRecord{
+		Decision: review.Decision,
+		Reviewer: review.Reviewer,
+		Time:     metav1.Now(),
+	}
+	mutationControllerLog.Info("Mutation reviewed", "mutation", mutation.Name, "decision", review.Decision,
+		"reviewer", review.Reviewer)
+	if to == corev1alpha1.AutoRemediationPhaseInProgress {
+		return r.startOrQueue(ctx, mutation, k8sgpt, message)
+	}
+	return r.moveTo(ctx, mutation, to, message, ctrl.Result{RequeueAfter: util.NotStartedRequeueTime})
+}
+
+// ignoreDecision keeps the mutation awaiting approval and records why the decision on it is not
+// honored, the status is only written when the reason changes
+func (r *MutationReconciler) ignoreDecision(ctx context.Context, mutation *corev1alpha1.Mutation,
+	reason string) (ctrl.Result, error) {
+	message := "Awaiting approval, decision ignored: " + reason
+	mutationControllerLog.Info("Ignoring approval decision", "mutation", mutation.Name, "reason", reason)
+	if mutation.Status.Message == message {
+		return ctrl.Result{RequeueAfter: util.PendingRequeueTime}, nil
+	}
+	return r.moveTo(ctx, mutation, corev1alpha1.AutoRemediationAwaitingApproval, message,
+		ctrl.Result{RequeueAfter: util.PendingRequeueTime})
+}
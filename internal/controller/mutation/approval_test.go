@@ -0,0 +1,102 @@
+package mutation
+
+import (
+	"testing"
+	"time"
+
+	corev1alpha1 "github.com/k8sgpt-ai/k8sgpt-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_ApprovalDecision(t *testing.T) {
+	earlier := metav1.NewTime(time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC))
+	later := metav1.NewTime(earlier.Add(time.Hour))
+	annotated := func(manager string, at metav1.Time) metav1.ManagedFieldsEntry {
+		return metav1.ManagedFieldsEntry{Manager: manager, Time: &at, FieldsV1: &metav1.FieldsV1{
+			Raw: []byte(`{"f:metadata":{"f:annotations":{"f:k8sgpt.ai/approval":{}}}}`)}}
+	}
+	tests := []struct {
+		name              string
+		mutation          corev1alpha1.Mutation
+		expectedDecision  corev1alpha1.ApprovalDecision
+		expectedReviewer  string
+		expectedDecidedAt *metav1.Time
+	}{
+		{
+			name: "no decision",
+		},
+		{
+			name: "spec field takes precedence over annotations",
+			mutation: corev1alpha1.Mutation{
+				ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{corev1alpha1.ApprovalAnnotation: "rejected"}},
+				Spec: corev1alpha1.MutationSpec{Approval: &corev1alpha1.MutationApproval{
+					Decision: corev1alpha1.ApprovalDecisionApproved, Reviewer: "alex"}},
+			},
+			expectedDecision: corev1alpha1.ApprovalDecisionApproved,
+			expectedReviewer: "alex",
+		},
+		{
+			name: "annotation with reviewer",
+			mutation: corev1alpha1.Mutation{
+				ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+					corev1alpha1.ApprovalAnnotation: "Rejected",
+					corev1alpha1.ReviewerAnnotation: "sam",
+				}},
+			},
+			expectedDecision: corev1alpha1.ApprovalDecisionRejected,
+			expectedReviewer: "sam",
+		},
+		{
+			name: "field manager is not taken as the reviewer",
+			mutation: corev1alpha1.Mutation{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations:   map[string]string{corev1alpha1.ApprovalAnnotation: "approved"},
+					ManagedFields: []metav1.ManagedFieldsEntry{annotated("kubectl-annotate", earlier)},
+				},
+			},
+			expectedDecision:  corev1alpha1.ApprovalDecisionApproved,
+			expectedDecidedAt: &earlier,
+		},
+		{
+			name: "decision is dated by the latest spec write",
+			mutation: corev1alpha1.Mutation{
+				ObjectMeta: metav1.ObjectMeta{ManagedFields: []metav1.ManagedFieldsEntry{
+					annotated("kubectl-annotate", later),
+					{Manager: "kubectl-edit", Time: &earlier, FieldsV1: &metav1.FieldsV1{
+						Raw: []byte(`{"f:spec":{"f:approval":{"f:decision":{}}}}`)}},
+					{Manager: "kubectl-patch", Time: &later, FieldsV1: &metav1.FieldsV1{
+						Raw: []byte(`{"f:spec":{"f:approval":{"f:reviewer":{}}}}`)}},
+					{Manager: "manager", Time: &later, Subresource: "status", FieldsV1: &metav1.FieldsV1{
+						Raw: []byte(`{"f:spec":{"f:approval":{}}}`)}},
+				}},
+				Spec: corev1alpha1.MutationSpec{Approval: &corev1alpha1.MutationApproval{
+					Decision: corev1alpha1.ApprovalDecisionRejected, Reviewer: "alex"}},
+			},
+			expectedDecision:  corev1alpha1.ApprovalDecisionRejected,
+			expectedReviewer:  "alex",
+			expectedDecidedAt: &later,
+		},
+		{
+			name: "unknown annotation value is ignored",
+			mutation: corev1alpha1.Mutation{
+				ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{corev1alpha1.ApprovalAnnotation: "maybe"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			review := approvalDecision(tt.mutation)
+			assert.Equal(t, tt.expectedDecision, review.Decision)
+			assert.Equal(t, tt.expectedReviewer, review.Reviewer)
+			assert.Equal(t, tt.expectedDecidedAt, review.DecidedAt)
+		})
+	}
+}
+
+func Test_RequiresApproval(t *testing.T) {
+	assert.False(t, requiresApproval(nil))
+	assert.False(t, requiresApproval(&corev1alpha1.K8sGPT{}))
+	assert.True(t, requiresApproval(&corev1alpha1.K8sGPT{Spec: corev1alpha1.K8sGPTSpec{AI: &corev1alpha1.AISpec{
+		AutoRemediation: corev1alpha1.AutoRemediation{ApprovalMode: corev1alpha1.ApprovalModeManual}}}}))
+}
@@ -0,0 +1,212 @@
+/*
+Copyright 2023 K8sGPT Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mutation
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1alpha1 "github.com/k8sgpt-ai/k8sgpt-operator/api/v1alpha1"
+	"github.com/k8sgpt-ai/k8sgpt-operator/internal/controller/util"
+	"github.com/robfig/cron/v3"
+	corev1 "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// dailyCapWindow is the period MaxDailyPerTarget is counted over
+const dailyCapWindow = 24 * time.Hour
+
+// isInFlight reports whether the mutation is being applied or awaiting its result
+func isInFlight(mutation *corev1alpha1.Mutation) bool {
+	switch mutation.Status.Phase {
+	case corev1alpha1.AutoRemediationPhaseInProgress, corev1alpha1.AutoRemediationPhaseCompleted:
+		return true
+	case corev1alpha1.AutoRemediationPending:
+		return !isHeld(mutation)
+	}
+	return false
+}
+
+// startOrQueue moves the mutation to InProgress when the blast radius limits of its instance let
+// it through and holds it in Pending otherwise. A held mutation is only written again when the
+// reason it is held changes.
+func (r *MutationReconciler) startOrQueue(ctx context.Context, mutation *corev1alpha1.Mutation,
+	k8sgpt *corev1alpha1.K8sGPT, message string) (ctrl.Result, error) {
+	reason, retryAfter, err := r.admit(ctx, mutation, k8sgpt, time.Now())
+	if err != nil {
+		mutationControllerLog.Error(err, "unable to check blast radius limits", "mutation", mutation.Name)
+		return ctrl.Result{RequeueAfter: util.ErrorRequeueTime}, err
+	}
+	if reason == "" {
+		return r.moveTo(ctx, mutation, corev1alpha1.AutoRemediationPhaseInProgress, message,
+			ctrl.Result{RequeueAfter: util.NotStartedRequeueTime})
+	}
+	queued := "Queued: " + reason
+	mutationControllerLog.Info("Mutation queued", "mutation", mutation.Name, "reason", reason, "retryAfter", retryAfter)
+	if isHeld(mutation) && mutation.Status.HeldBy == reason {
+		return ctrl.Result{RequeueAfter: retryAfter}, nil
+	}
+	mutation.Status.HeldBy = reason
+	return r.moveTo(ctx, mutation, corev1alpha1.AutoRemediationPending, queued, ctrl.Result{RequeueAfter: retryAfter})
+}
+
+// admit returns why the mutation may not be applied at now and when to check again, the reason
+// is empty when it may be applied. A workload is locked by the mutation in flight for it, the
+// other limits only apply when the instance sets them. The mutations are counted from a live
+// read, the cache may not hold the mutations the last reconciles admitted yet.
+func (r *MutationReconciler) admit(ctx context.Context, mutation *corev1alpha1.Mutation,
+	k8sgpt *corev1alpha1.K8sGPT, now time.Time) (string, time.Duration, error) {
+	var reader client.Reader = r.Client
+	if r.APIReader != nil {
+		reader = r.APIReader
+	}
+	var mutations corev1alpha1.MutationList
+	if err := reader.List(ctx, &mutations); err != nil {
+		return "", 0, err
+	}
+	target := mutationWorkload(mutation)
+	for _, other := range mutations.Items {
+		if other.Namespace == mutation.Namespace && other.Name == mutation.Name {
+			continue
+		}
+		if isInFlight(&other) && util.SameWorkload(mutationWorkload(&other), target) {
+			return fmt.Sprintf("%s is locked by mutation %s/%s", describeRef(target), other.Namespace, other.Name),
+				util.PendingRequeueTime, nil
+		}
+	}
+
+	if k8sgpt == nil || k8sgpt.Spec.AI == nil || k8sgpt.Spec.AI.AutoRemediation.BlastRadius == nil {
+		return "", 0, nil
+	}
+	limits := k8sgpt.Spec.AI.AutoRemediation.BlastRadius
+
+	open, opensAt, err := inMaintenanceWindow(limits.MaintenanceWindows, now)
+	if err != nil {
+		return fmt.Sprintf("invalid maintenance window: %v", err), util.FailedRequeueTime, nil
+	}
+	if !open {
+		return fmt.Sprintf("outside maintenance windows, next opens at %s", opensAt.UTC().Format(time.RFC3339)),
+			opensAt.Sub(now), nil
+	}
+
+	cooldown, _ := time.ParseDuration(limits.Cooldown)
+	var inFlight, inFlightInNamespace, appliedToday int
+	var lastApplied, firstAppliedToday time.Time
+	for _, other := range mutations.Items {
+		if other.Namespace == mutation.Namespace && other.Name == mutation.Name {
+			continue
+		}
+		if isInFlight(&other) {
+			inFlight++
+			if other.Spec.ResourceRef.Namespace == target.Namespace {
+				inFlightInNamespace++
+			}
+		}
+		if !util.SameWorkload(mutationWorkload(&other), target) || other.Status.AppliedAt == nil {
+			continue
+		}
+		applied := other.Status.AppliedAt.Time
+		if applied.After(lastApplied) {
+			lastApplied = applied
+		}
+		if now.Sub(applied) < dailyCapWindow {
+			appliedToday++
+			if firstAppliedToday.IsZero() || applied.Before(firstAppliedToday) {
+				firstAppliedToday = applied
+			}
+		}
+	}
+
+	if cooldown > 0 && !lastApplied.IsZero() && now.Sub(lastApplied) < cooldown {
+		return fmt.Sprintf("%s was mutated %s ago, cooldown is %s", describeRef(target),
+			now.Sub(lastApplied).Round(time.Second), cooldown), cooldown - now.Sub(lastApplied), nil
+	}
+	if limits.MaxDailyPerTarget > 0 && appliedToday >= limits.MaxDailyPerTarget {
+		return fmt.Sprintf("%s was mutated %d times in the last 24h, the limit is %d", describeRef(target),
+			appliedToday, limits.MaxDailyPerTarget), dailyCapWindow - now.Sub(firstAppliedToday), nil
+	}
+	if limits.MaxInFlight > 0 && inFlight >= limits.MaxInFlight {
+		return fmt.Sprintf("%d mutations in flight in the cluster, the limit is %d", inFlight, limits.MaxInFlight),
+			util.PendingRequeueTime, nil
+	}
+	if limits.MaxInFlightPerNamespace > 0 && inFlightInNamespace >= limits.MaxInFlightPerNamespace {
+		return fmt.Sprintf("%d mutations in flight in namespace %s, the limit is %d", inFlightInNamespace,
+			target.Namespace, limits.MaxInFlightPerNamespace), util.PendingRequeueTime, nil
+	}
+	return "", 0, nil
+}
+
+// mutationWorkload returns the workload the mutation targets, mutations created before the
+// workload was recorded target their resource
+func mutationWorkload(mutation *corev1alpha1.Mutation) corev1.ObjectReference {
+	if mutation.Spec.Workload.Name != "" {
+		return mutation.Spec.Workload
+	}
+	return mutation.Spec.ResourceRef
+}
+
+func describeRef(ref corev1.ObjectReference) string {
+	return fmt.Sprintf("%s %s/%s", ref.Kind, ref.Namespace, ref.Name)
+}
+
+// inMaintenanceWindow reports whether now is within one of the windows and, when it is not,
+// when the next window opens. Without windows mutations may be applied at any time.
+func inMaintenanceWindow(windows []corev1alpha1.MaintenanceWindow, now time.Time) (bool, time.Time, error) {
+	if len(windows) == 0 {
+		return true, now, nil
+	}
+	var opensAt time.Time
+	for _, window := range windows {
+		schedule, duration, err := parseWindow(window)
+		if err != nil {
+			return false, time.Time{}, err
+		}
+		// The window is open when it opened within the last duration
+		if opened := schedule.Next(now.Add(-duration)); !opened.IsZero() && !opened.After(now) {
+			return true, now, nil
+		}
+		next := schedule.Next(now)
+		if next.IsZero() {
+			return false, time.Time{}, fmt.Errorf("schedule %q never opens", window.Schedule)
+		}
+		if opensAt.IsZero() || next.Before(opensAt) {
+			opensAt = next
+		}
+	}
+	return false, opensAt, nil
+}
+
+func parseWindow(window corev1alpha1.MaintenanceWindow) (cron.Schedule, time.Duration, error) {
+	spec := window.Schedule
+	if window.TimeZone != "" {
+		if _, err := time.LoadLocation(window.TimeZone); err != nil {
+			return nil, 0, err
+		}
+		spec = "CRON_TZ=" + window.TimeZone + " " + spec
+	}
+	schedule, err := cron.ParseStandard(spec)
+	if err != nil {
+		return nil, 0, fmt.Errorf("schedule %q: %w", window.Schedule, err)
+	}
+	duration, err := time.ParseDuration(window.Duration)
+	if err != nil || duration <= 0 {
+		return nil, 0, fmt.Errorf("duration %q is not a positive duration", window.Duration)
+	}
+	return schedule, duration, nil
+}
@@ -0,0 +1,163 @@
+package mutation
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1alpha1 "github.com/k8sgpt-ai/k8sgpt-operator/api/v1alpha1"
+	"github.com/k8sgpt-ai/k8sgpt-operator/internal/controller/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func Test_InMaintenanceWindow(t *testing.T) {
+	weeknights := []corev1alpha1.MaintenanceWindow{{Schedule: "0 22 * * 1-5", Duration: "3h"}}
+	// Tuesday
+	day := time.Date(2024, time.March, 5, 0, 0, 0, 0, time.UTC)
+
+	open, _, err := inMaintenanceWindow(weeknights, day.Add(23*time.Hour))
+	require.NoError(t, err)
+	assert.True(t, open)
+	// The window opened on Monday at 22:00 and runs into Tuesday
+	open, _, err = inMaintenanceWindow(weeknights, day.Add(30*time.Minute))
+	require.NoError(t, err)
+	assert.True(t, open)
+
+	open, opensAt, err := inMaintenanceWindow(weeknights, day.Add(12*time.Hour))
+	require.NoError(t, err)
+	assert.False(t, open)
+	assert.Equal(t, day.Add(22*time.Hour), opensAt)
+
+	// Schedules are read in their time zone, 22:00 in Berlin is 21:00 UTC in winter
+	berlin := []corev1alpha1.MaintenanceWindow{{Schedule: "0 22 * * *", Duration: "30m", TimeZone: "Europe/Berlin"}}
+	open, _, err = inMaintenanceWindow(berlin, day.Add(21*time.Hour+10*time.Minute))
+	require.NoError(t, err)
+	assert.True(t, open)
+
+	open, _, err = inMaintenanceWindow(nil, day)
+	require.NoError(t, err)
+	assert.True(t, open)
+
+	for _, invalid := range []corev1alpha1.MaintenanceWindow{
+		{Schedule: "every night", Duration: "1h"},
+		{Schedule: "0 22 * * *", Duration: "0s"},
+		{Schedule: "0 22 * * *", Duration: "1h", TimeZone: "Mars/Olympus"},
+		{Schedule: "0 0 30 2 *", Duration: "1h"},
+	} {
+		_, _, err = inMaintenanceWindow([]corev1alpha1.MaintenanceWindow{invalid}, day)
+		assert.Error(t, err, invalid.Schedule)
+	}
+}
+
+func Test_Admit(t *testing.T) {
+	// Applied times are stored with second precision
+	now := time.Now().Truncate(time.Second)
+	target := func(namespace, name string) corev1alpha1.MutationSpec {
+		return corev1alpha1.MutationSpec{ResourceRef: corev1.ObjectReference{Kind: "Deployment", Namespace: namespace, Name: name}}
+	}
+	applied := func(name string, phase corev1alpha1.AutoRemediationPhase, spec corev1alpha1.MutationSpec,
+		ago time.Duration) *corev1alpha1.Mutation {
+		mutation := newMutation(name, phase, spec)
+		appliedAt := metav1.NewTime(now.Add(-ago))
+		mutation.Status.AppliedAt = &appliedAt
+		return mutation
+	}
+	held := func(name string, spec corev1alpha1.MutationSpec) *corev1alpha1.Mutation {
+		mutation := newMutation(name, corev1alpha1.AutoRemediationPending, spec)
+		mutation.Status.HeldBy = "outside maintenance windows"
+		return mutation
+	}
+	limited := func(limits corev1alpha1.BlastRadius) *corev1alpha1.K8sGPT {
+		return &corev1alpha1.K8sGPT{Spec: corev1alpha1.K8sGPTSpec{AI: &corev1alpha1.AISpec{
+			AutoRemediation: corev1alpha1.AutoRemediation{BlastRadius: &limits}}}}
+	}
+
+	cacheDeployment := corev1.ObjectReference{Kind: "Deployment", Namespace: "default", Name: "cache", UID: "cache-uid"}
+	cachePod := func(name string) corev1alpha1.MutationSpec {
+		return corev1alpha1.MutationSpec{ResourceRef: corev1.ObjectReference{Kind: "Pod", Namespace: "default", Name: name},
+			Workload: cacheDeployment}
+	}
+
+	f := newReconcileFixture(t, corev1alpha1.AutoRemediation{},
+		applied("web-1", corev1alpha1.AutoRemediationPhaseSuccessful, target("default", "web"), 20*time.Hour),
+		applied("web-2", corev1alpha1.AutoRemediationPhaseSuccessful, target("default", "web"), 2*time.Hour),
+		applied("web-3", corev1alpha1.AutoRemediationPhaseSuccessful, target("default", "web"), 10*time.Minute),
+		applied("cache-1", corev1alpha1.AutoRemediationPending, cachePod("cache-5d4f-x2"), 10*time.Minute),
+		applied("db-1", corev1alpha1.AutoRemediationPhaseCompleted, target("data", "db"), time.Minute),
+		held("api-1", target("default", "api")),
+	)
+	admit := func(spec corev1alpha1.MutationSpec, limits corev1alpha1.BlastRadius) (string, time.Duration) {
+		reason, retryAfter, err := f.reconciler.admit(context.Background(),
+			newMutation("candidate", corev1alpha1.AutoRemediationPhaseNotStarted, spec), limited(limits), now)
+		require.NoError(t, err)
+		return reason, retryAfter
+	}
+
+	reason, _ := admit(target("default", "web"), corev1alpha1.BlastRadius{})
+	assert.Empty(t, reason)
+
+	// The workload of an in-flight mutation is locked, whichever of its pods another mutation is for
+	reason, retryAfter := admit(cachePod("cache-5d4f-q7"), corev1alpha1.BlastRadius{})
+	assert.Equal(t, "Deployment default/cache is locked by mutation k8sgpt/cache-1", reason)
+	assert.Equal(t, util.PendingRequeueTime, retryAfter)
+
+	reason, retryAfter = admit(target("default", "web"), corev1alpha1.BlastRadius{Cooldown: "30m"})
+	assert.Contains(t, reason, "cooldown is 30m0s")
+	assert.Equal(t, 20*time.Minute, retryAfter)
+	reason, _ = admit(target("default", "api"), corev1alpha1.BlastRadius{Cooldown: "30m"})
+	assert.Empty(t, reason)
+
+	reason, retryAfter = admit(target("default", "web"), corev1alpha1.BlastRadius{MaxDailyPerTarget: 3})
+	assert.Equal(t, "Deployment default/web was mutated 3 times in the last 24h, the limit is 3", reason)
+	assert.Equal(t, 4*time.Hour, retryAfter)
+
+	// cache-1 and db-1 are in flight, api-1 is held before it is applied
+	reason, _ = admit(target("default", "api"), corev1alpha1.BlastRadius{MaxInFlight: 2})
+	assert.Equal(t, "2 mutations in flight in the cluster, the limit is 2", reason)
+	reason, _ = admit(target("default", "api"), corev1alpha1.BlastRadius{MaxInFlightPerNamespace: 1})
+	assert.Equal(t, "1 mutations in flight in namespace default, the limit is 1", reason)
+	reason, _ = admit(target("staging", "api"), corev1alpha1.BlastRadius{MaxInFlightPerNamespace: 1})
+	assert.Empty(t, reason)
+
+	reason, _ = admit(target("default", "api"), corev1alpha1.BlastRadius{MaintenanceWindows: []corev1alpha1.MaintenanceWindow{
+		{Schedule: "0 0 1 1 *", Duration: "1m"}}})
+	assert.Contains(t, reason, "outside maintenance windows")
+
+	// Mutations are counted from the API server, the cache may not have seen the last ones admitted
+	admitted := newMutation("admitted", corev1alpha1.AutoRemediationPhaseInProgress, target("staging", "worker"))
+	f.reconciler.APIReader = newReconcileFixture(t, corev1alpha1.AutoRemediation{}, admitted).client
+	reason, _ = admit(target("staging", "api"), corev1alpha1.BlastRadius{MaxInFlightPerNamespace: 1})
+	assert.Equal(t, "1 mutations in flight in namespace staging, the limit is 1", reason)
+}
+
+func Test_ReconcileQueued(t *testing.T) {
+	inFlight := newMutation("in-flight", corev1alpha1.AutoRemediationPhaseInProgress, deploymentSpec)
+	inFlight.Spec.ResourceRef.Name = "api"
+	f := newReconcileFixture(t, corev1alpha1.AutoRemediation{BlastRadius: &corev1alpha1.BlastRadius{MaxInFlight: 1}},
+		newMutation("planned", corev1alpha1.AutoRemediationPhaseNotStarted, deploymentSpec),
+		inFlight,
+	)
+
+	_, mutation := f.reconcile("planned")
+	assert.Equal(t, corev1alpha1.AutoRemediationPending, mutation.Status.Phase)
+	assert.Equal(t, "1 mutations in flight in the cluster, the limit is 1", mutation.Status.HeldBy)
+	assert.Equal(t, "Queued: 1 mutations in flight in the cluster, the limit is 1", mutation.Status.Message)
+
+	// Requeues for the same reason do not add to the history
+	_, mutation = f.reconcile("planned")
+	assert.Equal(t, corev1alpha1.AutoRemediationPending, mutation.Status.Phase)
+	assert.Len(t, mutation.Status.History, 1)
+
+	var done corev1alpha1.Mutation
+	require.NoError(t, f.client.Get(context.Background(), client.ObjectKey{Namespace: "k8sgpt", Name: "in-flight"}, &done))
+	done.Status.Phase = corev1alpha1.AutoRemediationPhaseSuccessful
+	require.NoError(t, f.client.Status().Update(context.Background(), &done))
+
+	_, mutation = f.reconcile("planned")
+	assert.Equal(t, corev1alpha1.AutoRemediationPhaseInProgress, mutation.Status.Phase)
+	assert.Empty(t, mutation.Status.HeldBy)
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2023 K8sGPT Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mutation
+
+import (
+	"context"
+	"fmt"
+
+	corev1alpha1 "github.com/k8sgpt-ai/k8sgpt-operator/api/v1alpha1"
+	"github.com/k8sgpt-ai/k8sgpt-operator/internal/controller/conversions"
+	"github.com/k8sgpt-ai/k8sgpt-operator/internal/controller/util"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// MaxMutationHistory bounds the history kept in the status of a mutation
+const MaxMutationHistory = 20
+
+const (
+	applyResultApplied    = "Applied"
+	applyResultNotApplied = "NotApplied"
+	applyResultFailed     = "Failed"
+	applyResultRolledBack = "RolledBack"
+
+	eventReasonRetry                 = "Retry"
+	eventReasonRemediated            = "Remediated"
+	eventReasonRemediationFailed     = "RemediationFailed"
+	eventReasonRemediationRolledBack = "RemediationRolledBack"
+)
+
+// appendHistory records the current phase and message of the mutation, together with the
+// backend query and score that led to it, dropping the oldest entries beyond MaxMutationHistory
+func appendHistory(mutation *corev1alpha1.Mutation, from corev1alpha1.AutoRemediationPhase, applyResult string) {
+	mutation.Status.History = append(mutation.Status.History, corev1alpha1.MutationHistoryEntry{
+		PreviousPhase:   from,
+		Phase:           mutation.Status.Phase,
+		Time:            metav1.Now(),
+		Message:         mutation.Status.Message,
+		TemplateVersion: mutation.Status.TemplateVersion,
+		PromptHash:      mutation.Status.PromptHash,
+		ResponseHash:    mutation.Status.ResponseHash,
+		SimilarityScore: mutation.Spec.SimilarityScore,
+		ApplyResult:     applyResult,
+	})
+	if overflow := len(mutation.Status.History) - MaxMutationHistory; overflow > 0 {
+		mutation.Status.History = mutation.Status.History[overflow:]
+	}
+}
+
+// transitionApplyResult describes what a transition means for the target object
+func transitionApplyResult(from, to corev1alpha1.AutoRemediationPhase) string {
+	switch {
+	case to == corev1alpha1.AutoRemediationRolledBack:
+		return applyResultRolledBack
+	case from != corev1alpha1.AutoRemediationPhaseInProgress:
+		return ""
+	case to == corev1alpha1.AutoRemediationPhaseCompleted:
+		return applyResultApplied
+	case to == corev1alpha1.AutoRemediationAborted:
+		return applyResultNotApplied
+	case to == corev1alpha1.AutoRemediationFailed:
+		return applyResultFailed
+	}
+	return ""
+}
+
+// emitEvents publishes the latest history entry as an Event on the mutation, and on the target
+// object when the entry records a write to it. Retries are published with the Retry reason.
+func (r *MutationReconciler) emitEvents(ctx context.Context, mutation *corev1alpha1.Mutation, retry bool) {
+	if r.Recorder == nil || len(mutation.Status.History) == 0 {
+		return
+	}
+	entry := mutation.Status.History[len(mutation.Status.History)-1]
+
+	eventType, reason := corev1.EventTypeNormal, entry.Phase.String()
+	switch {
+	case retry:
+		eventType, reason = corev1.EventTypeWarning, eventReasonRetry
+	case entry.Phase == corev1alpha1.AutoRemediationFailed, entry.Phase == corev1alpha1.AutoRemediationAborted,
+		entry.Phase == corev1alpha1.AutoRemediationRolledBack:
+		eventType = corev1.EventTypeWarning
+	}
+	r.Recorder.Event(mutation, eventType, reason, entry.Message)
+
+	var targetType, targetReason, targetMessage string
+	switch entry.ApplyResult {
+	case applyResultApplied:
+		targetType, targetReason = corev1.EventTypeNormal, eventReasonRemediated
+		targetMessage = fmt.Sprintf("Mutation %s/%s applied a change with similarity score %s",
+			mutation.Namespace, mutation.Name, mutation.Spec.SimilarityScore)
+	case applyResultFailed:
+		targetType, targetReason = corev1.EventTypeWarning, eventReasonRemediationFailed
+		targetMessage = fmt.Sprintf("Mutation %s/%s could not be applied: %s", mutation.Namespace, mutation.Name, entry.Message)
+	case applyResultRolledBack:
+		targetType, targetReason = corev1.EventTypeWarning, eventReasonRemediationRolledBack
+		targetMessage = fmt.Sprintf("Mutation %s/%s was rolled back: %s", mutation.Namespace, mutation.Name,
+			mutation.Status.RollbackReason)
+	default:
+		return
+	}
+	target, err := r.liveTarget(ctx, mutation)
+	if err != nil {
+		mutationControllerLog.Error(err, "unable to get mutation target for event", "mutation", mutation.Name)
+		return
+	}
+	r.Recorder.Event(target, targetType, targetReason, targetMessage)
+}
+
+// liveTarget returns the object the mutation writes, which is the owning deployment for owned pods
+func (r *MutationReconciler) liveTarget(ctx context.Context, mutation *corev1alpha1.Mutation) (*unstructured.Unstructured, error) {
+	var target *unstructured.Unstructured
+	if mutation.Status.PreviousConfiguration != "" {
+		previous, err := conversions.FromManifest(mutation.Status.PreviousConfiguration)
+		if err != nil {
+			return nil, err
+		}
+		target = previous
+	} else {
+		obj, err := util.FromConfig(util.FromObjectConfig{
+			Kind:      mutation.Spec.ResourceRef.Kind,
+			GvkStr:    mutation.Spec.ResourceGVK,
+			Config:    "{}",
+			Name:      mutation.Spec.ResourceRef.Name,
+			Namespace: mutation.Spec.ResourceRef.Namespace,
+		})
+		if err != nil {
+			return nil, err
+		}
+		target = obj.(*unstructured.Unstructured)
+	}
+	live := &unstructured.Unstructured{}
+	live.SetGroupVersionKind(target.GroupVersionKind())
+	if err := r.Get(ctx, client.ObjectKeyFromObject(target), live); err != nil {
+		return nil, err
+	}
+	return live, nil
+}
@@ -0,0 +1,83 @@
+package mutation
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	corev1alpha1 "github.com/k8sgpt-ai/k8sgpt-operator/api/v1alpha1"
+	"github.com/k8sgpt-ai/k8sgpt-operator/internal/controller/conversions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_TransitionAppendsHistory(t *testing.T) {
+	mutation := &corev1alpha1.Mutation{
+		Spec:   corev1alpha1.MutationSpec{SimilarityScore: "95.000000"},
+		Status: corev1alpha1.MutationStatus{Phase: corev1alpha1.AutoRemediationPhaseInProgress},
+	}
+	conversions.RecordQuery(mutation, "v1", "prompt", "response")
+
+	require.NoError(t, transition(mutation, corev1alpha1.AutoRemediationPhaseCompleted, "Completed"))
+	require.Len(t, mutation.Status.History, 1)
+	entry := mutation.Status.History[0]
+	assert.Equal(t, corev1alpha1.AutoRemediationPhaseInProgress, entry.PreviousPhase)
+	assert.Equal(t, corev1alpha1.AutoRemediationPhaseCompleted, entry.Phase)
+	assert.Equal(t, "Completed", entry.Message)
+	assert.Equal(t, "v1", entry.TemplateVersion)
+	assert.Equal(t, "95.000000", entry.SimilarityScore)
+	assert.Equal(t, applyResultApplied, entry.ApplyResult)
+	assert.Len(t, entry.PromptHash, 64)
+	assert.NotEqual(t, entry.PromptHash, entry.ResponseHash)
+	assert.False(t, entry.Time.IsZero())
+
+	// Refused transitions leave no trace
+	assert.Error(t, transition(mutation, corev1alpha1.AutoRemediationPhaseNotStarted, "Restarted"))
+	assert.Len(t, mutation.Status.History, 1)
+}
+
+func Test_RecordFailureAppendsHistory(t *testing.T) {
+	mutation := &corev1alpha1.Mutation{Status: corev1alpha1.MutationStatus{Phase: corev1alpha1.AutoRemediationPhaseInProgress}}
+	_, err := recordFailure(mutation, 1, corev1alpha1.MutationFailureApplyFailed, errors.New("conflict"))
+	require.NoError(t, err)
+	_, err = recordFailure(mutation, 1, corev1alpha1.MutationFailureApplyFailed, errors.New("conflict"))
+	require.NoError(t, err)
+
+	require.Len(t, mutation.Status.History, 2)
+	assert.Equal(t, corev1alpha1.AutoRemediationPhaseInProgress, mutation.Status.History[0].Phase)
+	assert.Equal(t, applyResultFailed, mutation.Status.History[0].ApplyResult)
+	assert.Equal(t, corev1alpha1.AutoRemediationFailed, mutation.Status.History[1].Phase)
+	assert.Equal(t, applyResultFailed, mutation.Status.History[1].ApplyResult)
+}
+
+func Test_HistoryIsBounded(t *testing.T) {
+	mutation := &corev1alpha1.Mutation{Status: corev1alpha1.MutationStatus{Phase: corev1alpha1.AutoRemediationAwaitingApproval}}
+	for i := 0; i < MaxMutationHistory+5; i++ {
+		require.NoError(t, transition(mutation, corev1alpha1.AutoRemediationAwaitingApproval, fmt.Sprintf("message %d", i)))
+	}
+	require.Len(t, mutation.Status.History, MaxMutationHistory)
+	assert.Equal(t, "message 5", mutation.Status.History[0].Message)
+	assert.Equal(t, fmt.Sprintf("message %d", MaxMutationHistory+4), mutation.Status.History[MaxMutationHistory-1].Message)
+}
+
+func Test_ReconcileEmitsEvents(t *testing.T) {
+	planned := newMutation("apply", corev1alpha1.AutoRemediationPhaseInProgress, deploymentSpec)
+	planned.Status.PlannedConfiguration = plannedDeployment(t, "nginx:1.1")
+	f := newReconcileFixture(t, corev1alpha1.AutoRemediation{RetryBudget: 3},
+		webDeployment("nginx:1.0"), planned,
+		newMutation("query", corev1alpha1.AutoRemediationPhaseNotStarted, corev1alpha1.MutationSpec{}),
+		&corev1alpha1.Result{ObjectMeta: metav1.ObjectMeta{Namespace: "k8sgpt", Name: "query"}})
+
+	_, mutation := f.reconcile("apply")
+	require.Len(t, mutation.Status.History, 1)
+	assert.Equal(t, "Normal Completed Completed", <-f.recorder.Events)
+	assert.Equal(t, "Normal Remediated Mutation k8sgpt/apply applied a change with similarity score 95.000000",
+		<-f.recorder.Events)
+
+	// The backend is unreachable, so the query is retried
+	_, mutation = f.reconcile("query")
+	require.Len(t, mutation.Status.History, 1)
+	assert.Contains(t, <-f.recorder.Events, "Warning Retry Retry 1 of 3 after QueryFailed")
+	assert.Empty(t, f.recorder.Events)
+}
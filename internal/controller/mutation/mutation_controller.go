@@ -18,6 +18,7 @@ package mutation
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
@@ -31,7 +32,12 @@ import (
 	"github.com/k8sgpt-ai/k8sgpt-operator/internal/controller/util"
 	"github.com/k8sgpt-ai/k8sgpt-operator/internal/prompts"
 	metricspkg "github.com/k8sgpt-ai/k8sgpt-operator/pkg/metrics"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/openapi"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/metrics"
@@ -40,12 +46,18 @@ import (
 // MutationReconciler reconciles a Mutation object
 type MutationReconciler struct {
 	client.Client
-	logger            logr.Logger
-	Scheme            *runtime.Scheme
-	ServerQueryClient *rpc.ServerQueryServiceClient
-	MetricsBuilder    *metricspkg.MetricBuilder
-	RemoteBackend     string
-	K8sGPT            *corev1alpha1.K8sGPT
+	logger         logr.Logger
+	Scheme         *runtime.Scheme
+	MetricsBuilder *metricspkg.MetricBuilder
+	// ClientRegistry resolves the K8sGPT server connection of the instance owning a mutation
+	ClientRegistry *shared.ClientRegistry
+	// Recorder publishes the history of mutations as Events, it may be nil
+	Recorder record.EventRecorder
+	// Schemas serves the OpenAPI schemas targets are validated against, validation is skipped when nil
+	Schemas openapi.Client
+	// APIReader reads the mutations counted against the blast radius limits without the cache, the
+	// client is used when it is nil
+	APIReader client.Reader
 }
 
 var (
@@ -55,6 +67,10 @@ var (
 // +kubebuilder:rbac:groups=core.k8sgpt.ai,resources=mutations,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core.k8sgpt.ai,resources=mutations/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=core.k8sgpt.ai,resources=mutations/finalizers,verbs=update
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch;list
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch
+// +kubebuilder:rbac:groups=authorization.k8s.io,resources=subjectaccessreviews,verbs=create
+// +kubebuilder:rbac:groups=admissionregistration.k8s.io,resources=validatingadmissionpolicies;validatingadmissionpolicybindings,verbs=get;list;watch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -68,6 +84,11 @@ var (
 // +kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=apps,resources=replicasets,verbs=get;list;watch
+// +kubebuilder:rbac:groups=apps,resources=statefulsets;daemonsets,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups="",resources=services,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;delete
+// +kubebuilder:rbac:groups=batch,resources=cronjobs,verbs=get;list;watch;update;patch
 // +kubebuilder:rbac:groups="*",resources="*",verbs="*"
 // +kubebuilder:rbac:groups="apiextensions.k8s.io",resources="*",verbs="*"
 func (r *MutationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
@@ -80,16 +101,7 @@ func (r *MutationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 	}
 	mutationControllerLog.Info("Reconciling mutation", "mutation", mutation.Name)
 
-	if r.ServerQueryClient == nil {
-		r.ServerQueryClient = shared.GetServerQueryClient()
-	}
-	if r.ServerQueryClient == nil {
-		mutationControllerLog.Info("K8sGPT client not ready, requeuing")
-		return ctrl.Result{RequeueAfter: util.ErrorRequeueTime}, nil
-	}
 	// check if the object is being deleted
-
-	// check if object has a finalizer
 	if mutation.ObjectMeta.Finalizers != nil && !mutation.ObjectMeta.DeletionTimestamp.IsZero() {
 		finalizer := mutation.ObjectMeta.GetFinalizers()
 		if util.IsStringInSlice("mutation.finalizer.k8sgpt.ai", finalizer) {
@@ -102,57 +114,120 @@ func (r *MutationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 			mutationControllerLog.Error(err, "unable to update mutation")
 			return ctrl.Result{RequeueAfter: util.ErrorRequeueTime}, err
 		}
+		return ctrl.Result{}, nil
+	}
+
+	// Successful, Aborted, Failed and RolledBack mutations are finished
+	if IsTerminal(mutation.Status.Phase) {
+		mutationControllerLog.Info("Mutation is finished", "mutation", mutation.Name,
+			"phase", mutation.Status.Phase, "message", mutation.Status.Message)
+		return ctrl.Result{}, nil
 	}
 
+	// Resolve the K8sGPT instance that owns this mutation and its server connection
+	owner, err := r.owningK8sGPT(ctx, mutation)
+	if err != nil {
+		mutationControllerLog.Error(err, "unable to resolve owning K8sGPT", "mutation", mutation.Name)
+		return ctrl.Result{RequeueAfter: util.ErrorRequeueTime}, nil
+	}
+	// The connection stays open while it is used, even if the instance replaces it meanwhile
+	signal, release, ok := r.ClientRegistry.Acquire(owner)
+	defer release()
+	if !ok || signal.K8sGPTClient == nil {
+		mutationControllerLog.Info("K8sGPT client not ready, requeuing", "k8sgpt", owner.String())
+		return ctrl.Result{RequeueAfter: util.ErrorRequeueTime}, nil
+	}
+	if signal.K8sGPT.Spec.AI == nil || !signal.K8sGPT.Spec.AI.AutoRemediation.Enabled {
+		mutationControllerLog.Info("Auto remediation disabled on owning K8sGPT, skipping", "k8sgpt", owner.String())
+		return ctrl.Result{RequeueAfter: util.SuccessfulRequeueTime}, nil
+	}
+	queryClient := rpc.NewServerQueryServiceClient(signal.K8sGPTClient.Conn)
+
+	budget := retryBudget(signal.K8sGPT)
+
 	switch mutation.Status.Phase {
 	case corev1alpha1.AutoRemediationPhaseNotStarted:
 		// This phase means that there is an origin configuration, resource and result
 		// It needs an additional API call to determine targetConfiguration (mutation)
 		// The goal now is to set the target Configuration and move phases to InProgress
-		// Get the actual result from the reference
-		var result corev1alpha1.Result
-		err := r.Client.Get(ctx, client.ObjectKey{Name: mutation.Spec.ResultRef.Name,
-			Namespace: mutation.Spec.ResultRef.Namespace}, &result)
-		if err != nil {
-			mutationControllerLog.Error(err, "Unable to retrieve result from reference",
-				"Name", mutation.Spec.ResultRef.Name)
-			return ctrl.Result{Requeue: false}, err
-		}
-
-		queryResponse, err := (*r.ServerQueryClient).Query(context.Background(), &schemav1.QueryRequest{
-			Backend: r.RemoteBackend,
-			Query: fmt.Sprintf(prompts.Mutation_prompt, result.Spec.Details,
-				mutation.Spec.OriginConfiguration),
-		})
-		if err != nil {
-			mutationControllerLog.Error(err, "unable to query K8sGPT")
-			return ctrl.Result{RequeueAfter: util.ErrorRequeueTime}, nil
-		}
-		if queryResponse.GetResponse() == "{null}" {
-			mutationControllerLog.Info("Unable to progress with this mutation, unknown solution", "name", mutation.Name)
-			mutation.Status.Message = "No known fix"
+		if mutation.Spec.TargetConfiguration == "" {
+			// Get the actual result from the reference
+			var result corev1alpha1.Result
+			err := r.Client.Get(ctx, client.ObjectKey{Name: mutation.Spec.ResultRef.Name,
+				Namespace: mutation.Spec.ResultRef.Namespace}, &result)
+			if err != nil {
+				mutationControllerLog.Error(err, "Unable to retrieve result from reference",
+					"Name", mutation.Spec.ResultRef.Name)
+				return ctrl.Result{Requeue: false}, err
+			}
 
-			err := r.Client.Status().Update(ctx, &mutation)
+			templates := r.promptTemplates(signal.K8sGPT)
+			prompt, templateVersion, err := templates.Render(ctx, prompts.StepMutation, result.Spec.Kind,
+				r.promptData(ctx, &mutation, &result, templates))
+			if err != nil {
+				mutationControllerLog.Error(err, "unable to render prompt", "mutation", mutation.Name)
+				return r.retryOrFail(ctx, &mutation, budget, corev1alpha1.MutationFailureQueryFailed, err)
+			}
+			queryResponse, err := queryClient.Query(context.Background(), &schemav1.QueryRequest{
+				Backend: signal.Backend,
+				Query:   prompt,
+			})
+			if err != nil {
+				mutationControllerLog.Error(err, "unable to query K8sGPT")
+				return r.retryOrFail(ctx, &mutation, budget, corev1alpha1.MutationFailureQueryFailed, err)
+			}
+			conversions.RecordQuery(&mutation, templateVersion, prompt, queryResponse.GetResponse())
+			if queryResponse.GetResponse() == "{null}" {
+				mutationControllerLog.Info("Unable to progress with this mutation, unknown solution", "name", mutation.Name)
+				return r.retryOrFail(ctx, &mutation, budget, corev1alpha1.MutationFailureNoKnownFix,
+					errors.New("the backend returned no known fix"))
+			}
+			// compare the origin and target objects field by field
+			patch, risk, err := util.StructuralDiff(mutation.Spec.OriginConfiguration, queryResponse.GetResponse())
 			if err != nil {
-				mutationControllerLog.Error(err, "unable to update mutation status")
+				mutationControllerLog.Error(err, "unable to compare target with origin configuration, assuming maximum risk", "mutation", mutation.Name)
+			}
+			score := util.SimilarityFromRisk(risk)
+			mutationControllerLog.Info("Similarity score", "score", score, "risk", risk)
+			mutationControllerLog.Info("Got mutation targetConfiguration for", "mutation", mutation.Name)
+			mutation.Spec.TargetConfiguration = queryResponse.GetResponse()
+			mutation.Spec.Patch = patch
+			mutation.Spec.RiskScore = risk
+			mutation.Spec.SimilarityScore = fmt.Sprintf("%f", score)
+			// The spec update returns the stored status, keep the retries counted so far
+			status := mutation.Status.DeepCopy()
+			if err := r.Client.Update(ctx, &mutation); err != nil {
+				mutationControllerLog.Error(err, "unable to update mutation")
 				return ctrl.Result{RequeueAfter: util.ErrorRequeueTime}, err
 			}
-			return ctrl.Result{RequeueAfter: util.ErrorRequeueTime * 10}, nil
+			mutation.Status = *status
 		}
-		// compute similarity score
-		score := util.SimilarityScore(mutation.Spec.OriginConfiguration, queryResponse.GetResponse())
-		mutationControllerLog.Info("Similarity score", "score", score)
-		mutationControllerLog.Info("Got mutation targetConfiguration for", "mutation", mutation.Name)
-		mutation.Spec.TargetConfiguration = queryResponse.GetResponse()
-		mutation.Spec.SimilarityScore = fmt.Sprintf("%f", score)
-		mutation.Status.Phase = corev1alpha1.AutoRemediationPhaseInProgress
-		mutation.Status.Message = "In Progress"
-		if err := r.Client.Update(ctx, &mutation); err != nil {
-			mutationControllerLog.Error(err, "unable to update mutation")
-			return ctrl.Result{RequeueAfter: util.ErrorRequeueTime}, err
+		if requiresApproval(signal.K8sGPT) {
+			// The planned change and its dry-run diff are computed in the next phase
+			return r.moveTo(ctx, &mutation, corev1alpha1.AutoRemediationAwaitingApproval, "Planning",
+				ctrl.Result{RequeueAfter: util.NotStartedRequeueTime})
+		}
+		return r.startOrQueue(ctx, &mutation, signal.K8sGPT, "In Progress")
+	case corev1alpha1.AutoRemediationAwaitingApproval:
+		// Mutations that would not meet the similarity requirement once approved are not offered
+		if !similarityRequirementMet(signal.K8sGPT, mutation) {
+			mutationControllerLog.Info("Similarity score is less than risk threshold, not planning mutation", "mutation", mutation.Name)
+			return r.moveTo(ctx, &mutation, corev1alpha1.AutoRemediationAborted, "Risk threshold not met", ctrl.Result{})
+		}
+		// The mutation is held until a reviewer approves or rejects the planned configuration
+		if mutation.Status.PlannedConfiguration != "" {
+			return r.reviewMutation(ctx, &mutation, signal.K8sGPT)
 		}
-		mutationControllerLog.Info("Updated mutation status to InProgress", "mutation", mutation.Name)
-		return ctrl.Result{RequeueAfter: util.NotStartedRequeueTime}, err
+		config, err := r.executionConfig(ctx, &mutation, signal.K8sGPT, signal.Backend, queryClient)
+		var refused *conversions.GuardrailError
+		if errors.As(err, &refused) {
+			return r.refuse(ctx, &mutation, refused)
+		}
+		if err != nil {
+			mutationControllerLog.Error(err, "unable to convert targetConfiguration to object", "mutation", mutation.Name)
+			return r.failNow(ctx, &mutation, corev1alpha1.MutationFailureInvalidTarget, err)
+		}
+		return r.planMutation(ctx, &mutation, config, budget)
 	case corev1alpha1.AutoRemediationPhaseInProgress:
 		// This means that the executor has applied the configuration, and we are
 		// in a period of waiting for result to expire, therefore showing success
@@ -163,75 +238,99 @@ func (r *MutationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 			mutationControllerLog.Info("Target configuration is not set, this shouldn't occur at this phase", "mutation", mutation.Name)
 			return ctrl.Result{RequeueAfter: util.ErrorRequeueTime}, nil
 		}
-		if r.K8sGPT != nil {
-			if r.K8sGPT.Spec.AI.AutoRemediation.SimilarityRequirement != "" {
-				// If the current Similarity score is less than the riskThreshold, we should not apply the mutation
-				ss, err := strconv.ParseFloat(strings.TrimSpace(mutation.Spec.SimilarityScore), 64)
-				if err != nil {
-					mutationControllerLog.Error(err, "unable to parse similarity score", "mutation", mutation.Name)
-				} else {
-					rt, err := strconv.ParseFloat(r.K8sGPT.Spec.AI.AutoRemediation.SimilarityRequirement, 64)
-					if err != nil {
-						mutationControllerLog.Error(err, "unable to parse risk threshold", "mutation", mutation.Name)
-					} else {
-						if ss < rt {
-							mutationControllerLog.Info("Similarity score is less than risk threshold, not applying mutation", "mutation", mutation.Name)
-							mutation.Status.Phase = corev1alpha1.AutoRemediationAborted
-							mutation.Status.Message = "Risk threshold not met"
-							if err := r.Client.Update(ctx, &mutation); err != nil {
-								mutationControllerLog.Error(err, "unable to update mutation status")
-								return ctrl.Result{Requeue: false}, err
-							}
-							// On success, drop down through to the next phase
-						}
-					}
-				}
-			}
+		if !similarityRequirementMet(signal.K8sGPT, mutation) {
+			mutationControllerLog.Info("Similarity score is less than risk threshold, not applying mutation", "mutation", mutation.Name)
+			return r.moveTo(ctx, &mutation, corev1alpha1.AutoRemediationAborted, "Risk threshold not met", ctrl.Result{})
+		}
+		config, err := r.executionConfig(ctx, &mutation, signal.K8sGPT, signal.Backend, queryClient)
+		var refused *conversions.GuardrailError
+		if errors.As(err, &refused) {
+			return r.refuse(ctx, &mutation, refused)
 		}
-		// Convert the spec.targetConfiguration to an Object
-		// 1. Get the GVK from the Kind string
-		obj, err := util.FromConfig(util.FromObjectConfig{
-			Kind:      mutation.Spec.ResourceRef.Kind,
-			GvkStr:    mutation.Spec.ResourceGVK,
-			Config:    mutation.Spec.TargetConfiguration,
-			Name:      mutation.Spec.ResourceRef.Name,
-			Namespace: mutation.Spec.ResourceRef.Namespace,
-		})
 		if err != nil {
 			mutationControllerLog.Error(err, "unable to convert targetConfiguration to object", "mutation", mutation.Name)
-			return ctrl.Result{RequeueAfter: util.ErrorRequeueTime}, err
+			return r.failNow(ctx, &mutation, corev1alpha1.MutationFailureInvalidTarget, err)
+		}
+		var target client.Object
+		if mutation.Status.PlannedConfiguration != "" {
+			// Write exactly what the reviewer approved
+			target, err = conversions.FromManifest(mutation.Status.PlannedConfiguration)
+			if err != nil {
+				mutationControllerLog.Error(err, "unable to parse planned configuration", "mutation", mutation.Name)
+				return r.failNow(ctx, &mutation, corev1alpha1.MutationFailureInvalidTarget, err)
+			}
+		} else {
+			target, err = conversions.ResolveTarget(config)
+			if errors.Is(err, conversions.ErrUnsupportedKind) {
+				return r.failNow(ctx, &mutation, corev1alpha1.MutationFailureUnsupportedKind, err)
+			}
+			if errors.As(err, &refused) {
+				return r.refuse(ctx, &mutation, refused)
+			}
+			if err != nil {
+				return r.retryOrFail(ctx, &mutation, budget, corev1alpha1.MutationFailureResolveFailed, err)
+			}
 		}
-		//This horrible code also needs to go here in case we reconcile before the signal is received
-		if r.ServerQueryClient == nil {
-			r.ServerQueryClient = shared.GetServerQueryClient()
+		// The object written, e.g. the workload of a pod, must be in scope itself
+		if err := conversions.CheckScope(config, target); err != nil {
+			return r.outOfScope(ctx, &mutation, budget, err)
 		}
-		if r.ServerQueryClient == nil {
-			mutationControllerLog.Info("K8sGPT client not ready, requeuing")
-			return ctrl.Result{RequeueAfter: util.ErrorRequeueTime}, nil
+		// Nothing the backend produced is written before it passes the guardrails
+		if err := conversions.Guardrails(config, target, r.Schemas); err != nil {
+			if errors.As(err, &refused) {
+				return r.refuse(ctx, &mutation, refused)
+			}
+			mutationControllerLog.Error(err, "unable to check mutation guardrails", "mutation", mutation.Name)
+			return r.retryOrFail(ctx, &mutation, budget, corev1alpha1.MutationFailureApplyFailed, err)
+		}
+		violations, err := conversions.CheckPolicies(config, target)
+		if err != nil {
+			mutationControllerLog.Error(err, "unable to evaluate remediation policies", "mutation", mutation.Name)
+			return r.retryOrFail(ctx, &mutation, budget, corev1alpha1.MutationFailureApplyFailed, err)
+		}
+		if len(violations) > 0 {
+			return r.block(ctx, &mutation, violations)
+		}
+		done, err := conversions.ExecuteTarget(config, target)
+		if errors.Is(err, conversions.ErrUnsupportedKind) {
+			return r.failNow(ctx, &mutation, corev1alpha1.MutationFailureUnsupportedKind, err)
 		}
-		return conversions.ResourceToExecution(conversions.ObjectExecutionConfig{
-			Ctx:         ctx,
-			Rc:          r.Client,
-			Log:         mutationControllerLog,
-			Obj:         obj,
-			Backend:     r.RemoteBackend,
-			Mutation:    mutation,
-			QueryClient: *r.ServerQueryClient,
-		})
+		if errors.Is(err, conversions.ErrImmutableField) || errors.Is(err, conversions.ErrRemovedField) {
+			return r.failNow(ctx, &mutation, corev1alpha1.MutationFailureInvalidTarget, err)
+		}
+		if errors.Is(err, conversions.ErrApplyConflict) {
+			return r.failNow(ctx, &mutation, corev1alpha1.MutationFailureConflict, err)
+		}
+		if err != nil {
+			return r.retryOrFail(ctx, &mutation, budget, corev1alpha1.MutationFailureApplyFailed, err)
+		}
+		if !done {
+			// The executor needs another pass, keep what it recorded so far
+			return r.updateStatus(ctx, &mutation, ctrl.Result{RequeueAfter: util.InProgressRequeueTime})
+		}
+		return r.moveTo(ctx, &mutation, corev1alpha1.AutoRemediationPhaseCompleted, "Completed",
+			ctrl.Result{RequeueAfter: util.SuccessfulRequeueTime})
 	case corev1alpha1.AutoRemediationPhaseCompleted:
 		// this    is when the execute/apply is completed
 		mutationControllerLog.Info("Mutation has been completed", "mutation", mutation.Name)
+		if handled, result, err := r.rollbackIfFailed(ctx, &mutation, signal.K8sGPT); handled {
+			return result, err
+		}
 		// find the original result
-		return r.doesResultExist(ctx, mutation)
-	case corev1alpha1.AutoRemediationPhaseSuccessful:
-		// This phase occurs when the result has expired and no longer exists
-		mutationControllerLog.Info("Mutation has been successful", "mutation", mutation.Name)
-		return ctrl.Result{RequeueAfter: util.SuccessfulRequeueTime}, nil
+		return r.doesResultExist(ctx, &mutation)
 	case corev1alpha1.AutoRemediationPending:
+		if isHeld(&mutation) {
+			// The mutation is ready to be applied and waits for the blast radius limits to let it through
+			return r.startOrQueue(ctx, &mutation, signal.K8sGPT, "In Progress")
+		}
 		// This phase will occur when a result does not expire after phase completed
 		mutationControllerLog.Info("Mutation is pending, result still exists", "mutation", mutation.Name)
-		return r.doesResultExist(ctx, mutation)
+		if handled, result, err := r.rollbackIfFailed(ctx, &mutation, signal.K8sGPT); handled {
+			return result, err
+		}
+		return r.doesResultExist(ctx, &mutation)
 	}
+	mutationControllerLog.Info("Mutation is in an unknown phase", "mutation", mutation.Name, "phase", mutation.Status.Phase)
 	return ctrl.Result{}, nil
 }
 
@@ -250,26 +349,121 @@ func (r *MutationReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		Named("mutation").
 		Complete(r)
 }
-func (r *MutationReconciler) doesResultExist(ctx context.Context, mutation corev1alpha1.Mutation) (ctrl.Result, error) {
-	var result corev1alpha1.Result
-	if err := r.Get(ctx, client.ObjectKey{
-		Name: mutation.Spec.ResultRef.Name, Namespace: mutation.Spec.ResultRef.Namespace}, &result); err != nil {
-		mutationControllerLog.Error(err, "unable to get result mutation successful", "mutation", mutation.Name, "result", mutation.Spec.ResultRef.Name)
-		mutation.Status.Phase = corev1alpha1.AutoRemediationPhaseSuccessful
-		mutation.Status.Message = "Successful"
-		// update status
-		if err := r.Client.Update(ctx, &mutation); err != nil {
-			mutationControllerLog.Error(err, "unable to update mutation status")
-			return ctrl.Result{RequeueAfter: util.ErrorRequeueTime}, err
+
+// executionConfig converts the spec.targetConfiguration to an object and wraps it for the executors
+func (r *MutationReconciler) executionConfig(ctx context.Context, mutation *corev1alpha1.Mutation,
+	k8sgpt *corev1alpha1.K8sGPT, backend string, queryClient rpc.ServerQueryServiceClient) (conversions.ObjectExecutionConfig, error) {
+	obj, err := util.FromConfig(util.FromObjectConfig{
+		Kind:      mutation.Spec.ResourceRef.Kind,
+		GvkStr:    mutation.Spec.ResourceGVK,
+		Config:    mutation.Spec.TargetConfiguration,
+		Name:      mutation.Spec.ResourceRef.Name,
+		Namespace: mutation.Spec.ResourceRef.Namespace,
+	})
+	if err != nil {
+		return conversions.ObjectExecutionConfig{}, &conversions.GuardrailError{
+			Violations: []string{fmt.Sprintf("target configuration does not parse: %v", err)}}
+	}
+	expected, err := util.ParseGVK(mutation.Spec.ResourceGVK, mutation.Spec.ResourceRef.Kind)
+	if err != nil {
+		return conversions.ObjectExecutionConfig{}, err
+	}
+	if violations := conversions.CheckIdentity(expected, mutation.Spec.ResourceRef.Name,
+		mutation.Spec.ResourceRef.Namespace, obj); len(violations) > 0 {
+		return conversions.ObjectExecutionConfig{}, &conversions.GuardrailError{Violations: violations}
+	}
+	config := conversions.ObjectExecutionConfig{
+		Ctx:         ctx,
+		Rc:          r.Client,
+		Log:         mutationControllerLog,
+		Obj:         obj,
+		Backend:     backend,
+		Mutation:    mutation,
+		QueryClient: queryClient,
+	}
+	templates := r.promptTemplates(k8sgpt)
+	config.Prompts = templates
+	config.PromptData = func() prompts.Data {
+		var result *corev1alpha1.Result
+		var found corev1alpha1.Result
+		if err := r.Client.Get(ctx, client.ObjectKey{Name: mutation.Spec.ResultRef.Name,
+			Namespace: mutation.Spec.ResultRef.Namespace}, &found); err == nil {
+			result = &found
 		}
-	} else {
-		mutation.Status.Phase = corev1alpha1.AutoRemediationPending
-		mutation.Status.Message = "Pending"
-		if err := r.Client.Update(ctx, &mutation); err != nil {
-			mutationControllerLog.Error(err, "unable to update mutation status")
+		return r.promptData(ctx, mutation, result, templates)
+	}
+	if k8sgpt != nil && k8sgpt.Spec.AI != nil {
+		config.ForceApply = k8sgpt.Spec.AI.AutoRemediation.ForceApply
+		config.Policies = k8sgpt.Spec.AI.AutoRemediation.Policies
+		if config.Scope, err = conversions.NewRemediationScope(k8sgpt.Spec.AI.AutoRemediation); err != nil {
+			return conversions.ObjectExecutionConfig{}, err
+		}
+	}
+	return config, nil
+}
+
+// similarityRequirementMet reports whether the similarity score of the mutation reaches the
+// requirement of its instance. Scores or requirements that cannot be parsed do not block.
+func similarityRequirementMet(k8sgpt *corev1alpha1.K8sGPT, mutation corev1alpha1.Mutation) bool {
+	if k8sgpt == nil || k8sgpt.Spec.AI == nil || k8sgpt.Spec.AI.AutoRemediation.SimilarityRequirement == "" {
+		return true
+	}
+	ss, err := strconv.ParseFloat(strings.TrimSpace(mutation.Spec.SimilarityScore), 64)
+	if err != nil {
+		mutationControllerLog.Error(err, "unable to parse similarity score", "mutation", mutation.Name)
+		return true
+	}
+	rt, err := strconv.ParseFloat(k8sgpt.Spec.AI.AutoRemediation.SimilarityRequirement, 64)
+	if err != nil {
+		mutationControllerLog.Error(err, "unable to parse risk threshold", "mutation", mutation.Name)
+		return true
+	}
+	return ss >= rt
+}
+
+// owningK8sGPT returns the namespace/name of the K8sGPT instance that created the mutation.
+// Mutations created before they were labelled fall back to the labels of their result.
+func (r *MutationReconciler) owningK8sGPT(ctx context.Context, mutation corev1alpha1.Mutation) (types.NamespacedName, error) {
+	labels := mutation.GetLabels()
+	if labels["k8sgpts.k8sgpt.ai/name"] == "" {
+		var result corev1alpha1.Result
+		if err := r.Get(ctx, client.ObjectKey{Name: mutation.Spec.ResultRef.Name,
+			Namespace: mutation.Spec.ResultRef.Namespace}, &result); err != nil {
+			return types.NamespacedName{}, err
+		}
+		labels = result.GetLabels()
+	}
+	if labels["k8sgpts.k8sgpt.ai/name"] == "" {
+		return types.NamespacedName{}, fmt.Errorf("mutation %s has no owning K8sGPT", mutation.Name)
+	}
+	return types.NamespacedName{
+		Name:      labels["k8sgpts.k8sgpt.ai/name"],
+		Namespace: labels["k8sgpts.k8sgpt.ai/namespace"],
+	}, nil
+}
+
+// doesResultExist completes the mutation once none of the results it was raised for exist anymore
+func (r *MutationReconciler) doesResultExist(ctx context.Context, mutation *corev1alpha1.Mutation) (ctrl.Result, error) {
+	resultRefs := mutation.Spec.ResultRefs
+	if len(resultRefs) == 0 {
+		resultRefs = []corev1.ObjectReference{mutation.Spec.ResultRef}
+	}
+	for _, resultRef := range resultRefs {
+		var result corev1alpha1.Result
+		err := r.Get(ctx, client.ObjectKey{Name: resultRef.Name, Namespace: resultRef.Namespace}, &result)
+		if err == nil {
+			if mutation.Status.Phase == corev1alpha1.AutoRemediationPending {
+				return ctrl.Result{RequeueAfter: util.PendingRequeueTime}, nil
+			}
+			return r.moveTo(ctx, mutation, corev1alpha1.AutoRemediationPending, "Pending",
+				ctrl.Result{RequeueAfter: util.PendingRequeueTime})
+		}
+		if !apierrors.IsNotFound(err) {
+			mutationControllerLog.Error(err, "unable to get result", "mutation", mutation.Name, "result", resultRef.Name)
 			return ctrl.Result{RequeueAfter: util.ErrorRequeueTime}, err
 		}
-		return ctrl.Result{RequeueAfter: util.PendingRequeueTime}, nil
 	}
-	return ctrl.Result{RequeueAfter: util.CompletedRequeueTime}, nil
+	mutationControllerLog.Info("Results no longer exist, mutation successful", "mutation", mutation.Name,
+		"results", len(resultRefs))
+	return r.moveTo(ctx, mutation, corev1alpha1.AutoRemediationPhaseSuccessful, "Successful", ctrl.Result{})
 }
@@ -30,6 +30,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	corev1alpha1 "github.com/k8sgpt-ai/k8sgpt-operator/api/v1alpha1"
+	"github.com/k8sgpt-ai/k8sgpt-operator/internal/controller/shared"
 )
 
 var _ = Describe("Mutation Controller", func() {
@@ -92,12 +93,11 @@ spec:
 		It("should requeue the resource and not update the status", func() {
 			By("Reconciling the created resource")
 			controllerReconciler := &MutationReconciler{
-				Client:            reconciler.Client,
-				Scheme:            reconciler.Client.Scheme(),
-				ServerQueryClient: nil, // Simulate client not ready
-				RemoteBackend:     "test-backend",
+				Client:         reconciler.Client,
+				Scheme:         reconciler.Client.Scheme(),
+				ClientRegistry: shared.NewClientRegistry(), // Simulate client not ready
 			}
-			// The controller should now requeue if ServerQueryClient is nil, without blocking or using a signal.
+			// The controller should now requeue if no client is registered for the owning K8sGPT, without blocking or using a signal.
 			result, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
 				NamespacedName: typeNamespacedName,
 			})
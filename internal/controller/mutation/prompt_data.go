@@ -0,0 +1,139 @@
+/*
+Copyright 2023 K8sGPT Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mutation
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	corev1alpha1 "github.com/k8sgpt-ai/k8sgpt-operator/api/v1alpha1"
+	"github.com/k8sgpt-ai/k8sgpt-operator/internal/controller/util"
+	"github.com/k8sgpt-ai/k8sgpt-operator/internal/prompts"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// maxPromptEvents is how many of the most recent events of an object prompts are given
+const maxPromptEvents = 10
+
+// promptTemplates returns the prompt templates of the K8sGPT instance
+func (r *MutationReconciler) promptTemplates(k8sgpt *corev1alpha1.K8sGPT) prompts.Templates {
+	templates := prompts.Templates{Client: r.Client}
+	if k8sgpt != nil && k8sgpt.Spec.AI != nil && k8sgpt.Spec.AI.AutoRemediation.PromptTemplates != nil {
+		templates.ConfigMap = &types.NamespacedName{Namespace: k8sgpt.Namespace,
+			Name: k8sgpt.Spec.AI.AutoRemediation.PromptTemplates.Name}
+	}
+	return templates
+}
+
+// promptData collects the variables of the prompt templates of a mutation. The events and owner
+// chain of the object are only read for templates from a ConfigMap, the built-in prompts do not
+// use them. They are context for the backend, so failing to read them is logged and not fatal.
+func (r *MutationReconciler) promptData(ctx context.Context, mutation *corev1alpha1.Mutation,
+	result *corev1alpha1.Result, templates prompts.Templates) prompts.Data {
+	data := prompts.Data{Origin: mutation.Spec.OriginConfiguration}
+	if result != nil {
+		data.Result = prompts.Result{
+			Kind:         result.Spec.Kind,
+			Name:         result.Spec.Name,
+			ParentObject: result.Spec.ParentObject,
+			Details:      result.Spec.Details,
+		}
+		for _, failure := range result.Spec.Error {
+			data.Result.Errors = append(data.Result.Errors, failure.Text)
+		}
+	}
+	if !templates.Enabled() {
+		return data
+	}
+	events, err := r.objectEvents(ctx, mutation.Spec.ResourceRef)
+	if err != nil {
+		mutationControllerLog.Error(err, "unable to list events for prompt", "mutation", mutation.Name)
+	}
+	data.Events = events
+	owners, err := r.ownerChain(ctx, mutation)
+	if err != nil {
+		mutationControllerLog.Error(err, "unable to resolve owners for prompt", "mutation", mutation.Name)
+	}
+	data.Owners = owners
+	return data
+}
+
+// objectEvents returns the most recent events of the object, newest first
+func (r *MutationReconciler) objectEvents(ctx context.Context, ref corev1.ObjectReference) ([]prompts.Event, error) {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("EventList"))
+	if err := r.Client.List(ctx, list, client.InNamespace(ref.Namespace)); err != nil {
+		return nil, err
+	}
+	var events []corev1.Event
+	for _, item := range list.Items {
+		var event corev1.Event
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(item.Object, &event); err != nil {
+			return nil, err
+		}
+		if event.InvolvedObject.Kind == ref.Kind && event.InvolvedObject.Name == ref.Name {
+			events = append(events, event)
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return eventTime(events[j]).Before(eventTime(events[i]))
+	})
+	if len(events) > maxPromptEvents {
+		events = events[:maxPromptEvents]
+	}
+	promptEvents := make([]prompts.Event, 0, len(events))
+	for _, event := range events {
+		promptEvents = append(promptEvents, prompts.Event{Type: event.Type, Reason: event.Reason,
+			Message: event.Message, Count: event.Count})
+	}
+	return promptEvents, nil
+}
+
+func eventTime(event corev1.Event) time.Time {
+	if !event.LastTimestamp.IsZero() {
+		return event.LastTimestamp.Time
+	}
+	if !event.EventTime.IsZero() {
+		return event.EventTime.Time
+	}
+	return event.CreationTimestamp.Time
+}
+
+// ownerChain follows the controllers of the object of the mutation up to the top level object
+func (r *MutationReconciler) ownerChain(ctx context.Context, mutation *corev1alpha1.Mutation) ([]prompts.Owner, error) {
+	gvk, err := util.ParseGVK(mutation.Spec.ResourceGVK, mutation.Spec.ResourceRef.Kind)
+	if err != nil {
+		return nil, err
+	}
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(gvk)
+	key := client.ObjectKey{Namespace: mutation.Spec.ResourceRef.Namespace, Name: mutation.Spec.ResourceRef.Name}
+	if err := r.Client.Get(ctx, key, obj); err != nil {
+		return nil, err
+	}
+	chain, err := util.OwnerChain(ctx, r.Client, obj)
+	owners := make([]prompts.Owner, 0, len(chain))
+	for _, owner := range chain {
+		owners = append(owners, prompts.Owner{APIVersion: owner.APIVersion, Kind: owner.Kind, Name: owner.Name})
+	}
+	return owners, err
+}
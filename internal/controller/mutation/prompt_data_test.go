@@ -0,0 +1,72 @@
+package mutation
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1alpha1 "github.com/k8sgpt-ai/k8sgpt-operator/api/v1alpha1"
+	"github.com/k8sgpt-ai/k8sgpt-operator/internal/prompts"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+func Test_PromptData(t *testing.T) {
+	controlledBy := func(kind, name string) []metav1.OwnerReference {
+		return []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: kind, Name: name, UID: "uid", Controller: ptr.To(true)}}
+	}
+	event := func(name, object, reason string, at time.Time) *corev1.Event {
+		return &corev1.Event{
+			ObjectMeta:     metav1.ObjectMeta{Namespace: "default", Name: name},
+			InvolvedObject: corev1.ObjectReference{Kind: "Pod", Namespace: "default", Name: object},
+			Type:           corev1.EventTypeWarning, Reason: reason, Message: reason + " " + object,
+			LastTimestamp: metav1.NewTime(at), Count: 1,
+		}
+	}
+	now := time.Now()
+	spec := corev1alpha1.MutationSpec{
+		ResourceRef:         corev1.ObjectReference{Kind: "Pod", Namespace: "default", Name: "web-5d4f-x2"},
+		ResourceGVK:         "/v1, Kind=Pod",
+		OriginConfiguration: "kind: Pod\n",
+	}
+	result := &corev1alpha1.Result{Spec: corev1alpha1.ResultSpec{Kind: "Pod", Name: "default/web-5d4f-x2",
+		Details: "the image does not exist", Error: []corev1alpha1.Failure{{Text: "Back-off pulling image"}}}}
+
+	f := newReconcileFixture(t, corev1alpha1.AutoRemediation{},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web-5d4f-x2",
+			OwnerReferences: controlledBy("ReplicaSet", "web-5d4f")}},
+		&appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web-5d4f",
+			OwnerReferences: controlledBy("Deployment", "web")}},
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web"}},
+		event("pulled", "web-5d4f-x2", "Failed", now.Add(-time.Minute)),
+		event("backoff", "web-5d4f-x2", "BackOff", now),
+		event("other", "db-0", "Failed", now),
+	)
+	mutation := newMutation("web", corev1alpha1.AutoRemediationPhaseNotStarted, spec)
+
+	// The built-in prompts only get the result and the origin
+	data := f.reconciler.promptData(context.Background(), mutation, result, prompts.Templates{})
+	assert.Equal(t, prompts.Data{
+		Result: prompts.Result{Kind: "Pod", Name: "default/web-5d4f-x2", Details: "the image does not exist",
+			Errors: []string{"Back-off pulling image"}},
+		Origin: "kind: Pod\n",
+	}, data)
+
+	templates := f.reconciler.promptTemplates(&corev1alpha1.K8sGPT{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "k8sgpt"},
+		Spec: corev1alpha1.K8sGPTSpec{AI: &corev1alpha1.AISpec{AutoRemediation: corev1alpha1.AutoRemediation{
+			PromptTemplates: &corev1.LocalObjectReference{Name: "prompts"}}}},
+	})
+	data = f.reconciler.promptData(context.Background(), mutation, result, templates)
+	assert.Equal(t, []prompts.Event{
+		{Type: corev1.EventTypeWarning, Reason: "BackOff", Message: "BackOff web-5d4f-x2", Count: 1},
+		{Type: corev1.EventTypeWarning, Reason: "Failed", Message: "Failed web-5d4f-x2", Count: 1},
+	}, data.Events)
+	assert.Equal(t, []prompts.Owner{
+		{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "web-5d4f"},
+		{APIVersion: "apps/v1", Kind: "Deployment", Name: "web"},
+	}, data.Owners)
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2023 K8sGPT Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mutation
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1alpha1 "github.com/k8sgpt-ai/k8sgpt-operator/api/v1alpha1"
+	"github.com/k8sgpt-ai/k8sgpt-operator/internal/controller/conversions"
+	"github.com/k8sgpt-ai/k8sgpt-operator/internal/controller/util"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// DefaultPendingDeadline is how long an applied mutation may stay Pending before it is rolled back
+const DefaultPendingDeadline = 30 * time.Minute
+
+// rollbackPolicy returns whether rollback is enabled for the instance and its pending deadline
+func rollbackPolicy(k8sgpt *corev1alpha1.K8sGPT) (bool, time.Duration) {
+	if k8sgpt == nil || k8sgpt.Spec.AI == nil || k8sgpt.Spec.AI.AutoRemediation.Rollback == nil {
+		return true, DefaultPendingDeadline
+	}
+	policy := k8sgpt.Spec.AI.AutoRemediation.Rollback
+	deadline, err := time.ParseDuration(policy.PendingDeadline)
+	if err != nil || deadline <= 0 {
+		deadline = DefaultPendingDeadline
+	}
+	return policy.Enabled, deadline
+}
+
+// rollbackReason returns why an applied mutation has to be rolled back, or an empty string
+func (r *MutationReconciler) rollbackReason(ctx context.Context, mutation corev1alpha1.Mutation,
+	previous *unstructured.Unstructured, deadline time.Duration, now time.Time) (string, error) {
+	reason, err := conversions.RolloutFailure(ctx, r.Client, previous, mutation.Status.CrashLoopsBeforeApply,
+		mutation.Status.RolloutStalledBeforeApply)
+	if err != nil || reason != "" {
+		return reason, err
+	}
+	if mutation.Status.Phase == corev1alpha1.AutoRemediationPending && mutation.Status.AppliedAt != nil &&
+		now.Sub(mutation.Status.AppliedAt.Time) > deadline {
+		return conversions.RollbackReasonPendingDeadlineExceeded, nil
+	}
+	return "", nil
+}
+
+// rollbackIfFailed restores the previous configuration of an applied mutation whose rollout
+// failed or whose result outlived the pending deadline, then moves it to RolledBack.
+// handled reports whether a rollback is under way and the returned result should be used.
+func (r *MutationReconciler) rollbackIfFailed(ctx context.Context, mutation *corev1alpha1.Mutation,
+	k8sgpt *corev1alpha1.K8sGPT) (bool, ctrl.Result, error) {
+	enabled, deadline := rollbackPolicy(k8sgpt)
+	if !enabled || mutation.Status.PreviousConfiguration == "" {
+		return false, ctrl.Result{}, nil
+	}
+	previous, err := conversions.FromManifest(mutation.Status.PreviousConfiguration)
+	if err != nil {
+		mutationControllerLog.Error(err, "unable to parse previous configuration, rollback not possible", "mutation", mutation.Name)
+		return false, ctrl.Result{}, nil
+	}
+
+	if mutation.Status.RollbackReason == "" {
+		reason, err := r.rollbackReason(ctx, *mutation, previous, deadline, time.Now())
+		if err != nil {
+			mutationControllerLog.Error(err, "unable to check mutation rollout", "mutation", mutation.Name)
+			return true, ctrl.Result{RequeueAfter: util.ErrorRequeueTime}, nil
+		}
+		if reason == "" {
+			return false, ctrl.Result{}, nil
+		}
+		mutationControllerLog.Info("Rolling back mutation", "mutation", mutation.Name, "reason", reason)
+		mutation.Status.RollbackReason = reason
+		mutation.Status.Message = fmt.Sprintf("Rolling back: %s", reason)
+	}
+
+	done, err := conversions.Rollback(ctx, r.Client, previous)
+	if err != nil {
+		mutationControllerLog.Error(err, "unable to roll back mutation", "mutation", mutation.Name)
+	}
+	if !done {
+		result, err := r.updateStatus(ctx, mutation, ctrl.Result{RequeueAfter: util.ErrorRequeueTime})
+		return true, result, err
+	}
+	result, err := r.moveTo(ctx, mutation, corev1alpha1.AutoRemediationRolledBack,
+		fmt.Sprintf("Rolled back: %s", mutation.Status.RollbackReason), ctrl.Result{})
+	return true, result, err
+}
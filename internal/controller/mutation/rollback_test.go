@@ -0,0 +1,101 @@
+package mutation
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1alpha1 "github.com/k8sgpt-ai/k8sgpt-operator/api/v1alpha1"
+	"github.com/k8sgpt-ai/k8sgpt-operator/internal/controller/conversions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_RollbackPolicy(t *testing.T) {
+	enabled, deadline := rollbackPolicy(&corev1alpha1.K8sGPT{})
+	assert.True(t, enabled)
+	assert.Equal(t, DefaultPendingDeadline, deadline)
+
+	enabled, deadline = rollbackPolicy(&corev1alpha1.K8sGPT{Spec: corev1alpha1.K8sGPTSpec{AI: &corev1alpha1.AISpec{
+		AutoRemediation: corev1alpha1.AutoRemediation{Rollback: &corev1alpha1.RollbackPolicy{PendingDeadline: "10m"}}}}})
+	assert.False(t, enabled)
+	assert.Equal(t, 10*time.Minute, deadline)
+}
+
+func Test_RollbackIfFailedAfterPendingDeadline(t *testing.T) {
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, corev1alpha1.AddToScheme(scheme))
+
+	deployment := func(image string) *appsv1.Deployment {
+		return &appsv1.Deployment{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web"},
+			Spec: appsv1.DeploymentSpec{
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+				Template: corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "web", Image: image}}},
+				},
+			},
+		}
+	}
+	previous, err := conversions.ToApplyObject(deployment("nginx:1.0"))
+	require.NoError(t, err)
+	manifest, err := conversions.ToManifest(previous)
+	require.NoError(t, err)
+
+	appliedAt := metav1.NewTime(time.Now().Add(-time.Hour))
+	mutation := &corev1alpha1.Mutation{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "k8sgpt", Name: "web"},
+		Status: corev1alpha1.MutationStatus{
+			Phase:                 corev1alpha1.AutoRemediationPending,
+			AppliedAt:             &appliedAt,
+			PreviousConfiguration: manifest,
+		},
+	}
+	// The mutation applied the image of the broken deployment
+	broken := deployment("nginx:broken")
+	broken.ManagedFields = []metav1.ManagedFieldsEntry{{
+		Manager:    conversions.RemediationFieldManager,
+		Operation:  metav1.ManagedFieldsOperationApply,
+		APIVersion: "apps/v1",
+		FieldsType: "FieldsV1",
+		FieldsV1: &metav1.FieldsV1{Raw: []byte(
+			`{"f:spec":{"f:template":{"f:spec":{"f:containers":{"k:{\"name\":\"web\"}":{"f:image":{}}}}}}}`)},
+	}}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(broken, mutation).
+		WithStatusSubresource(mutation).WithInterceptorFuncs(applyAsStrategicMerge).Build()
+	r := &MutationReconciler{Client: c, Scheme: scheme}
+
+	handled, _, err := r.rollbackIfFailed(ctx, mutation, &corev1alpha1.K8sGPT{})
+	require.NoError(t, err)
+	assert.True(t, handled)
+
+	var updated corev1alpha1.Mutation
+	require.NoError(t, c.Get(ctx, client.ObjectKeyFromObject(mutation), &updated))
+	assert.Equal(t, corev1alpha1.AutoRemediationRolledBack, updated.Status.Phase)
+	assert.Equal(t, conversions.RollbackReasonPendingDeadlineExceeded, updated.Status.RollbackReason)
+
+	var restored appsv1.Deployment
+	require.NoError(t, c.Get(ctx, client.ObjectKey{Namespace: "default", Name: "web"}, &restored))
+	assert.Equal(t, "nginx:1.0", restored.Spec.Template.Spec.Containers[0].Image)
+
+	// A mutation within its deadline and with a healthy rollout is left alone
+	recent := metav1.Now()
+	mutation.Status = corev1alpha1.MutationStatus{
+		Phase:                 corev1alpha1.AutoRemediationPending,
+		AppliedAt:             &recent,
+		PreviousConfiguration: manifest,
+	}
+	handled, _, err = r.rollbackIfFailed(ctx, mutation, &corev1alpha1.K8sGPT{})
+	require.NoError(t, err)
+	assert.False(t, handled)
+}
@@ -0,0 +1,245 @@
+/*
+Copyright 2023 K8sGPT Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mutation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	corev1alpha1 "github.com/k8sgpt-ai/k8sgpt-operator/api/v1alpha1"
+	"github.com/k8sgpt-ai/k8sgpt-operator/internal/controller/conversions"
+	"github.com/k8sgpt-ai/k8sgpt-operator/internal/controller/util"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// mutationTransitions lists the phases a mutation may move to from each phase.
+// Phases without transitions are terminal.
+var mutationTransitions = map[corev1alpha1.AutoRemediationPhase][]corev1alpha1.AutoRemediationPhase{
+	corev1alpha1.AutoRemediationPhaseNotStarted: {
+		corev1alpha1.AutoRemediationPhaseInProgress,
+		corev1alpha1.AutoRemediationAwaitingApproval,
+		corev1alpha1.AutoRemediationPending,
+		corev1alpha1.AutoRemediationAborted,
+		corev1alpha1.AutoRemediationFailed,
+	},
+	corev1alpha1.AutoRemediationAwaitingApproval: {
+		corev1alpha1.AutoRemediationPhaseInProgress,
+		corev1alpha1.AutoRemediationPending,
+		corev1alpha1.AutoRemediationAborted,
+		corev1alpha1.AutoRemediationFailed,
+	},
+	corev1alpha1.AutoRemediationPhaseInProgress: {
+		corev1alpha1.AutoRemediationPhaseCompleted,
+		corev1alpha1.AutoRemediationAborted,
+		corev1alpha1.AutoRemediationFailed,
+	},
+	corev1alpha1.AutoRemediationPhaseCompleted: {
+		corev1alpha1.AutoRemediationPhaseSuccessful,
+		corev1alpha1.AutoRemediationPending,
+		corev1alpha1.AutoRemediationRolledBack,
+	},
+	corev1alpha1.AutoRemediationPending: {
+		corev1alpha1.AutoRemediationPhaseSuccessful,
+		corev1alpha1.AutoRemediationRolledBack,
+	},
+	corev1alpha1.AutoRemediationPhaseSuccessful: nil,
+	corev1alpha1.AutoRemediationAborted:         nil,
+	corev1alpha1.AutoRemediationFailed:          nil,
+	corev1alpha1.AutoRemediationRolledBack:      nil,
+}
+
+// heldTransitions lists the phases a mutation the blast radius limits hold in the Pending phase
+// may move to, it has not been applied yet
+var heldTransitions = []corev1alpha1.AutoRemediationPhase{
+	corev1alpha1.AutoRemediationPhaseInProgress,
+	corev1alpha1.AutoRemediationAborted,
+	corev1alpha1.AutoRemediationFailed,
+}
+
+// allowedTransitions returns the phases the mutation may move to from its phase
+func allowedTransitions(mutation *corev1alpha1.Mutation) []corev1alpha1.AutoRemediationPhase {
+	if isHeld(mutation) {
+		return heldTransitions
+	}
+	return mutationTransitions[mutation.Status.Phase]
+}
+
+// isHeld reports whether the blast radius limits hold the mutation before it is applied
+func isHeld(mutation *corev1alpha1.Mutation) bool {
+	return mutation.Status.Phase == corev1alpha1.AutoRemediationPending && mutation.Status.HeldBy != ""
+}
+
+// DefaultRetryBudget is how often a mutation is retried when its instance sets no budget
+const DefaultRetryBudget = 3
+
+// IsTerminal reports whether a mutation in the phase is finished
+func IsTerminal(phase corev1alpha1.AutoRemediationPhase) bool {
+	transitions, known := mutationTransitions[phase]
+	return known && len(transitions) == 0
+}
+
+// transition moves the mutation to a phase if the state machine allows it. Staying in the
+// current phase is always allowed so that the message can be updated.
+func transition(mutation *corev1alpha1.Mutation, to corev1alpha1.AutoRemediationPhase, message string) error {
+	from := mutation.Status.Phase
+	if from != to && !slices.Contains(allowedTransitions(mutation), to) {
+		return fmt.Errorf("invalid mutation transition from %s to %s", from, to)
+	}
+	if from != to && isHeld(mutation) {
+		mutation.Status.HeldBy = ""
+	}
+	mutation.Status.Phase = to
+	mutation.Status.Message = message
+	appendHistory(mutation, from, transitionApplyResult(from, to))
+	return nil
+}
+
+// Cancel aborts a mutation that has not been applied yet, e.g. when its K8sGPT instance is
+// deleted, and reports whether it did. Mutations that were applied are left in their phase.
+func Cancel(mutation *corev1alpha1.Mutation, message string) bool {
+	if !slices.Contains(allowedTransitions(mutation), corev1alpha1.AutoRemediationAborted) {
+		return false
+	}
+	return transition(mutation, corev1alpha1.AutoRemediationAborted, message) == nil
+}
+
+// retryBudget returns how often mutations of the instance are retried before they fail, the
+// default budget when the instance sets none
+func retryBudget(k8sgpt *corev1alpha1.K8sGPT) int {
+	if k8sgpt == nil || k8sgpt.Spec.AI == nil || k8sgpt.Spec.AI.AutoRemediation.RetryBudget <= 0 {
+		return DefaultRetryBudget
+	}
+	return k8sgpt.Spec.AI.AutoRemediation.RetryBudget
+}
+
+// recordFailure counts a failed attempt against the retry budget. Once the budget is spent the
+// mutation moves to Failed with the reason and true is returned.
+func recordFailure(mutation *corev1alpha1.Mutation, budget int, reason corev1alpha1.MutationFailureReason, cause error) (bool, error) {
+	mutation.Status.Retries++
+	if mutation.Status.Retries <= budget {
+		mutation.Status.Message = fmt.Sprintf("Retry %d of %d after %s: %v", mutation.Status.Retries, budget, reason, cause)
+		applyResult := ""
+		if reason == corev1alpha1.MutationFailureApplyFailed {
+			applyResult = applyResultFailed
+		}
+		appendHistory(mutation, mutation.Status.Phase, applyResult)
+		return false, nil
+	}
+	return true, fail(mutation, reason, cause)
+}
+
+// fail moves the mutation to Failed with the reason
+func fail(mutation *corev1alpha1.Mutation, reason corev1alpha1.MutationFailureReason, cause error) error {
+	if err := transition(mutation, corev1alpha1.AutoRemediationFailed, fmt.Sprintf("%s: %v", reason, cause)); err != nil {
+		return err
+	}
+	mutation.Status.FailureReason = reason
+	return nil
+}
+
+// updateStatus persists the status of the mutation through the status subresource
+func (r *MutationReconciler) updateStatus(ctx context.Context, mutation *corev1alpha1.Mutation, result ctrl.Result) (ctrl.Result, error) {
+	if err := r.Client.Status().Update(ctx, mutation); err != nil {
+		mutationControllerLog.Error(err, "unable to update mutation status", "mutation", mutation.Name)
+		return ctrl.Result{RequeueAfter: util.ErrorRequeueTime}, err
+	}
+	return result, nil
+}
+
+// updateStatusAndEmit persists the status of the mutation and publishes its latest history entry
+func (r *MutationReconciler) updateStatusAndEmit(ctx context.Context, mutation *corev1alpha1.Mutation,
+	result ctrl.Result, retry bool) (ctrl.Result, error) {
+	result, err := r.updateStatus(ctx, mutation, result)
+	if err == nil {
+		r.emitEvents(ctx, mutation, retry)
+	}
+	return result, err
+}
+
+// moveTo transitions the mutation and persists its status
+func (r *MutationReconciler) moveTo(ctx context.Context, mutation *corev1alpha1.Mutation,
+	to corev1alpha1.AutoRemediationPhase, message string, result ctrl.Result) (ctrl.Result, error) {
+	if err := transition(mutation, to, message); err != nil {
+		mutationControllerLog.Error(err, "refusing mutation transition", "mutation", mutation.Name)
+		return ctrl.Result{}, err
+	}
+	return r.updateStatusAndEmit(ctx, mutation, result, false)
+}
+
+// retryOrFail records a failed attempt and persists the status, the mutation is retried until its budget is spent
+func (r *MutationReconciler) retryOrFail(ctx context.Context, mutation *corev1alpha1.Mutation, budget int,
+	reason corev1alpha1.MutationFailureReason, cause error) (ctrl.Result, error) {
+	failed, err := recordFailure(mutation, budget, reason, cause)
+	if err != nil {
+		mutationControllerLog.Error(err, "refusing mutation transition", "mutation", mutation.Name)
+		return ctrl.Result{}, err
+	}
+	if failed {
+		mutationControllerLog.Info("Mutation failed, retry budget spent", "mutation", mutation.Name, "reason", reason)
+		return r.updateStatusAndEmit(ctx, mutation, ctrl.Result{}, false)
+	}
+	return r.updateStatusAndEmit(ctx, mutation, ctrl.Result{RequeueAfter: util.ErrorRequeueTime}, true)
+}
+
+// failNow moves the mutation to Failed without retrying, for errors a retry cannot fix
+func (r *MutationReconciler) failNow(ctx context.Context, mutation *corev1alpha1.Mutation,
+	reason corev1alpha1.MutationFailureReason, cause error) (ctrl.Result, error) {
+	if err := fail(mutation, reason, cause); err != nil {
+		mutationControllerLog.Error(err, "refusing mutation transition", "mutation", mutation.Name)
+		return ctrl.Result{}, err
+	}
+	return r.updateStatusAndEmit(ctx, mutation, ctrl.Result{}, false)
+}
+
+// refuse fails the mutation with the guardrail violations of its target
+func (r *MutationReconciler) refuse(ctx context.Context, mutation *corev1alpha1.Mutation,
+	refused *conversions.GuardrailError) (ctrl.Result, error) {
+	mutationControllerLog.Info("Mutation target refused by guardrails", "mutation", mutation.Name,
+		"violations", refused.Violations)
+	mutation.Status.GuardrailViolations = refused.Violations
+	return r.failNow(ctx, mutation, corev1alpha1.MutationFailureGuardrailViolation, refused)
+}
+
+// outOfScope fails the mutation when its target is outside the remediation scope, errors reading
+// the target are retried
+func (r *MutationReconciler) outOfScope(ctx context.Context, mutation *corev1alpha1.Mutation, budget int,
+	err error) (ctrl.Result, error) {
+	if errors.Is(err, conversions.ErrOutOfScope) {
+		mutationControllerLog.Info("Mutation target outside the remediation scope", "mutation", mutation.Name,
+			"reason", err.Error())
+		return r.failNow(ctx, mutation, corev1alpha1.MutationFailureOutOfScope, err)
+	}
+	mutationControllerLog.Error(err, "unable to check mutation scope", "mutation", mutation.Name)
+	return r.retryOrFail(ctx, mutation, budget, corev1alpha1.MutationFailureApplyFailed, err)
+}
+
+// block fails the mutation with the remediation policies its target violates
+func (r *MutationReconciler) block(ctx context.Context, mutation *corev1alpha1.Mutation,
+	violations []corev1alpha1.PolicyViolation) (ctrl.Result, error) {
+	policies := make([]string, 0, len(violations))
+	for _, violation := range violations {
+		policies = append(policies, violation.Policy)
+	}
+	mutationControllerLog.Info("Mutation target blocked by remediation policies", "mutation", mutation.Name,
+		"policies", policies)
+	mutation.Status.PolicyViolations = violations
+	return r.failNow(ctx, mutation, corev1alpha1.MutationFailurePolicyViolation,
+		fmt.Errorf("remediation policies violated: %s", strings.Join(policies, ", ")))
+}
@@ -0,0 +1,606 @@
+package mutation
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	corev1alpha1 "github.com/k8sgpt-ai/k8sgpt-operator/api/v1alpha1"
+	"github.com/k8sgpt-ai/k8sgpt-operator/internal/controller/conversions"
+	"github.com/k8sgpt-ai/k8sgpt-operator/internal/controller/shared"
+	"github.com/k8sgpt-ai/k8sgpt-operator/internal/controller/types"
+	kclient "github.com/k8sgpt-ai/k8sgpt-operator/pkg/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ktypes "k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+var allPhases = []corev1alpha1.AutoRemediationPhase{
+	corev1alpha1.AutoRemediationPhaseNotStarted,
+	corev1alpha1.AutoRemediationPhaseInProgress,
+	corev1alpha1.AutoRemediationPhaseCompleted,
+	corev1alpha1.AutoRemediationPhaseSuccessful,
+	corev1alpha1.AutoRemediationPending,
+	corev1alpha1.AutoRemediationAborted,
+	corev1alpha1.AutoRemediationAwaitingApproval,
+	corev1alpha1.AutoRemediationRolledBack,
+	corev1alpha1.AutoRemediationFailed,
+}
+
+func Test_MutationTransitions(t *testing.T) {
+	type edge struct {
+		from, to corev1alpha1.AutoRemediationPhase
+	}
+	allowed := map[edge]bool{
+		{corev1alpha1.AutoRemediationPhaseNotStarted, corev1alpha1.AutoRemediationPhaseInProgress}:  true,
+		{corev1alpha1.AutoRemediationPhaseNotStarted, corev1alpha1.AutoRemediationAwaitingApproval}: true,
+		{corev1alpha1.AutoRemediationPhaseNotStarted, corev1alpha1.AutoRemediationPending}:          true,
+		{corev1alpha1.AutoRemediationPhaseNotStarted, corev1alpha1.AutoRemediationAborted}:          true,
+		{corev1alpha1.AutoRemediationPhaseNotStarted, corev1alpha1.AutoRemediationFailed}:           true,
+		{corev1alpha1.AutoRemediationAwaitingApproval, corev1alpha1.AutoRemediationPhaseInProgress}: true,
+		{corev1alpha1.AutoRemediationAwaitingApproval, corev1alpha1.AutoRemediationPending}:         true,
+		{corev1alpha1.AutoRemediationAwaitingApproval, corev1alpha1.AutoRemediationAborted}:         true,
+		{corev1alpha1.AutoRemediationAwaitingApproval, corev1alpha1.AutoRemediationFailed}:          true,
+		{corev1alpha1.AutoRemediationPhaseInProgress, corev1alpha1.AutoRemediationPhaseCompleted}:   true,
+		{corev1alpha1.AutoRemediationPhaseInProgress, corev1alpha1.AutoRemediationAborted}:          true,
+		{corev1alpha1.AutoRemediationPhaseInProgress, corev1alpha1.AutoRemediationFailed}:           true,
+		{corev1alpha1.AutoRemediationPhaseCompleted, corev1alpha1.AutoRemediationPhaseSuccessful}:   true,
+		{corev1alpha1.AutoRemediationPhaseCompleted, corev1alpha1.AutoRemediationPending}:           true,
+		{corev1alpha1.AutoRemediationPhaseCompleted, corev1alpha1.AutoRemediationRolledBack}:        true,
+		{corev1alpha1.AutoRemediationPending, corev1alpha1.AutoRemediationPhaseSuccessful}:          true,
+		{corev1alpha1.AutoRemediationPending, corev1alpha1.AutoRemediationRolledBack}:               true,
+	}
+	for _, from := range allPhases {
+		for _, to := range allPhases {
+			mutation := &corev1alpha1.Mutation{Status: corev1alpha1.MutationStatus{Phase: from}}
+			err := transition(mutation, to, "message")
+			if from == to || allowed[edge{from, to}] {
+				assert.NoError(t, err, "%s -> %s", from, to)
+				assert.Equal(t, to, mutation.Status.Phase)
+				assert.Equal(t, "message", mutation.Status.Message)
+				continue
+			}
+			assert.Error(t, err, "%s -> %s", from, to)
+			assert.Equal(t, from, mutation.Status.Phase)
+		}
+	}
+}
+
+func Test_HeldTransitions(t *testing.T) {
+	for _, to := range allPhases {
+		mutation := &corev1alpha1.Mutation{Status: corev1alpha1.MutationStatus{
+			Phase: corev1alpha1.AutoRemediationPending, HeldBy: "outside maintenance windows"}}
+		err := transition(mutation, to, "message")
+		switch to {
+		case corev1alpha1.AutoRemediationPending:
+			assert.NoError(t, err)
+			assert.Equal(t, "outside maintenance windows", mutation.Status.HeldBy)
+		case corev1alpha1.AutoRemediationPhaseInProgress, corev1alpha1.AutoRemediationAborted,
+			corev1alpha1.AutoRemediationFailed:
+			assert.NoError(t, err, to.String())
+			assert.Empty(t, mutation.Status.HeldBy, to.String())
+		default:
+			// A held mutation was not applied, it has no result to wait for
+			assert.Error(t, err, to.String())
+		}
+	}
+}
+
+func Test_TerminalPhases(t *testing.T) {
+	terminal := map[corev1alpha1.AutoRemediationPhase]bool{
+		corev1alpha1.AutoRemediationPhaseSuccessful: true,
+		corev1alpha1.AutoRemediationAborted:         true,
+		corev1alpha1.AutoRemediationFailed:          true,
+		corev1alpha1.AutoRemediationRolledBack:      true,
+	}
+	for _, phase := range allPhases {
+		assert.Equal(t, terminal[phase], IsTerminal(phase), phase.String())
+	}
+	assert.False(t, IsTerminal(corev1alpha1.AutoRemediationPhase(42)))
+}
+
+func Test_Cancel(t *testing.T) {
+	for _, phase := range allPhases {
+		mutation := &corev1alpha1.Mutation{Status: corev1alpha1.MutationStatus{Phase: phase}}
+		cancelled := Cancel(mutation, "Cancelled")
+		switch phase {
+		case corev1alpha1.AutoRemediationPhaseNotStarted, corev1alpha1.AutoRemediationAwaitingApproval,
+			corev1alpha1.AutoRemediationPhaseInProgress:
+			assert.True(t, cancelled, phase.String())
+			assert.Equal(t, corev1alpha1.AutoRemediationAborted, mutation.Status.Phase)
+			assert.Equal(t, "Cancelled", mutation.Status.Message)
+		default:
+			assert.False(t, cancelled, phase.String())
+			assert.Equal(t, phase, mutation.Status.Phase)
+		}
+	}
+
+	// Pending mutations held by the blast radius limits were not applied yet
+	held := &corev1alpha1.Mutation{Status: corev1alpha1.MutationStatus{
+		Phase: corev1alpha1.AutoRemediationPending, HeldBy: "outside maintenance windows"}}
+	assert.True(t, Cancel(held, "Cancelled"))
+	assert.Equal(t, corev1alpha1.AutoRemediationAborted, held.Status.Phase)
+}
+
+func Test_RecordFailureSpendsRetryBudget(t *testing.T) {
+	mutation := &corev1alpha1.Mutation{Status: corev1alpha1.MutationStatus{Phase: corev1alpha1.AutoRemediationPhaseInProgress}}
+	cause := errors.New("conflict")
+	for i := 1; i <= 2; i++ {
+		failed, err := recordFailure(mutation, 2, corev1alpha1.MutationFailureApplyFailed, cause)
+		require.NoError(t, err)
+		assert.False(t, failed)
+		assert.Equal(t, i, mutation.Status.Retries)
+		assert.Equal(t, corev1alpha1.AutoRemediationPhaseInProgress, mutation.Status.Phase)
+	}
+	failed, err := recordFailure(mutation, 2, corev1alpha1.MutationFailureApplyFailed, cause)
+	require.NoError(t, err)
+	assert.True(t, failed)
+	assert.Equal(t, corev1alpha1.AutoRemediationFailed, mutation.Status.Phase)
+	assert.Equal(t, corev1alpha1.MutationFailureApplyFailed, mutation.Status.FailureReason)
+}
+
+func Test_RetryBudget(t *testing.T) {
+	assert.Equal(t, DefaultRetryBudget, retryBudget(nil))
+	// Instances created without the CRD default have no budget
+	assert.Equal(t, DefaultRetryBudget, retryBudget(&corev1alpha1.K8sGPT{Spec: corev1alpha1.K8sGPTSpec{
+		AI: &corev1alpha1.AISpec{AutoRemediation: corev1alpha1.AutoRemediation{Enabled: true}}}}))
+	assert.Equal(t, 5, retryBudget(&corev1alpha1.K8sGPT{Spec: corev1alpha1.K8sGPTSpec{AI: &corev1alpha1.AISpec{
+		AutoRemediation: corev1alpha1.AutoRemediation{RetryBudget: 5}}}}))
+}
+
+// reconcileFixture runs the mutation reconciler against a fake client. The registered K8sGPT
+// client connects lazily to a closed port, so backend queries fail without a server.
+type reconcileFixture struct {
+	t          *testing.T
+	client     client.Client
+	recorder   *record.FakeRecorder
+	reconciler *MutationReconciler
+}
+
+func newReconcileFixture(t *testing.T, autoRemediation corev1alpha1.AutoRemediation, objects ...client.Object) *reconcileFixture {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, corev1alpha1.AddToScheme(scheme))
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := listener.Addr().String()
+	require.NoError(t, listener.Close())
+	k8sgptClient, err := kclient.NewClient(address)
+	require.NoError(t, err)
+
+	autoRemediation.Enabled = true
+	if autoRemediation.Resources == nil {
+		// The default of the CRD
+		autoRemediation.Resources = []string{"Pod", "Deployment", "Service", "Ingress"}
+	}
+	k8sgpt := &corev1alpha1.K8sGPT{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "k8sgpt", Name: "k8sgpt"},
+		Spec:       corev1alpha1.K8sGPTSpec{AI: &corev1alpha1.AISpec{AutoRemediation: autoRemediation}},
+	}
+	registry := shared.NewClientRegistry()
+	registry.Register(ktypes.NamespacedName{Namespace: "k8sgpt", Name: "k8sgpt"},
+		types.InterControllerSignal{K8sGPTClient: k8sgptClient, Backend: "openai", K8sGPT: k8sgpt})
+	t.Cleanup(func() { registry.Remove(ktypes.NamespacedName{Namespace: "k8sgpt", Name: "k8sgpt"}) })
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).
+		WithStatusSubresource(&corev1alpha1.Mutation{}).WithInterceptorFuncs(interceptor.Funcs{
+		Patch:  applyAsStrategicMerge.Patch,
+		Create: reviewAccess,
+	}).Build()
+	recorder := record.NewFakeRecorder(100)
+	return &reconcileFixture{
+		t:          t,
+		client:     c,
+		recorder:   recorder,
+		reconciler: &MutationReconciler{Client: c, Scheme: scheme, ClientRegistry: registry, Recorder: recorder},
+	}
+}
+
+// applyAsStrategicMerge emulates server-side apply in the fake client, which does not support
+// it, with a strategic merge patch of the applied fields
+var applyAsStrategicMerge = interceptor.Funcs{
+	Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+		if patch.Type() != ktypes.ApplyPatchType {
+			return c.Patch(ctx, obj, patch, opts...)
+		}
+		data, err := patch.Data(obj)
+		if err != nil {
+			return err
+		}
+		return c.Patch(ctx, obj, client.RawPatch(ktypes.StrategicMergePatchType, data))
+	},
+}
+
+// approver is the only user allowed to approve mutations in the fixture
+const approver = "alex"
+
+// reviewAccess answers subject access reviews, which the fake client cannot evaluate
+func reviewAccess(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+	review, ok := obj.(*authorizationv1.SubjectAccessReview)
+	if !ok {
+		return c.Create(ctx, obj, opts...)
+	}
+	review.Status.Allowed = review.Spec.User == approver && review.Spec.ResourceAttributes.Verb == approveVerb
+	return nil
+}
+
+func (f *reconcileFixture) reconcile(name string) (ctrl.Result, corev1alpha1.Mutation) {
+	key := ktypes.NamespacedName{Namespace: "k8sgpt", Name: name}
+	result, _ := f.reconciler.Reconcile(context.Background(), ctrl.Request{NamespacedName: key})
+	var mutation corev1alpha1.Mutation
+	require.NoError(f.t, f.client.Get(context.Background(), key, &mutation))
+	return result, mutation
+}
+
+func newMutation(name string, phase corev1alpha1.AutoRemediationPhase, spec corev1alpha1.MutationSpec) *corev1alpha1.Mutation {
+	if spec.ResultRef.Name == "" {
+		spec.ResultRef = corev1.ObjectReference{Namespace: "k8sgpt", Name: name}
+	}
+	return &corev1alpha1.Mutation{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "k8sgpt",
+			Name:      name,
+			Labels: map[string]string{
+				"k8sgpts.k8sgpt.ai/name":      "k8sgpt",
+				"k8sgpts.k8sgpt.ai/namespace": "k8sgpt",
+			},
+		},
+		Spec:   spec,
+		Status: corev1alpha1.MutationStatus{Phase: phase},
+	}
+}
+
+func webDeployment(image string) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web"},
+		Spec: appsv1.DeploymentSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "web"}},
+				Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "web", Image: image}}},
+			},
+		},
+	}
+}
+
+func plannedDeployment(t *testing.T, image string) string {
+	obj, err := conversions.ToApplyObject(webDeployment(image))
+	require.NoError(t, err)
+	manifest, err := conversions.ToManifest(obj)
+	require.NoError(t, err)
+	return manifest
+}
+
+var deploymentSpec = corev1alpha1.MutationSpec{
+	ResourceRef:         corev1.ObjectReference{Kind: "Deployment", Namespace: "default", Name: "web"},
+	ResourceGVK:         "apps/v1, Kind=Deployment",
+	OriginConfiguration: "kind: Deployment\napiVersion: apps/v1\n",
+	TargetConfiguration: "kind: Deployment\napiVersion: apps/v1\n",
+	SimilarityScore:     "95.000000",
+}
+
+func Test_ReconcileNotStarted(t *testing.T) {
+	f := newReconcileFixture(t, corev1alpha1.AutoRemediation{RetryBudget: 1},
+		newMutation("planned", corev1alpha1.AutoRemediationPhaseNotStarted, deploymentSpec),
+		newMutation("query", corev1alpha1.AutoRemediationPhaseNotStarted, corev1alpha1.MutationSpec{}),
+		&corev1alpha1.Result{ObjectMeta: metav1.ObjectMeta{Namespace: "k8sgpt", Name: "query"}},
+	)
+
+	_, mutation := f.reconcile("planned")
+	assert.Equal(t, corev1alpha1.AutoRemediationPhaseInProgress, mutation.Status.Phase)
+
+	// The backend is unreachable, the first failure is retried and the second spends the budget
+	_, mutation = f.reconcile("query")
+	assert.Equal(t, corev1alpha1.AutoRemediationPhaseNotStarted, mutation.Status.Phase)
+	assert.Equal(t, 1, mutation.Status.Retries)
+	_, mutation = f.reconcile("query")
+	assert.Equal(t, corev1alpha1.AutoRemediationFailed, mutation.Status.Phase)
+	assert.Equal(t, corev1alpha1.MutationFailureQueryFailed, mutation.Status.FailureReason)
+}
+
+func Test_ReconcileNotStartedRequiringApproval(t *testing.T) {
+	f := newReconcileFixture(t, corev1alpha1.AutoRemediation{ApprovalMode: corev1alpha1.ApprovalModeManual},
+		newMutation("planned", corev1alpha1.AutoRemediationPhaseNotStarted, deploymentSpec))
+
+	_, mutation := f.reconcile("planned")
+	assert.Equal(t, corev1alpha1.AutoRemediationAwaitingApproval, mutation.Status.Phase)
+}
+
+func Test_ReconcileAwaitingApproval(t *testing.T) {
+	plannedAt := metav1.NewTime(time.Now().Add(-time.Hour).Truncate(time.Second))
+	awaiting := func(name, decision, reviewer string, decidedAt time.Time) *corev1alpha1.Mutation {
+		mutation := newMutation(name, corev1alpha1.AutoRemediationAwaitingApproval, deploymentSpec)
+		mutation.Status.PlannedConfiguration = plannedDeployment(t, "nginx:1.1")
+		mutation.Status.PlannedAt = &plannedAt
+		if decision != "" {
+			mutation.Annotations = map[string]string{
+				corev1alpha1.ApprovalAnnotation: decision,
+				corev1alpha1.ReviewerAnnotation: reviewer,
+			}
+			at := metav1.NewTime(decidedAt)
+			mutation.ManagedFields = []metav1.ManagedFieldsEntry{{Manager: "kubectl-annotate", Time: &at,
+				Operation: metav1.ManagedFieldsOperationUpdate, FieldsV1: &metav1.FieldsV1{
+					Raw: []byte(`{"f:metadata":{"f:annotations":{"f:k8sgpt.ai/approval":{},"f:k8sgpt.ai/reviewer":{}}}}`)}}}
+		}
+		return mutation
+	}
+	risky := awaiting("risky", "approved", approver, plannedAt.Add(time.Minute))
+	risky.Spec.SimilarityScore = "50.000000"
+	// The approval admission policy as config/default installs it
+	labels := map[string]string{approvalPolicyLabel: "true"}
+	policy := &admissionregistrationv1.ValidatingAdmissionPolicy{ObjectMeta: metav1.ObjectMeta{
+		Name: "k8sgpt-operator-k8sgpt-mutation-approval", Labels: labels}}
+	binding := &admissionregistrationv1.ValidatingAdmissionPolicyBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: "k8sgpt-operator-k8sgpt-mutation-approval", Labels: labels},
+		Spec: admissionregistrationv1.ValidatingAdmissionPolicyBindingSpec{PolicyName: policy.Name,
+			ValidationActions: []admissionregistrationv1.ValidationAction{admissionregistrationv1.Deny}},
+	}
+	f := newReconcileFixture(t, corev1alpha1.AutoRemediation{ApprovalMode: corev1alpha1.ApprovalModeManual,
+		SimilarityRequirement: "90"}, policy, binding,
+		awaiting("undecided", "", "", time.Time{}),
+		awaiting("approved", "approved", approver, plannedAt.Add(time.Minute)),
+		awaiting("rejected", "rejected", approver, plannedAt.Add(time.Minute)),
+		awaiting("anonymous", "approved", "", plannedAt.Add(time.Minute)),
+		awaiting("stale", "approved", approver, plannedAt.Add(-time.Minute)),
+		awaiting("unauthorized", "approved", "sam", plannedAt.Add(time.Minute)),
+		risky,
+	)
+
+	_, mutation := f.reconcile("undecided")
+	assert.Equal(t, corev1alpha1.AutoRemediationAwaitingApproval, mutation.Status.Phase)
+	assert.Nil(t, mutation.Status.Approval)
+
+	_, mutation = f.reconcile("approved")
+	assert.Equal(t, corev1alpha1.AutoRemediationPhaseInProgress, mutation.Status.Phase)
+	require.NotNil(t, mutation.Status.Approval)
+	assert.Equal(t, corev1alpha1.ApprovalDecisionApproved, mutation.Status.Approval.Decision)
+	assert.Equal(t, approver, mutation.Status.Approval.Reviewer)
+
+	_, mutation = f.reconcile("rejected")
+	assert.Equal(t, corev1alpha1.AutoRemediationAborted, mutation.Status.Phase)
+
+	// Decisions without a reviewer, made before the plan or by a user who may not approve are ignored
+	for name, reason := range map[string]string{
+		"anonymous":    "names no reviewer",
+		"stale":        "before the current plan",
+		"unauthorized": "sam may not approve",
+	} {
+		_, mutation = f.reconcile(name)
+		assert.Equal(t, corev1alpha1.AutoRemediationAwaitingApproval, mutation.Status.Phase, name)
+		assert.Nil(t, mutation.Status.Approval, name)
+		assert.Contains(t, mutation.Status.Message, reason, name)
+	}
+
+	// An approval does not supersede the similarity requirement
+	_, mutation = f.reconcile("risky")
+	assert.Equal(t, corev1alpha1.AutoRemediationAborted, mutation.Status.Phase)
+	assert.Nil(t, mutation.Status.Approval)
+
+	// Without the admission policy nothing checks the reviewer, decisions are not honored
+	f = newReconcileFixture(t, corev1alpha1.AutoRemediation{ApprovalMode: corev1alpha1.ApprovalModeManual},
+		awaiting("approved", "approved", approver, plannedAt.Add(time.Minute)))
+	_, mutation = f.reconcile("approved")
+	assert.Equal(t, corev1alpha1.AutoRemediationAwaitingApproval, mutation.Status.Phase)
+	assert.Nil(t, mutation.Status.Approval)
+	assert.Contains(t, mutation.Status.Message, "admission policy is not installed")
+}
+
+func Test_ReconcileInProgress(t *testing.T) {
+	risky := deploymentSpec
+	risky.SimilarityScore = "50.000000"
+	unsupported := deploymentSpec
+	unsupported.ResourceRef = corev1.ObjectReference{Kind: "ConfigMap", Namespace: "default", Name: "web"}
+	unsupported.ResourceGVK = "/v1, Kind=ConfigMap"
+	unsupported.TargetConfiguration = "kind: ConfigMap\napiVersion: v1\n"
+	invalid := deploymentSpec
+	invalid.TargetConfiguration = "- not\n- an object\n"
+
+	planned := func(name string, spec corev1alpha1.MutationSpec, image string) *corev1alpha1.Mutation {
+		mutation := newMutation(name, corev1alpha1.AutoRemediationPhaseInProgress, spec)
+		mutation.Status.PlannedConfiguration = plannedDeployment(t, image)
+		return mutation
+	}
+	// The planned deployment does not exist, so applying it fails
+	missing := planned("missing", deploymentSpec, "nginx:1.1")
+	missing.Status.PlannedConfiguration = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: absent
+  namespace: default
+`
+
+	f := newReconcileFixture(t, corev1alpha1.AutoRemediation{SimilarityRequirement: "90", RetryBudget: 1},
+		webDeployment("nginx:1.0"),
+		planned("risky", risky, "nginx:broken"),
+		planned("apply", deploymentSpec, "nginx:1.1"),
+		missing,
+		newMutation("unsupported", corev1alpha1.AutoRemediationPhaseInProgress, unsupported),
+		newMutation("invalid", corev1alpha1.AutoRemediationPhaseInProgress, invalid),
+	)
+
+	// A mutation that does not meet the similarity requirement is aborted and not applied
+	result, mutation := f.reconcile("risky")
+	assert.Equal(t, corev1alpha1.AutoRemediationAborted, mutation.Status.Phase)
+	assert.Equal(t, ctrl.Result{}, result)
+	var deployment appsv1.Deployment
+	require.NoError(t, f.client.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: "web"}, &deployment))
+	assert.Equal(t, "nginx:1.0", deployment.Spec.Template.Spec.Containers[0].Image)
+
+	_, mutation = f.reconcile("apply")
+	assert.Equal(t, corev1alpha1.AutoRemediationPhaseCompleted, mutation.Status.Phase)
+	assert.NotNil(t, mutation.Status.AppliedAt)
+	assert.NotEmpty(t, mutation.Status.PreviousConfiguration)
+	require.NoError(t, f.client.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: "web"}, &deployment))
+	assert.Equal(t, "nginx:1.1", deployment.Spec.Template.Spec.Containers[0].Image)
+
+	_, mutation = f.reconcile("missing")
+	assert.Equal(t, corev1alpha1.AutoRemediationPhaseInProgress, mutation.Status.Phase)
+	assert.Equal(t, 1, mutation.Status.Retries)
+	_, mutation = f.reconcile("missing")
+	assert.Equal(t, corev1alpha1.AutoRemediationFailed, mutation.Status.Phase)
+	assert.Equal(t, corev1alpha1.MutationFailureApplyFailed, mutation.Status.FailureReason)
+
+	_, mutation = f.reconcile("unsupported")
+	assert.Equal(t, corev1alpha1.AutoRemediationFailed, mutation.Status.Phase)
+	assert.Equal(t, corev1alpha1.MutationFailureUnsupportedKind, mutation.Status.FailureReason)
+
+	_, mutation = f.reconcile("invalid")
+	assert.Equal(t, corev1alpha1.AutoRemediationFailed, mutation.Status.Phase)
+	assert.Equal(t, corev1alpha1.MutationFailureGuardrailViolation, mutation.Status.FailureReason)
+	assert.Len(t, mutation.Status.GuardrailViolations, 1)
+}
+
+func Test_ReconcileRefusedByGuardrails(t *testing.T) {
+	renamed := deploymentSpec
+	renamed.TargetConfiguration = "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: other\n  namespace: default\n"
+	privileged := newMutation("privileged", corev1alpha1.AutoRemediationPhaseInProgress, deploymentSpec)
+	escalated := webDeployment("nginx:1.1")
+	escalated.Spec.Template.Spec.HostNetwork = true
+	obj, err := conversions.ToApplyObject(escalated)
+	require.NoError(t, err)
+	privileged.Status.PlannedConfiguration, err = conversions.ToManifest(obj)
+	require.NoError(t, err)
+
+	f := newReconcileFixture(t, corev1alpha1.AutoRemediation{RetryBudget: 3},
+		webDeployment("nginx:1.0"),
+		newMutation("renamed", corev1alpha1.AutoRemediationPhaseInProgress, renamed),
+		privileged,
+	)
+
+	// A manifest naming another object is never applied
+	_, mutation := f.reconcile("renamed")
+	assert.Equal(t, corev1alpha1.AutoRemediationFailed, mutation.Status.Phase)
+	assert.Equal(t, corev1alpha1.MutationFailureGuardrailViolation, mutation.Status.FailureReason)
+	assert.Equal(t, []string{`name changed from "web" to "other"`}, mutation.Status.GuardrailViolations)
+
+	// Violations are not retried, the deployment is left as it was
+	_, mutation = f.reconcile("privileged")
+	assert.Equal(t, corev1alpha1.AutoRemediationFailed, mutation.Status.Phase)
+	assert.Equal(t, 0, mutation.Status.Retries)
+	assert.Equal(t, []string{"hostNetwork enabled"}, mutation.Status.GuardrailViolations)
+	var deployment appsv1.Deployment
+	require.NoError(t, f.client.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: "web"}, &deployment))
+	assert.Equal(t, "nginx:1.0", deployment.Spec.Template.Spec.Containers[0].Image)
+}
+
+func Test_ReconcileBlockedByPolicies(t *testing.T) {
+	planned := func(name, image string) *corev1alpha1.Mutation {
+		mutation := newMutation(name, corev1alpha1.AutoRemediationPhaseInProgress, deploymentSpec)
+		mutation.Status.PlannedConfiguration = plannedDeployment(t, image)
+		return mutation
+	}
+	f := newReconcileFixture(t, corev1alpha1.AutoRemediation{RetryBudget: 3, Policies: []corev1alpha1.RemediationPolicy{{
+		Name:       "pinned-nginx",
+		Expression: `newObject.spec.template.spec.containers.all(c, c.image.startsWith("nginx:1."))`,
+		Message:    "only nginx 1.x may be deployed",
+	}}},
+		webDeployment("nginx:1.0"),
+		planned("allowed", "nginx:1.1"),
+		planned("blocked", "nginx:2.0"),
+	)
+
+	_, mutation := f.reconcile("blocked")
+	assert.Equal(t, corev1alpha1.AutoRemediationFailed, mutation.Status.Phase)
+	assert.Equal(t, corev1alpha1.MutationFailurePolicyViolation, mutation.Status.FailureReason)
+	assert.Equal(t, []corev1alpha1.PolicyViolation{{Policy: "pinned-nginx", Message: "only nginx 1.x may be deployed"}},
+		mutation.Status.PolicyViolations)
+	var deployment appsv1.Deployment
+	require.NoError(t, f.client.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: "web"}, &deployment))
+	assert.Equal(t, "nginx:1.0", deployment.Spec.Template.Spec.Containers[0].Image)
+
+	_, mutation = f.reconcile("allowed")
+	assert.Equal(t, corev1alpha1.AutoRemediationPhaseCompleted, mutation.Status.Phase)
+	assert.Empty(t, mutation.Status.PolicyViolations)
+}
+
+func Test_ReconcileOutOfScopeTarget(t *testing.T) {
+	// The result is about a pod, the fix is written to its deployment
+	podSpec := corev1alpha1.MutationSpec{
+		ResourceRef:         corev1.ObjectReference{Kind: "Pod", Namespace: "default", Name: "web-5d4f-x2"},
+		ResourceGVK:         "/v1, Kind=Pod",
+		OriginConfiguration: "kind: Pod\napiVersion: v1\n",
+		TargetConfiguration: "kind: Pod\napiVersion: v1\n",
+		SimilarityScore:     "95.000000",
+	}
+	planned := newMutation("web-5d4f-x2", corev1alpha1.AutoRemediationPhaseInProgress, podSpec)
+	planned.Status.PlannedConfiguration = plannedDeployment(t, "nginx:1.1")
+	optedOut := webDeployment("nginx:1.0")
+	optedOut.Annotations = map[string]string{corev1alpha1.AutoRemediationAnnotation: corev1alpha1.AutoRemediationDisabled}
+
+	for name, fixture := range map[string]struct {
+		autoRemediation corev1alpha1.AutoRemediation
+		deployment      *appsv1.Deployment
+	}{
+		"deployment opted out":    {deployment: optedOut},
+		"deployment not in scope": {autoRemediation: corev1alpha1.AutoRemediation{Resources: []string{"Pod"}}, deployment: webDeployment("nginx:1.0")},
+	} {
+		f := newReconcileFixture(t, fixture.autoRemediation, fixture.deployment.DeepCopy(), planned.DeepCopy())
+		_, mutation := f.reconcile(planned.Name)
+		assert.Equal(t, corev1alpha1.AutoRemediationFailed, mutation.Status.Phase, name)
+		assert.Equal(t, corev1alpha1.MutationFailureOutOfScope, mutation.Status.FailureReason, name)
+		var deployment appsv1.Deployment
+		require.NoError(t, f.client.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: "web"}, &deployment))
+		assert.Equal(t, "nginx:1.0", deployment.Spec.Template.Spec.Containers[0].Image, name)
+	}
+}
+
+func Test_ReconcileCompletedAndPending(t *testing.T) {
+	f := newReconcileFixture(t, corev1alpha1.AutoRemediation{},
+		newMutation("unresolved", corev1alpha1.AutoRemediationPhaseCompleted, corev1alpha1.MutationSpec{}),
+		newMutation("resolved", corev1alpha1.AutoRemediationPhaseCompleted, corev1alpha1.MutationSpec{}),
+		newMutation("pending", corev1alpha1.AutoRemediationPending, corev1alpha1.MutationSpec{}),
+		newMutation("merged", corev1alpha1.AutoRemediationPhaseCompleted, corev1alpha1.MutationSpec{
+			ResultRefs: []corev1.ObjectReference{{Namespace: "k8sgpt", Name: "merged"}, {Namespace: "k8sgpt", Name: "web-pod"}}}),
+		&corev1alpha1.Result{ObjectMeta: metav1.ObjectMeta{Namespace: "k8sgpt", Name: "unresolved"}},
+		&corev1alpha1.Result{ObjectMeta: metav1.ObjectMeta{Namespace: "k8sgpt", Name: "web-pod"}},
+	)
+
+	_, mutation := f.reconcile("unresolved")
+	assert.Equal(t, corev1alpha1.AutoRemediationPending, mutation.Status.Phase)
+
+	_, mutation = f.reconcile("resolved")
+	assert.Equal(t, corev1alpha1.AutoRemediationPhaseSuccessful, mutation.Status.Phase)
+
+	_, mutation = f.reconcile("pending")
+	assert.Equal(t, corev1alpha1.AutoRemediationPhaseSuccessful, mutation.Status.Phase)
+
+	// A mutation merged from several results is only successful once all of them are gone
+	_, mutation = f.reconcile("merged")
+	assert.Equal(t, corev1alpha1.AutoRemediationPending, mutation.Status.Phase)
+	require.NoError(t, f.client.Delete(context.Background(),
+		&corev1alpha1.Result{ObjectMeta: metav1.ObjectMeta{Namespace: "k8sgpt", Name: "web-pod"}}))
+	_, mutation = f.reconcile("merged")
+	assert.Equal(t, corev1alpha1.AutoRemediationPhaseSuccessful, mutation.Status.Phase)
+}
+
+func Test_ReconcileTerminalPhasesAreNotRequeued(t *testing.T) {
+	var objects []client.Object
+	for _, phase := range allPhases {
+		if IsTerminal(phase) {
+			objects = append(objects, newMutation(strings.ToLower(phase.String()), phase, deploymentSpec))
+		}
+	}
+	f := newReconcileFixture(t, corev1alpha1.AutoRemediation{}, objects...)
+	for _, obj := range objects {
+		result, mutation := f.reconcile(obj.GetName())
+		assert.Equal(t, ctrl.Result{}, result)
+		assert.Equal(t, obj.(*corev1alpha1.Mutation).Status.Phase, mutation.Status.Phase)
+	}
+}
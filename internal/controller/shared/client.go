@@ -3,24 +3,102 @@ package shared
 import (
 	"sync"
 
-	rpc "buf.build/gen/go/k8sgpt-ai/k8sgpt/grpc/go/schema/v1/schemav1grpc"
+	"github.com/k8sgpt-ai/k8sgpt-operator/internal/controller/types"
+	kclient "github.com/k8sgpt-ai/k8sgpt-operator/pkg/client"
+	ktypes "k8s.io/apimachinery/pkg/types"
 )
 
-var (
-	serverQueryClient     *rpc.ServerQueryServiceClient
-	serverQueryClientLock sync.RWMutex
-)
+// ClientRegistry holds the K8sGPT server connection of every K8sGPT instance, keyed by
+// the namespace/name of the instance. The K8sGPT controller registers a signal once the
+// server is reachable and the Mutation controller resolves the owning instance through it,
+// so several instances with different backends can coexist.
+type ClientRegistry struct {
+	lock    sync.Mutex
+	entries map[ktypes.NamespacedName]types.InterControllerSignal
+	// users counts the holders of each acquired client
+	users map[*kclient.Client]int
+	// retired are the clients that were replaced or removed while in use, they are closed
+	// once their last user releases them
+	retired map[*kclient.Client]bool
+}
+
+// NewClientRegistry creates an empty ClientRegistry.
+func NewClientRegistry() *ClientRegistry {
+	return &ClientRegistry{
+		entries: make(map[ktypes.NamespacedName]types.InterControllerSignal),
+		users:   make(map[*kclient.Client]int),
+		retired: make(map[*kclient.Client]bool),
+	}
+}
+
+// Register stores the signal for a K8sGPT instance. A previously registered client
+// that is being replaced by a different one is closed once no one uses it.
+func (r *ClientRegistry) Register(key ktypes.NamespacedName, signal types.InterControllerSignal) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if previous, ok := r.entries[key]; ok && previous.K8sGPTClient != signal.K8sGPTClient {
+		r.retire(previous.K8sGPTClient)
+	}
+	delete(r.retired, signal.K8sGPTClient)
+	r.entries[key] = signal
+}
+
+// Get returns the signal registered for a K8sGPT instance. Callers outside of the
+// reconcile of the instance must Acquire the signal to use its client.
+func (r *ClientRegistry) Get(key ktypes.NamespacedName) (types.InterControllerSignal, bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	signal, ok := r.entries[key]
+	return signal, ok
+}
+
+// Acquire returns the signal registered for a K8sGPT instance and keeps its client open
+// until the returned release func is called. Release is safe to call when nothing is found.
+func (r *ClientRegistry) Acquire(key ktypes.NamespacedName) (types.InterControllerSignal, func(), bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	signal, ok := r.entries[key]
+	if !ok || signal.K8sGPTClient == nil {
+		return signal, func() {}, ok
+	}
+	c := signal.K8sGPTClient
+	r.users[c]++
+	var once sync.Once
+	return signal, func() { once.Do(func() { r.release(c) }) }, true
+}
+
+// Remove drops the signal of a K8sGPT instance and closes its client once no one uses it.
+func (r *ClientRegistry) Remove(key ktypes.NamespacedName) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if previous, ok := r.entries[key]; ok {
+		r.retire(previous.K8sGPTClient)
+	}
+	delete(r.entries, key)
+}
 
-// SetServerQueryClient sets the shared ServerQueryClient.
-func SetServerQueryClient(client *rpc.ServerQueryServiceClient) {
-	serverQueryClientLock.Lock()
-	defer serverQueryClientLock.Unlock()
-	serverQueryClient = client
+// retire closes a client that is no longer registered, or defers it to its last release.
+// The lock must be held.
+func (r *ClientRegistry) retire(c *kclient.Client) {
+	if c == nil {
+		return
+	}
+	if r.users[c] > 0 {
+		r.retired[c] = true
+		return
+	}
+	_ = c.Close()
 }
 
-// GetServerQueryClient gets the shared ServerQueryClient.
-func GetServerQueryClient() *rpc.ServerQueryServiceClient {
-	serverQueryClientLock.RLock()
-	defer serverQueryClientLock.RUnlock()
-	return serverQueryClient
+func (r *ClientRegistry) release(c *kclient.Client) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.users[c]--; r.users[c] > 0 {
+		return
+	}
+	delete(r.users, c)
+	if r.retired[c] {
+		delete(r.retired, c)
+		_ = c.Close()
+	}
 }
@@ -0,0 +1,91 @@
+package shared
+
+import (
+	"testing"
+
+	"github.com/k8sgpt-ai/k8sgpt-operator/internal/controller/types"
+	kclient "github.com/k8sgpt-ai/k8sgpt-operator/pkg/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/connectivity"
+	ktypes "k8s.io/apimachinery/pkg/types"
+)
+
+func newTestClient(t *testing.T) *kclient.Client {
+	c, err := kclient.NewClient("localhost:8080")
+	require.NoError(t, err)
+	return c
+}
+
+func Test_ClientRegistryIsKeyedPerInstance(t *testing.T) {
+	registry := NewClientRegistry()
+	first := ktypes.NamespacedName{Namespace: "default", Name: "first"}
+	second := ktypes.NamespacedName{Namespace: "default", Name: "second"}
+
+	registry.Register(first, types.InterControllerSignal{K8sGPTClient: newTestClient(t), Backend: "openai"})
+	registry.Register(second, types.InterControllerSignal{K8sGPTClient: newTestClient(t), Backend: "amazonbedrock"})
+
+	signal, ok := registry.Get(first)
+	require.True(t, ok)
+	assert.Equal(t, "openai", signal.Backend)
+
+	signal, ok = registry.Get(second)
+	require.True(t, ok)
+	assert.Equal(t, "amazonbedrock", signal.Backend)
+
+	_, ok = registry.Get(ktypes.NamespacedName{Namespace: "other", Name: "first"})
+	assert.False(t, ok)
+}
+
+func Test_ClientRegistryClosesReplacedClients(t *testing.T) {
+	registry := NewClientRegistry()
+	key := ktypes.NamespacedName{Namespace: "default", Name: "k8sgpt"}
+	original := newTestClient(t)
+
+	registry.Register(key, types.InterControllerSignal{K8sGPTClient: original})
+	// Re-registering the same client must keep it open
+	registry.Register(key, types.InterControllerSignal{K8sGPTClient: original})
+	assert.NotEqual(t, connectivity.Shutdown, original.Conn.GetState())
+
+	replacement := newTestClient(t)
+	registry.Register(key, types.InterControllerSignal{K8sGPTClient: replacement})
+	assert.Equal(t, connectivity.Shutdown, original.Conn.GetState())
+
+	registry.Remove(key)
+	assert.Equal(t, connectivity.Shutdown, replacement.Conn.GetState())
+	_, ok := registry.Get(key)
+	assert.False(t, ok)
+}
+
+func Test_ClientRegistryDrainsClientsInUse(t *testing.T) {
+	registry := NewClientRegistry()
+	key := ktypes.NamespacedName{Namespace: "default", Name: "k8sgpt"}
+	original := newTestClient(t)
+	registry.Register(key, types.InterControllerSignal{K8sGPTClient: original})
+
+	signal, release, ok := registry.Acquire(key)
+	require.True(t, ok)
+	assert.Same(t, original, signal.K8sGPTClient)
+	_, other, _ := registry.Acquire(key)
+
+	// Replacing a client in use keeps it open until its last user releases it
+	replacement := newTestClient(t)
+	registry.Register(key, types.InterControllerSignal{K8sGPTClient: replacement})
+	assert.NotEqual(t, connectivity.Shutdown, original.Conn.GetState())
+	release()
+	release()
+	assert.NotEqual(t, connectivity.Shutdown, original.Conn.GetState())
+	other()
+	assert.Equal(t, connectivity.Shutdown, original.Conn.GetState())
+
+	// Removing an instance whose client is in use defers the close as well
+	_, release, _ = registry.Acquire(key)
+	registry.Remove(key)
+	assert.NotEqual(t, connectivity.Shutdown, replacement.Conn.GetState())
+	release()
+	assert.Equal(t, connectivity.Shutdown, replacement.Conn.GetState())
+
+	_, release, ok = registry.Acquire(key)
+	assert.False(t, ok)
+	release()
+}
@@ -3,8 +3,12 @@ package types
 import corev1 "k8s.io/api/core/v1"
 
 type EligibleResource struct {
-	ResultRef           corev1.ObjectReference
-	ObjectRef           corev1.ObjectReference
+	ResultRef corev1.ObjectReference
+	// ResultRefs are the results of every resource merged into this one, including ResultRef
+	ResultRefs []corev1.ObjectReference
+	ObjectRef  corev1.ObjectReference
+	// Workload is the top level owner of the object, or the object itself
+	Workload            corev1.ObjectReference
 	GVK                 string
 	OriginConfiguration string
 }
@@ -1,10 +1,30 @@
 package util
 
 import (
-	"github.com/agnivade/levenshtein"
+	"encoding/json"
 	"strings"
+
+	"gomodules.xyz/jsonpatch/v2"
+	"sigs.k8s.io/yaml"
 )
 
+// MaxRiskScore is the risk score of a change that is as risky as it gets, or cannot be compared
+const MaxRiskScore = 100
+
+// sensitivePathWeights weighs changes to fields that alter what a workload runs or can access.
+// Any other changed field weighs 1.
+var sensitivePathWeights = map[string]int{
+	"image":              10,
+	"command":            20,
+	"volumes":            20,
+	"securityContext":    25,
+	"serviceAccountName": 25,
+	"hostNetwork":        50,
+}
+
+// fields populated by the API server, they are not part of a change
+var serverMetadataFields = []string{"resourceVersion", "uid", "generation", "creationTimestamp", "managedFields", "selfLink"}
+
 func IsStringInSlice(a string, b []string) bool {
 	if len(b) == 0 {
 		return false
@@ -17,12 +37,75 @@ func IsStringInSlice(a string, b []string) bool {
 	return false
 }
 
-func SimilarityScore(text1 string, text2 string) float64 {
-	// Calculate the Levenshtein distance.
-	distance := levenshtein.ComputeDistance(text1, text2)
-	// Calculate the maximum length between the two strings.
-	maxLength := max(len(text1), len(text2))
-	// Calculate the similarity score as a percentage.
-	similarity := (1.0 - float64(distance)/float64(maxLength)) * 100.0
-	return similarity
+// StructuralDiff compares the origin and target manifests as objects rather than text.
+// It returns the JSON patch that turns origin into target and a risk score between 0 and
+// MaxRiskScore, where every changed field adds its sensitive path weight or 1.
+func StructuralDiff(origin string, target string) (string, int, error) {
+	originJSON, err := normalizedJSON(origin)
+	if err != nil {
+		return "", MaxRiskScore, err
+	}
+	targetJSON, err := normalizedJSON(target)
+	if err != nil {
+		return "", MaxRiskScore, err
+	}
+	operations, err := jsonpatch.CreatePatch(originJSON, targetJSON)
+	if err != nil {
+		return "", MaxRiskScore, err
+	}
+	patch, err := json.Marshal(operations)
+	if err != nil {
+		return "", MaxRiskScore, err
+	}
+	risk := 0
+	for _, operation := range operations {
+		risk += operationRisk(operation)
+	}
+	return string(patch), min(risk, MaxRiskScore), nil
+}
+
+// SimilarityFromRisk expresses a risk score as the similarity percentage SimilarityRequirement is compared with
+func SimilarityFromRisk(risk int) float64 {
+	return float64(MaxRiskScore - min(max(risk, 0), MaxRiskScore))
+}
+
+func normalizedJSON(manifest string) ([]byte, error) {
+	var obj map[string]interface{}
+	if err := yaml.Unmarshal([]byte(manifest), &obj); err != nil {
+		return nil, err
+	}
+	delete(obj, "status")
+	if metadata, ok := obj["metadata"].(map[string]interface{}); ok {
+		for _, field := range serverMetadataFields {
+			delete(metadata, field)
+		}
+	}
+	return json.Marshal(obj)
+}
+
+// operationRisk weighs an operation by the most sensitive segment of its path plus any sensitive
+// fields inside the value it adds or replaces, such as a whole new container
+func operationRisk(operation jsonpatch.Operation) int {
+	risk := 1
+	for _, segment := range strings.Split(operation.Path, "/") {
+		if weight, ok := sensitivePathWeights[segment]; ok && weight > risk {
+			risk = weight
+		}
+	}
+	return risk + valueRisk(operation.Value)
+}
+
+func valueRisk(value interface{}) int {
+	risk := 0
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, nested := range v {
+			risk += sensitivePathWeights[key] + valueRisk(nested)
+		}
+	case []interface{}:
+		for _, nested := range v {
+			risk += valueRisk(nested)
+		}
+	}
+	return risk
 }
@@ -0,0 +1,97 @@
+package util
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gomodules.xyz/jsonpatch/v2"
+)
+
+const originDeployment = `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  resourceVersion: "42"
+spec:
+  replicas: 1
+  template:
+    spec:
+      containers:
+      - name: web
+        image: nginx:1.0
+status:
+  replicas: 1
+`
+
+func Test_StructuralDiffIgnoresOrderingAndServerFields(t *testing.T) {
+	reordered := `
+kind: Deployment
+apiVersion: apps/v1
+spec:
+  template:
+    spec:
+      containers:
+      - image: nginx:1.0
+        name: web
+  replicas: 1
+metadata:
+  name: web
+`
+	patch, risk, err := StructuralDiff(originDeployment, reordered)
+	require.NoError(t, err)
+	assert.Equal(t, "[]", patch)
+	assert.Zero(t, risk)
+	assert.Equal(t, 100.0, SimilarityFromRisk(risk))
+}
+
+func Test_StructuralDiffWeighsSensitivePaths(t *testing.T) {
+	imageChange := `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 2
+  template:
+    spec:
+      containers:
+      - name: web
+        image: nginx:1.1
+`
+	patch, risk, err := StructuralDiff(originDeployment, imageChange)
+	require.NoError(t, err)
+	var operations []jsonpatch.Operation
+	require.NoError(t, json.Unmarshal([]byte(patch), &operations))
+	assert.Len(t, operations, 2)
+	assert.Equal(t, 11, risk, "image weighs 10 and replicas 1")
+
+	hostNetwork := `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 1
+  template:
+    spec:
+      hostNetwork: true
+      containers:
+      - name: web
+        image: nginx:1.0
+        securityContext:
+          privileged: true
+`
+	_, risk, err = StructuralDiff(originDeployment, hostNetwork)
+	require.NoError(t, err)
+	assert.Equal(t, 75, risk)
+	assert.Equal(t, 25.0, SimilarityFromRisk(risk))
+}
+
+func Test_StructuralDiffUnparseableTarget(t *testing.T) {
+	_, risk, err := StructuralDiff(originDeployment, "{null}")
+	assert.Error(t, err)
+	assert.Equal(t, MaxRiskScore, risk)
+}
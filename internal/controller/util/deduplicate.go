@@ -1,47 +1,123 @@
 package util
 
 import (
+	"context"
+	"sort"
+
 	"github.com/go-logr/logr"
 	"github.com/k8sgpt-ai/k8sgpt-operator/internal/controller/types"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
-func Deduplicate(input []types.EligibleResource, log logr.Logger) []types.EligibleResource {
-	var eligibleResource []types.EligibleResource
-	var ownedResources = make(map[string]types.EligibleResource)
-	for _, resource := range input {
-		switch resource.ObjectRef.Kind {
-		default:
-			eligibleResource = append(eligibleResource, resource)
-		case "Pod":
-			// Get the object and look for owner references
-			obj, err := FromConfig(FromObjectConfig{
-				Kind:      resource.ObjectRef.Kind,
-				GvkStr:    resource.GVK,
-				Config:    resource.OriginConfiguration,
-				Name:      resource.ObjectRef.Name,
-				Namespace: resource.ObjectRef.Namespace,
-			})
-			if err != nil {
-				log.Error(err, "error deduplicating object")
-				continue
-			}
-			ownerReferences := obj.GetOwnerReferences()
-			if len(ownerReferences) == 0 {
-				eligibleResource = append(eligibleResource, resource)
-				continue
-			}
-			// Check if the owner is in the incoming results list
-			for _, owner := range ownerReferences {
-				if _, ok := ownedResources[owner.Name]; !ok {
-					ownedResources[owner.Name] = resource
-				}
-			}
+// maxOwnerDepth bounds the owner chains that are followed, e.g. Pod→Job→CronJob is two deep
+const maxOwnerDepth = 5
+
+// OwnerChain follows the controller references of obj and returns its controllers from the
+// closest to the top level object, e.g. the ReplicaSet and the Deployment of a Pod. When an
+// owner cannot be read the chain ends with its reference and the error is returned with it.
+func OwnerChain(ctx context.Context, c client.Reader, obj client.Object) ([]corev1.ObjectReference, error) {
+	var chain []corev1.ObjectReference
+	current := obj
+	for range maxOwnerDepth {
+		controller := metav1.GetControllerOfNoCopy(current)
+		if controller == nil {
+			break
 		}
+		chain = append(chain, corev1.ObjectReference{APIVersion: controller.APIVersion, Kind: controller.Kind,
+			Namespace: obj.GetNamespace(), Name: controller.Name, UID: controller.UID})
+		gv, err := schema.ParseGroupVersion(controller.APIVersion)
+		if err != nil {
+			return chain, err
+		}
+		owner := &unstructured.Unstructured{}
+		owner.SetGroupVersionKind(gv.WithKind(controller.Kind))
+		if err := c.Get(ctx, client.ObjectKey{Namespace: obj.GetNamespace(), Name: controller.Name}, owner); err != nil {
+			return chain, err
+		}
+		current = owner
+	}
+	return chain, nil
+}
 
+// WorkloadKey identifies an object by namespace and UID, objects without a UID fall back to
+// their kind, namespace and name
+func WorkloadKey(ref corev1.ObjectReference) string {
+	if ref.UID != "" {
+		return ref.Namespace + "/" + string(ref.UID)
 	}
-	for _, resource := range ownedResources {
-		eligibleResource = append(eligibleResource, resource)
+	return ref.Kind + "/" + ref.Namespace + "/" + ref.Name
+}
+
+// SameWorkload reports whether both references are to the same object. The UIDs are only
+// compared when both references have one, references recorded without one match by name.
+func SameWorkload(a, b corev1.ObjectReference) bool {
+	if a.UID != "" && b.UID != "" {
+		return a.Namespace == b.Namespace && a.UID == b.UID
 	}
+	return a.Kind == b.Kind && a.Namespace == b.Namespace && a.Name == b.Name
+}
 
-	return eligibleResource
+// Deduplicate merges the eligible resources whose objects are controlled by the same top level
+// object, e.g. the pods of a Deployment and the Deployment itself, so that they produce a single
+// mutation. The merged resource is the one closest to the top level object, ties go to the first
+// result by name, and it references the results of every resource it was merged from.
+func Deduplicate(ctx context.Context, c client.Reader, input []types.EligibleResource,
+	log logr.Logger) []types.EligibleResource {
+	type workload struct {
+		resource types.EligibleResource
+		depth    int
+	}
+	workloads := make(map[string]*workload)
+	var keys []string
+	for _, resource := range input {
+		obj, err := FromConfig(FromObjectConfig{
+			Kind:      resource.ObjectRef.Kind,
+			GvkStr:    resource.GVK,
+			Config:    resource.OriginConfiguration,
+			Name:      resource.ObjectRef.Name,
+			Namespace: resource.ObjectRef.Namespace,
+		})
+		if err != nil {
+			log.Error(err, "error deduplicating object")
+			continue
+		}
+		// A chain that ends early still groups the objects that share the owners it reached
+		chain, err := OwnerChain(ctx, c, obj)
+		if err != nil {
+			log.Error(err, "unable to resolve owner chain", "object", resource.ObjectRef.Name)
+		}
+		resource.Workload = resource.ObjectRef
+		if len(chain) > 0 {
+			resource.Workload = chain[len(chain)-1]
+		}
+		resource.ResultRefs = []corev1.ObjectReference{resource.ResultRef}
+
+		key := WorkloadKey(resource.Workload)
+		existing, ok := workloads[key]
+		if !ok {
+			workloads[key] = &workload{resource: resource, depth: len(chain)}
+			keys = append(keys, key)
+			continue
+		}
+		resultRefs := append(existing.resource.ResultRefs, resource.ResultRef)
+		if len(chain) < existing.depth ||
+			(len(chain) == existing.depth && resource.ResultRef.Name < existing.resource.ResultRef.Name) {
+			existing.resource, existing.depth = resource, len(chain)
+		}
+		existing.resource.ResultRefs = resultRefs
+	}
+
+	eligibleResources := make([]types.EligibleResource, 0, len(keys))
+	for _, key := range keys {
+		resource := workloads[key].resource
+		sort.Slice(resource.ResultRefs, func(i, j int) bool {
+			return resource.ResultRefs[i].Name < resource.ResultRefs[j].Name
+		})
+		eligibleResources = append(eligibleResources, resource)
+	}
+	return eligibleResources
 }
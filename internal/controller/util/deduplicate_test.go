@@ -0,0 +1,117 @@
+package util
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/k8sgpt-ai/k8sgpt-operator/internal/controller/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	apitypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/yaml"
+)
+
+func controlledBy(apiVersion, kind, name, uid string) []metav1.OwnerReference {
+	return []metav1.OwnerReference{{APIVersion: apiVersion, Kind: kind, Name: name,
+		UID: apitypes.UID(uid), Controller: ptr.To(true)}}
+}
+
+func objectMeta(namespace, name, uid string, owners []metav1.OwnerReference) metav1.ObjectMeta {
+	return metav1.ObjectMeta{Namespace: namespace, Name: name, UID: apitypes.UID(uid), OwnerReferences: owners}
+}
+
+// eligible records the object the way the conversions do, result names are the object names
+func eligible(t *testing.T, obj client.Object, kind, gvk string) types.EligibleResource {
+	config, err := yaml.Marshal(obj)
+	require.NoError(t, err)
+	return types.EligibleResource{
+		ResultRef:           corev1.ObjectReference{Namespace: "k8sgpt", Name: obj.GetNamespace() + "-" + obj.GetName()},
+		ObjectRef:           corev1.ObjectReference{Kind: kind, Namespace: obj.GetNamespace(), Name: obj.GetName(), UID: obj.GetUID()},
+		GVK:                 gvk,
+		OriginConfiguration: string(config),
+	}
+}
+
+func Test_OwnerChain(t *testing.T) {
+	job := &batchv1.Job{ObjectMeta: objectMeta("default", "nightly-123", "job-uid",
+		controlledBy("batch/v1", "CronJob", "nightly", "cronjob-uid"))}
+	pod := &corev1.Pod{ObjectMeta: objectMeta("default", "nightly-123-x", "pod-uid",
+		controlledBy("batch/v1", "Job", "nightly-123", "job-uid"))}
+	c := fake.NewClientBuilder().WithObjects(job).Build()
+
+	// The CronJob is gone, the chain still ends with the reference the Job recorded
+	chain, err := OwnerChain(context.Background(), c, pod)
+	assert.Error(t, err)
+	assert.Equal(t, []corev1.ObjectReference{
+		{APIVersion: "batch/v1", Kind: "Job", Namespace: "default", Name: "nightly-123", UID: "job-uid"},
+		{APIVersion: "batch/v1", Kind: "CronJob", Namespace: "default", Name: "nightly", UID: "cronjob-uid"},
+	}, chain)
+
+	chain, err = OwnerChain(context.Background(), c, job.DeepCopy())
+	assert.Error(t, err)
+	assert.Len(t, chain, 1)
+
+	require.NoError(t, c.Create(context.Background(), &batchv1.CronJob{ObjectMeta: objectMeta("default", "nightly", "cronjob-uid", nil)}))
+	chain, err = OwnerChain(context.Background(), c, pod)
+	require.NoError(t, err)
+	assert.Len(t, chain, 2)
+}
+
+func Test_Deduplicate(t *testing.T) {
+	const podGVK, deploymentGVK = "/v1, Kind=Pod", "apps/v1, Kind=Deployment"
+	deployment := &appsv1.Deployment{ObjectMeta: objectMeta("default", "web", "web-uid", nil)}
+	replicaSet := &appsv1.ReplicaSet{ObjectMeta: objectMeta("default", "web-5d4f", "rs-uid",
+		controlledBy("apps/v1", "Deployment", "web", "web-uid"))}
+	// Another namespace runs a Deployment of the same name
+	stagingReplicaSet := &appsv1.ReplicaSet{ObjectMeta: objectMeta("staging", "web-5d4f", "staging-rs-uid",
+		controlledBy("apps/v1", "Deployment", "web", "staging-web-uid"))}
+	stagingDeployment := &appsv1.Deployment{ObjectMeta: objectMeta("staging", "web", "staging-web-uid", nil)}
+	statefulSet := &appsv1.StatefulSet{ObjectMeta: objectMeta("default", "db", "db-uid", nil)}
+	c := fake.NewClientBuilder().WithObjects(deployment, replicaSet, stagingReplicaSet, stagingDeployment, statefulSet).Build()
+
+	webPod := func(namespace, name, rsUID string) *corev1.Pod {
+		return &corev1.Pod{ObjectMeta: objectMeta(namespace, name, name+"-uid",
+			controlledBy("apps/v1", "ReplicaSet", "web-5d4f", rsUID))}
+	}
+	input := []types.EligibleResource{
+		eligible(t, webPod("default", "web-5d4f-b", "rs-uid"), "Pod", podGVK),
+		eligible(t, webPod("default", "web-5d4f-a", "rs-uid"), "Pod", podGVK),
+		eligible(t, webPod("staging", "web-5d4f-a", "staging-rs-uid"), "Pod", podGVK),
+		eligible(t, &corev1.Pod{ObjectMeta: objectMeta("default", "db-0", "db-0-uid",
+			controlledBy("apps/v1", "StatefulSet", "db", "db-uid"))}, "Pod", podGVK),
+		eligible(t, &corev1.Pod{ObjectMeta: objectMeta("default", "debug", "debug-uid", nil)}, "Pod", podGVK),
+	}
+
+	output := Deduplicate(context.Background(), c, input, logr.Discard())
+	require.Len(t, output, 4)
+	// The pods of a Deployment become one resource, the first pod by result name, with all their results
+	assert.Equal(t, "web-5d4f-a", output[0].ObjectRef.Name)
+	assert.Equal(t, corev1.ObjectReference{APIVersion: "apps/v1", Kind: "Deployment", Namespace: "default",
+		Name: "web", UID: "web-uid"}, output[0].Workload)
+	assert.Equal(t, []corev1.ObjectReference{{Namespace: "k8sgpt", Name: "default-web-5d4f-a"},
+		{Namespace: "k8sgpt", Name: "default-web-5d4f-b"}}, output[0].ResultRefs)
+	assert.Equal(t, output[0].ResultRefs[0], output[0].ResultRef)
+	// Owners of the same name in other namespaces are other workloads
+	assert.Equal(t, "staging", output[1].ObjectRef.Namespace)
+	assert.Equal(t, apitypes.UID("staging-web-uid"), output[1].Workload.UID)
+	assert.Equal(t, "StatefulSet", output[2].Workload.Kind)
+	// Objects without owners are their own workload
+	assert.Equal(t, output[3].ObjectRef, output[3].Workload)
+	assert.Len(t, output[3].ResultRefs, 1)
+
+	// A result for the Deployment itself takes over the results of its pods
+	input = append(input, eligible(t, deployment, "Deployment", deploymentGVK))
+	output = Deduplicate(context.Background(), c, input, logr.Discard())
+	require.Len(t, output, 4)
+	assert.Equal(t, "Deployment", output[0].ObjectRef.Kind)
+	assert.Equal(t, corev1.ObjectReference{Namespace: "k8sgpt", Name: "default-web"}, output[0].ResultRef)
+	assert.Len(t, output[0].ResultRefs, 3)
+}
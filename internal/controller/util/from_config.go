@@ -16,23 +16,44 @@ type FromObjectConfig struct {
 	Namespace string
 }
 
+// ParseGVK parses a GroupVersionKind stored in its String() form, e.g. "apps/v1, Kind=Deployment".
+// kind is used when the string does not name one.
+func ParseGVK(gvkStr string, kind string) (schema.GroupVersionKind, error) {
+	gvStr, kindStr, _ := strings.Cut(gvkStr, ",")
+	gv, err := schema.ParseGroupVersion(gvStr)
+	if err != nil {
+		return schema.GroupVersionKind{}, err
+	}
+	if parsed := strings.TrimPrefix(strings.TrimSpace(kindStr), "Kind="); parsed != "" {
+		kind = parsed
+	}
+	return gv.WithKind(kind), nil
+}
+
 func FromConfig(objConfig FromObjectConfig) (client.Object, error) {
-	gv, err := schema.ParseGroupVersion(objConfig.GvkStr)
+	gvk, err := ParseGVK(objConfig.GvkStr, objConfig.Kind)
 	if err != nil {
 		return nil, err
 	}
-	gvk := gv.WithKind(objConfig.Kind)
 	// 2. Create an unstructured object
 	obj := &unstructured.Unstructured{}
-	obj.SetGroupVersionKind(gvk)
 	// 3. Decode the targetConfiguration into the unstructured object
 	decoder := yaml.NewYAMLOrJSONDecoder(strings.NewReader(objConfig.Config), 1000)
-	if err := decoder.Decode(obj); err != nil {
+	if err := decoder.Decode(&obj.Object); err != nil {
 		return nil, err
 	}
-	// 4. Set the object's name and namespace (important for updates!)
-	obj.SetName(objConfig.Name)
-	obj.SetNamespace(objConfig.Namespace)
+	// Manifests without apiVersion/kind take the stored GVK
+	if obj.GetKind() == "" || obj.GetAPIVersion() == "" {
+		obj.SetGroupVersionKind(gvk)
+	}
+	// 4. Set the object's name and namespace when the manifest leaves them out (important for updates!).
+	// A manifest naming another object is kept as it is so that the guardrails can refuse it.
+	if obj.GetName() == "" {
+		obj.SetName(objConfig.Name)
+	}
+	if obj.GetNamespace() == "" {
+		obj.SetNamespace(objConfig.Namespace)
+	}
 
 	return obj, nil
 }
@@ -1,6 +1,131 @@
 package prompts
 
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"text/template"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Version identifies the built-in prompts in the mutation history
+const Version = "builtin-v2"
+
+// VersionKey optionally names the version of the templates in a ConfigMap, the resource
+// version of the ConfigMap is used when it is unset
+const VersionKey = "version"
+
+// Step is a query auto remediation sends to the AI backend
+type Step string
+
 const (
-	Mutation_prompt   string = "Take the following in k8sgpt result %s as a guide to re-write this manifest fix a fixed version (you may make reasonable changes, e.g., fixing an image name or broken value etc..): %s  and respond with just the new manifest as a string without yaml or backticks around it. If you cannot make a suggestion for remediation, return {null} only, otherwise the response must be a working manifest (no partial responses)."
-	Deployment_prompt string = "Take the following pod manifest %s make changes to the following deployment to produce this type of pod %s. Respond with just the a new valid manifest as a string without yaml or backticks around it. If you cannot make a suggestion for remediation, return {null} only, otherwise the response must be a working manifest (no partial responses). Do not change any metadata in the object."
+	// StepMutation asks for a fixed manifest of the object of a result
+	StepMutation Step = "mutation"
+	// StepDeployment asks for the workload, e.g. the Deployment or StatefulSet, that produces
+	// the fixed pod of a result
+	StepDeployment Step = "deployment"
 )
+
+// builtin are the prompts used when a K8sGPT instance has no templates of its own
+var builtin = map[Step]*template.Template{
+	StepMutation: template.Must(template.New(string(StepMutation)).Parse(
+		"Take the following in k8sgpt result {{.Result.Details}} as a guide to re-write this manifest fix a fixed version (you may make reasonable changes, e.g., fixing an image name or broken value etc..): {{.Origin}}  and respond with just the new manifest as a string without yaml or backticks around it. If you cannot make a suggestion for remediation, return {null} only, otherwise the response must be a working manifest (no partial responses).")),
+	StepDeployment: template.Must(template.New(string(StepDeployment)).Parse(
+		"Take the following pod manifest {{.Target}} make changes to the following workload manifest to produce this type of pod {{.Origin}}. Respond with just the a new valid manifest as a string without yaml or backticks around it. If you cannot make a suggestion for remediation, return {null} only, otherwise the response must be a working manifest (no partial responses). Do not change any metadata in the object.")),
+}
+
+// Data are the variables prompt templates are executed with
+type Data struct {
+	// Result is the K8sGPT result being remediated
+	Result Result
+	// Origin is the manifest of the object the fix is written to
+	Origin string
+	// Target is the fixed manifest the deployment step carries over to the workload
+	Target string
+	// Events are the most recent events of the object of the result
+	Events []Event
+	// Owners is the owner chain of the object of the result, its controller first
+	Owners []Owner
+}
+
+// Result are the details of a K8sGPT result
+type Result struct {
+	Kind         string
+	Name         string
+	ParentObject string
+	Details      string
+	Errors       []string
+}
+
+// Event is an event of the object of a result
+type Event struct {
+	Type    string
+	Reason  string
+	Message string
+	Count   int32
+}
+
+// Owner is an object in the owner chain of the object of a result
+type Owner struct {
+	APIVersion string
+	Kind       string
+	Name       string
+}
+
+// Templates renders the prompts of a K8sGPT instance. The ConfigMap holds a template per step,
+// keyed by the step, or by the step and the kind of the result, such as "mutation.Deployment",
+// which takes precedence. Steps without a template use the built-in prompts.
+type Templates struct {
+	Client client.Reader
+	// ConfigMap holds the templates, the built-in prompts are used when it is nil
+	ConfigMap *types.NamespacedName
+}
+
+// Enabled reports whether the templates are read from a ConfigMap
+func (t Templates) Enabled() bool {
+	return t.ConfigMap != nil
+}
+
+// Render executes the template of the step for the kind of the result and returns the prompt
+// and the version of the template
+func (t Templates) Render(ctx context.Context, step Step, kind string, data Data) (string, string, error) {
+	tmpl, version, err := t.lookup(ctx, step, kind)
+	if err != nil {
+		return "", "", err
+	}
+	var prompt bytes.Buffer
+	if err := tmpl.Execute(&prompt, data); err != nil {
+		return "", "", fmt.Errorf("prompt template %s: %w", version, err)
+	}
+	return prompt.String(), version, nil
+}
+
+func (t Templates) lookup(ctx context.Context, step Step, kind string) (*template.Template, string, error) {
+	if t.ConfigMap == nil {
+		return builtin[step], Version, nil
+	}
+	var configMap corev1.ConfigMap
+	if err := t.Client.Get(ctx, *t.ConfigMap, &configMap); err != nil {
+		return nil, "", fmt.Errorf("unable to read prompt templates: %w", err)
+	}
+	version := configMap.Data[VersionKey]
+	if version == "" {
+		version = configMap.ResourceVersion
+	}
+	for _, key := range []string{string(step) + "." + kind, string(step)} {
+		text, ok := configMap.Data[key]
+		if !ok {
+			continue
+		}
+		name := fmt.Sprintf("%s/%s@%s", configMap.Name, key, version)
+		tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return nil, "", fmt.Errorf("prompt template %s: %w", name, err)
+		}
+		return tmpl, name, nil
+	}
+	return builtin[step], Version, nil
+}
@@ -0,0 +1,87 @@
+package prompts
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// The built-in prompts as they were sent before they became templates
+const (
+	mutationPrompt   = "Take the following in k8sgpt result %s as a guide to re-write this manifest fix a fixed version (you may make reasonable changes, e.g., fixing an image name or broken value etc..): %s  and respond with just the new manifest as a string without yaml or backticks around it. If you cannot make a suggestion for remediation, return {null} only, otherwise the response must be a working manifest (no partial responses)."
+	deploymentPrompt = "Take the following pod manifest %s make changes to the following workload manifest to produce this type of pod %s. Respond with just the a new valid manifest as a string without yaml or backticks around it. If you cannot make a suggestion for remediation, return {null} only, otherwise the response must be a working manifest (no partial responses). Do not change any metadata in the object."
+)
+
+var data = Data{
+	Result: Result{Kind: "Pod", Name: "default/web", Details: "the image does not exist",
+		Errors: []string{"Back-off pulling image nginx:broken"}},
+	Origin: "kind: Pod\n",
+	Target: "kind: Pod\nspec: {}\n",
+	Events: []Event{{Type: "Warning", Reason: "Failed", Message: "ErrImagePull", Count: 3}},
+	Owners: []Owner{{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "web-5d4f"},
+		{APIVersion: "apps/v1", Kind: "Deployment", Name: "web"}},
+}
+
+func Test_RenderBuiltin(t *testing.T) {
+	prompt, version, err := Templates{}.Render(context.Background(), StepMutation, "Pod", data)
+	require.NoError(t, err)
+	assert.Equal(t, Version, version)
+	assert.Equal(t, fmt.Sprintf(mutationPrompt, data.Result.Details, data.Origin), prompt)
+
+	prompt, _, err = Templates{}.Render(context.Background(), StepDeployment, "Pod", data)
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf(deploymentPrompt, data.Target, data.Origin), prompt)
+}
+
+func Test_RenderConfigMap(t *testing.T) {
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "k8sgpt", Name: "prompts"},
+		Data: map[string]string{
+			VersionKey: "v3",
+			"mutation": "Fix {{.Result.Name}}: {{.Result.Details}}",
+			"mutation.Pod": `Fix pod {{.Result.Name}} owned by {{range .Owners}}{{.Kind}}/{{.Name}} {{end}}after ` +
+				`{{range .Events}}{{.Reason}}: {{.Message}} x{{.Count}}{{end}}`,
+			"deployment": "{{.Result.Missing}}",
+		},
+	}
+	c := fake.NewClientBuilder().WithObjects(configMap).Build()
+	templates := Templates{Client: c, ConfigMap: &types.NamespacedName{Namespace: "k8sgpt", Name: "prompts"}}
+
+	// The template of the kind takes precedence over the template of the step
+	prompt, version, err := templates.Render(context.Background(), StepMutation, "Pod", data)
+	require.NoError(t, err)
+	assert.Equal(t, "prompts/mutation.Pod@v3", version)
+	assert.Equal(t, "Fix pod default/web owned by ReplicaSet/web-5d4f Deployment/web after Failed: ErrImagePull x3", prompt)
+
+	prompt, version, err = templates.Render(context.Background(), StepMutation, "Service", data)
+	require.NoError(t, err)
+	assert.Equal(t, "prompts/mutation@v3", version)
+	assert.Equal(t, "Fix default/web: the image does not exist", prompt)
+
+	// Templates referring to variables that do not exist fail instead of sending a partial prompt
+	_, _, err = templates.Render(context.Background(), StepDeployment, "Pod", data)
+	assert.ErrorContains(t, err, "prompts/deployment@v3")
+
+	// Steps without a template fall back to the built-in prompts
+	delete(configMap.Data, "deployment")
+	delete(configMap.Data, VersionKey)
+	require.NoError(t, c.Update(context.Background(), configMap))
+	_, version, err = templates.Render(context.Background(), StepDeployment, "Pod", data)
+	require.NoError(t, err)
+	assert.Equal(t, Version, version)
+	_, version, err = templates.Render(context.Background(), StepMutation, "Service", data)
+	require.NoError(t, err)
+	assert.Equal(t, "prompts/mutation@"+configMap.ResourceVersion, version)
+
+	// A missing ConfigMap is an error rather than a silent fallback
+	missing := Templates{Client: c, ConfigMap: &types.NamespacedName{Namespace: "k8sgpt", Name: "absent"}}
+	_, _, err = missing.Render(context.Background(), StepMutation, "Pod", data)
+	assert.Error(t, err)
+}
@@ -110,6 +110,11 @@ func InitializeMetrics() *MetricBuilder {
 		Help:   "The total number of results by type",
 		Labels: []string{"object_namespace", "kind", "name", "k8sgpt"},
 		Type:   Gauge,
+	}).AddMetric(MetricConfig{
+		Name:   "k8sgpt_number_of_results_by_severity",
+		Help:   "The total number of results by severity",
+		Labels: []string{"severity", "k8sgpt"},
+		Type:   Gauge,
 	}).AddMetric(MetricConfig{
 		Name:   "k8sgpt_number_of_backend_ai_calls",
 		Help:   "The total number of backend AI calls",
@@ -125,6 +130,21 @@ func InitializeMetrics() *MetricBuilder {
 		Help:   "The total number of mutations",
 		Labels: []string{"mutations", "k8sgpt"},
 		Type:   Gauge,
+	}).AddMetric(MetricConfig{
+		Name:   "k8sgpt_ai_circuit_breaker_state",
+		Help:   "The state of the AI backend circuit breaker (0 closed, 1 half-open, 2 open)",
+		Labels: []string{"backend", "namespace", "k8sgpt"},
+		Type:   Gauge,
+	}).AddMetric(MetricConfig{
+		Name:   "k8sgpt_ai_circuit_breaker_trips",
+		Help:   "The total number of times the AI backend circuit breaker opened",
+		Labels: []string{"backend", "namespace", "k8sgpt", "reason"},
+		Type:   Counter,
+	}).AddMetric(MetricConfig{
+		Name:   "k8sgpt_number_of_evicted_results",
+		Help:   "The total number of results evicted by the retention of the K8sGPT instance",
+		Labels: []string{"namespace", "k8sgpt", "reason"},
+		Type:   Counter,
 	})
 
 	builder.RegisterMetrics()
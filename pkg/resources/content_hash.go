@@ -0,0 +1,39 @@
+package resources
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+
+	"github.com/k8sgpt-ai/k8sgpt-operator/api/v1alpha1"
+)
+
+// ContentHashAnnotation records the ContentHash of a result
+const ContentHashAnnotation = "results.k8sgpt.ai/content-hash"
+
+// ContentHash returns a stable hash of what a result says about its object: its errors, in any
+// order, its details and its parent object
+func ContentHash(resultSpec v1alpha1.ResultSpec) string {
+	errors := make([]string, 0, len(resultSpec.Error))
+	for _, failure := range resultSpec.Error {
+		errors = append(errors, failure.Text)
+	}
+	sort.Strings(errors)
+	content, _ := json.Marshal(struct {
+		Errors       []string `json:"errors"`
+		Details      string   `json:"details"`
+		ParentObject string   `json:"parentObject"`
+	}{errors, resultSpec.Details, resultSpec.ParentObject})
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// storedContentHash returns the content hash recorded on a result, results written before the
+// hash was recorded are hashed as they are
+func storedContentHash(result v1alpha1.Result) string {
+	if hash, ok := result.Annotations[ContentHashAnnotation]; ok {
+		return hash
+	}
+	return ContentHash(result.Spec)
+}
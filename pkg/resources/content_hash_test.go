@@ -0,0 +1,100 @@
+package resources
+
+import (
+	"context"
+	"testing"
+
+	"github.com/k8sgpt-ai/k8sgpt-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_ContentHash(t *testing.T) {
+	spec := v1alpha1.ResultSpec{
+		Kind:  "Pod",
+		Name:  "default/web",
+		Error: []v1alpha1.Failure{{Text: "back-off restarting"}, {Text: "image not found"}},
+	}
+	reordered := spec
+	reordered.Error = []v1alpha1.Failure{{Text: "image not found"}, {Text: "back-off restarting"}}
+	assert.Equal(t, ContentHash(spec), ContentHash(reordered))
+
+	changed := spec
+	changed.Error = []v1alpha1.Failure{{Text: "back-off restarting"}, {Text: "OOMKilled"}}
+	assert.NotEqual(t, ContentHash(spec), ContentHash(changed))
+
+	explained := spec
+	explained.Details = "The image tag does not exist"
+	assert.NotEqual(t, ContentHash(spec), ContentHash(explained))
+
+	parented := spec
+	parented.ParentObject = "Deployment/web"
+	assert.NotEqual(t, ContentHash(spec), ContentHash(parented))
+}
+
+func Test_CreateOrUpdateResultDetectsChanges(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+	ctx := context.Background()
+	spec := v1alpha1.ResultSpec{
+		Kind:    "Pod",
+		Name:    "default/web",
+		Error:   []v1alpha1.Failure{{Text: "back-off restarting"}},
+		Details: "The container keeps crashing",
+	}
+	name := ResultName("Pod", "default/web")
+	with := func(update func(*v1alpha1.ResultSpec)) v1alpha1.Result {
+		changed := *spec.DeepCopy()
+		update(&changed)
+		return GetResult(changed, name, "k8sgpt", "openai", changed.Details)
+	}
+
+	tests := []struct {
+		name      string
+		res       v1alpha1.Result
+		lifeCycle ResultOperation
+	}{
+		{"unchanged", with(func(*v1alpha1.ResultSpec) {}), NoOpResult},
+		{"same count, new error", with(func(s *v1alpha1.ResultSpec) {
+			s.Error = []v1alpha1.Failure{{Text: "OOMKilled"}}
+		}), UpdatedResult},
+		{"new details", with(func(s *v1alpha1.ResultSpec) { s.Details = "The pod runs out of memory" }), UpdatedResult},
+		{"new parent", with(func(s *v1alpha1.ResultSpec) { s.ParentObject = "Deployment/web" }), UpdatedResult},
+		{"details unavailable", with(func(s *v1alpha1.ResultSpec) { s.Details = "" }), NoOpResult},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := fake.NewClientBuilder().WithScheme(scheme).WithStatusSubresource(&v1alpha1.Result{}).Build()
+			_, err := CreateOrUpdateResult(ctx, c, GetResult(spec, name, "k8sgpt", "openai", spec.Details), nil)
+			require.NoError(t, err)
+
+			result, err := CreateOrUpdateResult(ctx, c, tt.res, nil)
+			require.NoError(t, err)
+			assert.Equal(t, string(tt.lifeCycle), result.Status.LifeCycle)
+
+			var stored v1alpha1.Result
+			require.NoError(t, c.Get(ctx, client.ObjectKey{Namespace: "k8sgpt", Name: name}, &stored))
+			assert.Equal(t, ContentHash(stored.Spec), stored.Annotations[ContentHashAnnotation])
+			assert.NotEmpty(t, stored.Spec.Details)
+		})
+	}
+}
+
+func Test_CreateOrUpdateResultHashesLegacyResults(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+	ctx := context.Background()
+	spec := v1alpha1.ResultSpec{Kind: "Pod", Name: "default/web", Error: []v1alpha1.Failure{{Text: "back-off restarting"}}}
+	legacy := GetResult(spec, ResultName("Pod", "default/web"), "k8sgpt", "openai", "")
+	c := fake.NewClientBuilder().WithScheme(scheme).WithStatusSubresource(&v1alpha1.Result{}).
+		WithObjects(&legacy).Build()
+
+	// Results written before the hash was recorded are not sent again on upgrade
+	result, err := CreateOrUpdateResult(ctx, c, legacy, nil)
+	require.NoError(t, err)
+	assert.Equal(t, string(NoOpResult), result.Status.LifeCycle)
+	assert.Equal(t, ContentHash(spec), result.Annotations[ContentHashAnnotation])
+}
@@ -0,0 +1,157 @@
+package resources
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/k8sgpt-ai/k8sgpt-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// ResultKindLabel is the kind of the object a result is about
+	ResultKindLabel = "results.k8sgpt.ai/kind"
+	// ResultNamespaceLabel is the namespace of the object a result is about
+	ResultNamespaceLabel = "results.k8sgpt.ai/namespace"
+	// ResultNameLabel is the name of the object a result is about, it is left out when the name
+	// is not a valid label value
+	ResultNameLabel = "results.k8sgpt.ai/name"
+
+	// maxResultNameLength keeps result names, and the mutations named after them, valid label values
+	maxResultNameLength = validation.LabelValueMaxLength
+	// resultNameHashLength is the length of the hash that keeps truncated and sanitized names apart
+	resultNameHashLength = 10
+)
+
+// ResultName returns the name of the result for an object of the kind, name is the object's
+// namespace/name as k8sgpt reports it. The readable part is the lowercased kind, namespace and
+// name, truncated as needed, and the hash of the kind and name keeps results apart.
+func ResultName(kind, name string) string {
+	sum := sha256.Sum256([]byte(kind + "/" + name))
+	hash := hex.EncodeToString(sum[:])[:resultNameHashLength]
+
+	var readable strings.Builder
+	for _, r := range strings.ToLower(kind + "-" + name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			readable.WriteRune(r)
+		} else {
+			readable.WriteRune('-')
+		}
+	}
+	prefix := readable.String()
+	if maxPrefix := maxResultNameLength - resultNameHashLength - 1; len(prefix) > maxPrefix {
+		prefix = prefix[:maxPrefix]
+	}
+	return strings.Trim(prefix, "-") + "-" + hash
+}
+
+// ResultLabels returns the labels recording the object a result is about
+func ResultLabels(resultSpec v1alpha1.ResultSpec) map[string]string {
+	labels := map[string]string{}
+	namespace, name, namespaced := strings.Cut(resultSpec.Name, "/")
+	if !namespaced {
+		namespace, name = "", resultSpec.Name
+	}
+	for key, value := range map[string]string{
+		ResultKindLabel:      resultSpec.Kind,
+		ResultNamespaceLabel: namespace,
+		ResultNameLabel:      name,
+	} {
+		if value != "" && len(validation.IsValidLabelValue(value)) == 0 {
+			labels[key] = value
+		}
+	}
+	return labels
+}
+
+// MigrateResultNames renames the results of the K8sGPT instance that were named before
+// ResultName, and points the mutations referencing them at the new names. The renamed results
+// keep their status and are marked historical, so they are not sent to the sink again.
+func MigrateResultNames(ctx context.Context, c client.Client, config v1alpha1.K8sGPT) (int, error) {
+	logger := log.FromContext(ctx)
+	var results v1alpha1.ResultList
+	if err := c.List(ctx, &results, client.InNamespace(config.Namespace), client.MatchingLabels{
+		"k8sgpts.k8sgpt.ai/name":      config.Name,
+		"k8sgpts.k8sgpt.ai/namespace": config.Namespace,
+	}); err != nil {
+		return 0, err
+	}
+
+	renamed := map[string]string{}
+	for _, legacy := range results.Items {
+		name := ResultName(legacy.Spec.Kind, legacy.Spec.Name)
+		if legacy.Name == name {
+			continue
+		}
+		result := v1alpha1.Result{
+			ObjectMeta: metav1.ObjectMeta{Namespace: legacy.Namespace, Name: name, Labels: legacy.Labels,
+				Annotations: legacy.Annotations},
+			Spec: legacy.Spec,
+		}
+		for key, value := range ResultLabels(legacy.Spec) {
+			metav1.SetMetaDataLabel(&result.ObjectMeta, key, value)
+		}
+		if err := c.Create(ctx, &result); err != nil && !errors.IsAlreadyExists(err) {
+			return len(renamed), err
+		} else if err == nil {
+			result.Status = legacy.Status
+			result.Status.LifeCycle = string(NoOpResult)
+			if err := c.Status().Update(ctx, &result); err != nil {
+				return len(renamed), err
+			}
+		}
+		renamed[legacy.Name] = name
+	}
+	if len(renamed) == 0 {
+		return 0, nil
+	}
+
+	// Mutations are only successful once their results are gone, they must follow the rename
+	// before the legacy results are deleted
+	var mutations v1alpha1.MutationList
+	if err := c.List(ctx, &mutations, client.InNamespace(config.Namespace)); err != nil {
+		return 0, err
+	}
+	for i := range mutations.Items {
+		mutation := &mutations.Items[i]
+		patch := client.MergeFrom(mutation.DeepCopy())
+		changed := renameRef(&mutation.Spec.ResultRef, renamed)
+		for j := range mutation.Spec.ResultRefs {
+			changed = renameRef(&mutation.Spec.ResultRefs[j], renamed) || changed
+		}
+		if changed {
+			if err := c.Patch(ctx, mutation, patch); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	for _, legacy := range results.Items {
+		if _, ok := renamed[legacy.Name]; !ok {
+			continue
+		}
+		if err := c.Delete(ctx, &legacy); client.IgnoreNotFound(err) != nil {
+			return 0, err
+		}
+		logger.Info("Renamed result", "from", legacy.Name, "to", renamed[legacy.Name])
+	}
+	return len(renamed), nil
+}
+
+func renameRef(ref *corev1.ObjectReference, renamed map[string]string) bool {
+	name, ok := renamed[ref.Name]
+	if !ok {
+		return false
+	}
+	ref.Name = name
+	ref.UID = ""
+	ref.ResourceVersion = ""
+	return true
+}
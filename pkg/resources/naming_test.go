@@ -0,0 +1,97 @@
+package resources
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/k8sgpt-ai/k8sgpt-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_ResultName(t *testing.T) {
+	name := ResultName("Pod", "default/web-5d4f")
+	assert.True(t, strings.HasPrefix(name, "pod-default-web-5d4f-"), name)
+	assert.Equal(t, name, ResultName("Pod", "default/web-5d4f"))
+
+	// Names the legacy scheme collapsed are kept apart
+	distinct := map[string]bool{}
+	for _, ref := range [][2]string{
+		{"Pod", "a-b/c"}, {"Pod", "ab/c"},
+		{"Pod", "default/my-pod"}, {"Pod", "defaultmy/pod"},
+		{"Pod", "default/web"}, {"Service", "default/web"},
+		{"Node", "worker-1"},
+	} {
+		distinct[ResultName(ref[0], ref[1])] = true
+	}
+	assert.Len(t, distinct, 7)
+
+	long := ResultName("Pod", "default/"+strings.Repeat("very-long-name.", 16))
+	assert.LessOrEqual(t, len(long), validation.LabelValueMaxLength)
+	assert.Empty(t, validation.IsDNS1123Label(long))
+	assert.NotEqual(t, long, ResultName("Pod", "default/"+strings.Repeat("very-long-name.", 17)))
+}
+
+func Test_ResultLabels(t *testing.T) {
+	assert.Equal(t, map[string]string{ResultKindLabel: "Pod", ResultNamespaceLabel: "default", ResultNameLabel: "web"},
+		ResultLabels(v1alpha1.ResultSpec{Kind: "Pod", Name: "default/web"}))
+	assert.Equal(t, map[string]string{ResultKindLabel: "Node", ResultNameLabel: "worker-1"},
+		ResultLabels(v1alpha1.ResultSpec{Kind: "Node", Name: "worker-1"}))
+	// Names that are not valid label values are only kept in the spec
+	assert.NotContains(t, ResultLabels(v1alpha1.ResultSpec{Kind: "Pod", Name: "default/" + strings.Repeat("x", 64)}),
+		ResultNameLabel)
+}
+
+func Test_MigrateResultNames(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+	config := v1alpha1.K8sGPT{ObjectMeta: metav1.ObjectMeta{Namespace: "k8sgpt", Name: "k8sgpt"}}
+	instanceLabels := map[string]string{"k8sgpts.k8sgpt.ai/name": "k8sgpt", "k8sgpts.k8sgpt.ai/namespace": "k8sgpt"}
+	legacy := &v1alpha1.Result{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "k8sgpt", Name: "defaultweb5d4f", Labels: instanceLabels},
+		Spec:       v1alpha1.ResultSpec{Kind: "Pod", Name: "default/web-5d4f"},
+		Status:     v1alpha1.ResultStatus{LifeCycle: string(CreatedResult), Webhook: "https://hooks.example.com", Occurrences: 7},
+	}
+	mutation := &v1alpha1.Mutation{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "k8sgpt", Name: "defaultweb5d4f"},
+		Spec: v1alpha1.MutationSpec{
+			ResultRef:  corev1.ObjectReference{Namespace: "k8sgpt", Name: "defaultweb5d4f", UID: "legacy-uid"},
+			ResultRefs: []corev1.ObjectReference{{Namespace: "k8sgpt", Name: "defaultweb5d4f"}},
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithStatusSubresource(&v1alpha1.Result{}).
+		WithObjects(legacy, mutation).Build()
+	ctx := context.Background()
+	name := ResultName("Pod", "default/web-5d4f")
+
+	renamed, err := MigrateResultNames(ctx, c, config)
+	require.NoError(t, err)
+	assert.Equal(t, 1, renamed)
+
+	var result v1alpha1.Result
+	require.NoError(t, c.Get(ctx, client.ObjectKey{Namespace: "k8sgpt", Name: name}, &result))
+	assert.Equal(t, "Pod", result.Labels[ResultKindLabel])
+	assert.Equal(t, "k8sgpt", result.Labels["k8sgpts.k8sgpt.ai/name"])
+	// The sink already has the result, the renamed one is not sent again
+	assert.Equal(t, string(NoOpResult), result.Status.LifeCycle)
+	assert.Equal(t, "https://hooks.example.com", result.Status.Webhook)
+	assert.Equal(t, 7, result.Status.Occurrences)
+	assert.Error(t, c.Get(ctx, client.ObjectKey{Namespace: "k8sgpt", Name: "defaultweb5d4f"}, &result))
+
+	var migrated v1alpha1.Mutation
+	require.NoError(t, c.Get(ctx, client.ObjectKey{Namespace: "k8sgpt", Name: "defaultweb5d4f"}, &migrated))
+	assert.Equal(t, corev1.ObjectReference{Namespace: "k8sgpt", Name: name}, migrated.Spec.ResultRef)
+	assert.Equal(t, name, migrated.Spec.ResultRefs[0].Name)
+
+	// Results with current names are left alone
+	renamed, err = MigrateResultNames(ctx, c, config)
+	require.NoError(t, err)
+	assert.Zero(t, renamed)
+}
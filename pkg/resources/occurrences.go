@@ -0,0 +1,112 @@
+package resources
+
+import (
+	"slices"
+	"time"
+
+	"github.com/k8sgpt-ai/k8sgpt-operator/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// DefaultTombstoneTTL is how long the history of a resolved result is kept for it to reappear,
+	// unless the K8sGPT instance sets analysis.resolvedTTL
+	DefaultTombstoneTTL = time.Hour
+	// FlappingWindow is how far back reappearances count towards flapping
+	FlappingWindow = time.Hour
+	// FlappingThreshold is how many reappearances within the FlappingWindow make a result flap
+	FlappingThreshold = 3
+	// DefaultMaxTombstones bounds the tombstones kept on the status of a K8sGPT instance, unless
+	// its retention sets maxResults
+	DefaultMaxTombstones = 500
+)
+
+// ObserveResult records on the status that an analysis run found the result at now. A result
+// seen for the first time continues the history of its tombstone, if it has one.
+func ObserveResult(status *v1alpha1.ResultStatus, tombstone *v1alpha1.ResultTombstone, now metav1.Time) {
+	if status.FirstSeen == nil {
+		status.FirstSeen = &now
+		if tombstone != nil {
+			if tombstone.FirstSeen != nil {
+				status.FirstSeen = tombstone.FirstSeen.DeepCopy()
+			}
+			status.Occurrences = tombstone.Occurrences
+			status.Reappearances = append(append([]metav1.Time(nil), tombstone.Reappearances...), now)
+		}
+	}
+	status.LastSeen = &now
+	status.Occurrences++
+
+	recent := status.Reappearances[:0]
+	for _, reappeared := range status.Reappearances {
+		if now.Sub(reappeared.Time) < FlappingWindow {
+			recent = append(recent, reappeared)
+		}
+	}
+	status.Reappearances = recent
+	if len(status.Reappearances) == 0 {
+		status.Reappearances = nil
+	}
+	status.Flapping = len(status.Reappearances) >= FlappingThreshold
+}
+
+// Tombstone returns the tombstone keeping the history of a result resolved at now
+func Tombstone(result v1alpha1.Result, now metav1.Time) v1alpha1.ResultTombstone {
+	return v1alpha1.ResultTombstone{
+		Name:          result.Name,
+		ResolvedAt:    now,
+		FirstSeen:     result.Status.FirstSeen,
+		Occurrences:   result.Status.Occurrences,
+		Reappearances: result.Status.Reappearances,
+	}
+}
+
+// TombstoneTTL returns how long the K8sGPT instance keeps the history of resolved results
+func TombstoneTTL(config v1alpha1.K8sGPT) time.Duration {
+	if config.Spec.Analysis == nil || config.Spec.Analysis.ResolvedTTL == "" {
+		return DefaultTombstoneTTL
+	}
+	ttl, err := time.ParseDuration(config.Spec.Analysis.ResolvedTTL)
+	if err != nil {
+		return DefaultTombstoneTTL
+	}
+	return ttl
+}
+
+// ResolvedAfter returns how long the problem of a result resolved at now lasted
+func ResolvedAfter(result v1alpha1.Result, now metav1.Time) time.Duration {
+	firstSeen := result.CreationTimestamp
+	if result.Status.FirstSeen != nil {
+		firstSeen = *result.Status.FirstSeen
+	}
+	if firstSeen.IsZero() || now.Before(&firstSeen) {
+		return 0
+	}
+	return now.Sub(firstSeen.Time)
+}
+
+// MaxTombstones returns how many tombstones the K8sGPT instance keeps, as many as it keeps results
+func MaxTombstones(config v1alpha1.K8sGPT) int {
+	if config.Spec.Retention == nil || config.Spec.Retention.MaxResults <= 0 {
+		return DefaultMaxTombstones
+	}
+	return config.Spec.Retention.MaxResults
+}
+
+// PruneTombstones drops the tombstones older than the ttl, and the oldest ones over the limit
+func PruneTombstones(tombstones []v1alpha1.ResultTombstone, now metav1.Time, ttl time.Duration,
+	limit int) []v1alpha1.ResultTombstone {
+	var kept []v1alpha1.ResultTombstone
+	for _, tombstone := range tombstones {
+		if now.Sub(tombstone.ResolvedAt.Time) < ttl {
+			kept = append(kept, tombstone)
+		}
+	}
+	if len(kept) > limit {
+		slices.SortStableFunc(kept, func(a, b v1alpha1.ResultTombstone) int {
+			return a.ResolvedAt.Compare(b.ResolvedAt.Time)
+		})
+		kept = kept[len(kept)-limit:]
+	}
+	return kept
+}
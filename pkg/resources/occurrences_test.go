@@ -0,0 +1,113 @@
+package resources
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/k8sgpt-ai/k8sgpt-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_ObserveResult(t *testing.T) {
+	start := time.Now().Truncate(time.Second)
+	at := func(offset time.Duration) metav1.Time { return metav1.NewTime(start.Add(offset)) }
+
+	var status v1alpha1.ResultStatus
+	ObserveResult(&status, nil, at(0))
+	ObserveResult(&status, nil, at(time.Minute))
+	assert.Equal(t, at(0), *status.FirstSeen)
+	assert.Equal(t, at(time.Minute), *status.LastSeen)
+	assert.Equal(t, 2, status.Occurrences)
+	assert.False(t, status.Flapping)
+
+	// Every time the result is resolved and comes back its history continues from the tombstone
+	for i := 1; i <= FlappingThreshold; i++ {
+		tombstone := Tombstone(v1alpha1.Result{Status: status}, at(time.Duration(i)*5*time.Minute))
+		status = v1alpha1.ResultStatus{}
+		ObserveResult(&status, &tombstone, at(time.Duration(i)*10*time.Minute))
+		assert.Len(t, status.Reappearances, i)
+		assert.Equal(t, i >= FlappingThreshold, status.Flapping)
+	}
+	assert.Equal(t, at(0), *status.FirstSeen)
+	assert.Equal(t, 2+FlappingThreshold, status.Occurrences)
+
+	// Reappearances older than the flapping window no longer count
+	ObserveResult(&status, nil, at(FlappingWindow+15*time.Minute))
+	assert.Len(t, status.Reappearances, FlappingThreshold-1)
+	assert.False(t, status.Flapping)
+}
+
+func Test_PruneTombstones(t *testing.T) {
+	now := metav1.Now()
+	tombstones := []v1alpha1.ResultTombstone{
+		{Name: "recent", ResolvedAt: metav1.NewTime(now.Add(-time.Minute))},
+		{Name: "expired", ResolvedAt: metav1.NewTime(now.Add(-DefaultTombstoneTTL))},
+	}
+	kept := PruneTombstones(tombstones, now, DefaultTombstoneTTL, DefaultMaxTombstones)
+	require.Len(t, kept, 1)
+	assert.Equal(t, "recent", kept[0].Name)
+
+	assert.Len(t, PruneTombstones(tombstones, now, 2*DefaultTombstoneTTL, DefaultMaxTombstones), 2)
+
+	// The most recently resolved results are kept over the limit
+	kept = PruneTombstones(tombstones, now, 2*DefaultTombstoneTTL, 1)
+	require.Len(t, kept, 1)
+	assert.Equal(t, "recent", kept[0].Name)
+}
+
+func Test_MaxTombstones(t *testing.T) {
+	config := v1alpha1.K8sGPT{}
+	assert.Equal(t, DefaultMaxTombstones, MaxTombstones(config))
+	config.Spec.Retention = &v1alpha1.RetentionConfig{MaxResults: 200}
+	assert.Equal(t, 200, MaxTombstones(config))
+}
+
+func Test_TombstoneTTL(t *testing.T) {
+	config := v1alpha1.K8sGPT{}
+	assert.Equal(t, DefaultTombstoneTTL, TombstoneTTL(config))
+	config.Spec.Analysis = &v1alpha1.AnalysisConfig{ResolvedTTL: "24h"}
+	assert.Equal(t, 24*time.Hour, TombstoneTTL(config))
+	config.Spec.Analysis.ResolvedTTL = "a day"
+	assert.Equal(t, DefaultTombstoneTTL, TombstoneTTL(config))
+}
+
+func Test_ResolvedAfter(t *testing.T) {
+	now := metav1.Now()
+	created := metav1.NewTime(now.Add(-10 * time.Minute))
+	firstSeen := metav1.NewTime(now.Add(-2 * time.Hour))
+	result := v1alpha1.Result{ObjectMeta: metav1.ObjectMeta{CreationTimestamp: created}}
+	assert.Equal(t, 10*time.Minute, ResolvedAfter(result, now))
+	// A result that reappeared counts from when the problem was first found
+	result.Status.FirstSeen = &firstSeen
+	assert.Equal(t, 2*time.Hour, ResolvedAfter(result, now))
+}
+
+func Test_CreateOrUpdateResultRecordsOccurrences(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+	c := fake.NewClientBuilder().WithScheme(scheme).WithStatusSubresource(&v1alpha1.Result{}).Build()
+	ctx := context.Background()
+
+	firstSeen := metav1.NewTime(time.Now().Add(-30 * time.Minute).Truncate(time.Second))
+	tombstone := &v1alpha1.ResultTombstone{Name: "defaultweb", FirstSeen: &firstSeen, Occurrences: 4}
+	res := GetResult(v1alpha1.ResultSpec{Kind: "Pod", Name: "default/web"}, "defaultweb", "k8sgpt", "openai", "")
+
+	created, err := CreateOrUpdateResult(ctx, c, res, tombstone)
+	require.NoError(t, err)
+	assert.Equal(t, string(CreatedResult), created.Status.LifeCycle)
+
+	_, err = CreateOrUpdateResult(ctx, c, res, nil)
+	require.NoError(t, err)
+	var stored v1alpha1.Result
+	require.NoError(t, c.Get(ctx, client.ObjectKey{Namespace: "k8sgpt", Name: "defaultweb"}, &stored))
+	assert.Equal(t, string(NoOpResult), stored.Status.LifeCycle)
+	assert.Equal(t, firstSeen, *stored.Status.FirstSeen)
+	assert.Equal(t, 6, stored.Status.Occurrences)
+	assert.Len(t, stored.Status.Reappearances, 1)
+}
@@ -3,10 +3,11 @@ package resources
 import (
 	"context"
 	"reflect"
-	"strings"
+	"slices"
 
 	"github.com/k8sgpt-ai/k8sgpt-operator/api/v1alpha1"
 	"github.com/k8sgpt-ai/k8sgpt-operator/pkg/integrations"
+	"github.com/k8sgpt-ai/k8sgpt-operator/pkg/utils"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/util/retry"
@@ -20,21 +21,30 @@ const (
 	CreatedResult ResultOperation = "created"
 	UpdatedResult ResultOperation = "updated"
 	NoOpResult    ResultOperation = "historical"
+	// ResolvedResult is a result that is no longer reported, it is deleted once the sink knows
+	ResolvedResult ResultOperation = "resolved"
 )
 
-func MapResults(i integrations.Integrations, resultsSpec []v1alpha1.ResultSpec, config v1alpha1.K8sGPT) (map[string]v1alpha1.Result, error) {
+func MapResults(i integrations.Integrations, resultsSpec []v1alpha1.ResultSpec, config v1alpha1.K8sGPT) map[string]v1alpha1.Result {
 	namespace := config.Namespace
 	backend := config.Spec.AI.Backend
 	backstageEnabled := config.Spec.ExtraOptions != nil && config.Spec.ExtraOptions.Backstage.Enabled
+	var severityRules []v1alpha1.SeverityRule
+	if config.Spec.Analysis != nil {
+		severityRules = config.Spec.Analysis.SeverityRules
+	}
+	classifier := NewSeverityClassifier(severityRules)
 	rawResults := make(map[string]v1alpha1.Result)
 	for _, resultSpec := range resultsSpec {
-		name := strings.ReplaceAll(resultSpec.Name, "-", "")
-		name = strings.ReplaceAll(name, "/", "")
+		resultSpec.Severity = classifier.Classify(resultSpec)
+		name := ResultName(resultSpec.Kind, resultSpec.Name)
 		result := GetResult(resultSpec, name, namespace, backend, resultSpec.Details)
-		labels := map[string]string{
-			"k8sgpts.k8sgpt.ai/name":      config.Name,
-			"k8sgpts.k8sgpt.ai/namespace": config.Namespace,
+		if config.Spec.Retention != nil {
+			TruncateDetails(&result.Spec, config.Spec.Retention.MaxDetailsLength)
 		}
+		labels := ResultLabels(resultSpec)
+		labels["k8sgpts.k8sgpt.ai/name"] = config.Name
+		labels["k8sgpts.k8sgpt.ai/namespace"] = config.Namespace
 		if config.Spec.AI != nil {
 			labels["k8sgpts.k8sgpt.ai/backend"] = config.Spec.AI.Backend
 		}
@@ -46,10 +56,26 @@ func MapResults(i integrations.Integrations, resultsSpec []v1alpha1.ResultSpec,
 			}
 		}
 		result.SetLabels(labels)
+		result.SetOwnerReferences([]metav1.OwnerReference{OwnerReference(config)})
 
 		rawResults[name] = result
 	}
-	return rawResults, nil
+	return rawResults
+}
+
+// OwnerReference returns the reference making the K8sGPT instance an owner of its results and
+// mutations, so they are garbage collected with it. Instances in the same namespace that report
+// the same object share its result, so the reference is not a controller reference: an object
+// can only have one.
+func OwnerReference(config v1alpha1.K8sGPT) metav1.OwnerReference {
+	gvk := v1alpha1.GroupVersion.WithKind("K8sGPT")
+	return metav1.OwnerReference{
+		APIVersion:         gvk.GroupVersion().String(),
+		Kind:               gvk.Kind,
+		Name:               config.Name,
+		UID:                config.UID,
+		BlockOwnerDeletion: utils.PtrBool(true),
+	}
 }
 
 func GetResult(resultSpec v1alpha1.ResultSpec, name, namespace, backend string, detail string) v1alpha1.Result {
@@ -64,26 +90,60 @@ func GetResult(resultSpec v1alpha1.ResultSpec, name, namespace, backend string,
 	}
 }
 
-func CreateOrUpdateResult(ctx context.Context, c client.Client, res v1alpha1.Result) (*v1alpha1.Result, error) {
+// CreateOrUpdateResult writes a result found by an analysis run and records the occurrence on
+// its status. A result that is created continues the history of its tombstone, if any.
+func CreateOrUpdateResult(ctx context.Context, c client.Client, res v1alpha1.Result,
+	tombstone *v1alpha1.ResultTombstone) (*v1alpha1.Result, error) {
 	logger := log.FromContext(ctx)
+	now := metav1.Now()
 
 	var finalResult *v1alpha1.Result
 	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
 		var existing v1alpha1.Result
 		if err := c.Get(ctx, client.ObjectKey{Namespace: res.Namespace, Name: res.Name}, &existing); err != nil {
 			if errors.IsNotFound(err) {
-				if err := c.Create(ctx, &res); err != nil {
+				created := res.DeepCopy()
+				metav1.SetMetaDataAnnotation(&created.ObjectMeta, ContentHashAnnotation, ContentHash(created.Spec))
+				if err := c.Create(ctx, created); err != nil {
+					return err
+				}
+				created.Status.LifeCycle = string(CreatedResult)
+				created.Status.LastChanged = &now
+				ObserveResult(&created.Status, tombstone, now)
+				if err := c.Status().Update(ctx, created); err != nil {
 					return err
 				}
 				logger.Info("Created result", "name", res.Name)
-				finalResult = &res
+				finalResult = created
 				return nil
 			}
 			return err
 		}
 
-		if len(existing.Spec.Error) == len(res.Spec.Error) && reflect.DeepEqual(res.Labels, existing.Labels) {
+		// Without details, e.g. while the AI backend is unavailable, the result keeps the ones it has
+		if res.Spec.Details == "" && existing.Spec.Details != "" {
+			withDetails := res.Spec
+			withDetails.Details = existing.Spec.Details
+			if ContentHash(withDetails) == storedContentHash(existing) {
+				res.Spec.Details = existing.Spec.Details
+			}
+		}
+		hash := ContentHash(res.Spec)
+
+		if hash == storedContentHash(existing) && reflect.DeepEqual(res.Labels, existing.Labels) {
+			// A new severity, e.g. after the severity rules changed, is recorded without notifying again, as
+			// are the content hash and owner of results written before they were recorded
+			_, annotated := existing.Annotations[ContentHashAnnotation]
+			owned := addOwnerReferences(&existing, res.OwnerReferences)
+			if existing.Spec.Severity != res.Spec.Severity || !annotated || !owned {
+				existing.Spec.Severity = res.Spec.Severity
+				metav1.SetMetaDataAnnotation(&existing.ObjectMeta, ContentHashAnnotation, hash)
+				if err := c.Update(ctx, &existing); err != nil {
+					return err
+				}
+			}
 			existing.Status.LifeCycle = string(NoOpResult)
+			ObserveResult(&existing.Status, nil, now)
 			if err := c.Status().Update(ctx, &existing); err != nil {
 				return err
 			}
@@ -93,10 +153,14 @@ func CreateOrUpdateResult(ctx context.Context, c client.Client, res v1alpha1.Res
 
 		existing.Spec = res.Spec
 		existing.Labels = res.Labels
+		addOwnerReferences(&existing, res.OwnerReferences)
+		metav1.SetMetaDataAnnotation(&existing.ObjectMeta, ContentHashAnnotation, hash)
 		if err := c.Update(ctx, &existing); err != nil {
 			return err
 		}
 		existing.Status.LifeCycle = string(UpdatedResult)
+		existing.Status.LastChanged = &now
+		ObserveResult(&existing.Status, nil, now)
 		if err := c.Status().Update(ctx, &existing); err != nil {
 			return err
 		}
@@ -107,3 +171,18 @@ func CreateOrUpdateResult(ctx context.Context, c client.Client, res v1alpha1.Res
 
 	return finalResult, err
 }
+
+// addOwnerReferences adds the owner references the result does not have yet and reports whether
+// it had all of them
+func addOwnerReferences(result *v1alpha1.Result, ownerReferences []metav1.OwnerReference) bool {
+	owned := true
+	for _, ownerReference := range ownerReferences {
+		if !slices.ContainsFunc(result.OwnerReferences, func(ref metav1.OwnerReference) bool {
+			return ref.UID == ownerReference.UID
+		}) {
+			result.OwnerReferences = append(result.OwnerReferences, ownerReference)
+			owned = false
+		}
+	}
+	return owned
+}
@@ -0,0 +1,73 @@
+package resources
+
+import (
+	"context"
+	"testing"
+
+	"github.com/k8sgpt-ai/k8sgpt-operator/api/v1alpha1"
+	"github.com/k8sgpt-ai/k8sgpt-operator/pkg/integrations"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_ResultsAreOwnedByTheirInstance(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+	ctx := context.Background()
+	config := v1alpha1.K8sGPT{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "k8sgpt", Name: "k8sgpt", UID: "k8sgpt-uid"},
+		Spec:       v1alpha1.K8sGPTSpec{AI: &v1alpha1.AISpec{Backend: "openai"}},
+	}
+	spec := v1alpha1.ResultSpec{Kind: "Pod", Name: "default/web", Error: []v1alpha1.Failure{{Text: "back-off restarting"}}}
+
+	rawResults := MapResults(integrations.Integrations{}, []v1alpha1.ResultSpec{spec}, config)
+	res := rawResults[ResultName("Pod", "default/web")]
+	require.Len(t, res.OwnerReferences, 1)
+	owner := res.OwnerReferences[0]
+	assert.Equal(t, "K8sGPT", owner.Kind)
+	assert.Equal(t, v1alpha1.GroupVersion.String(), owner.APIVersion)
+	assert.Equal(t, config.UID, owner.UID)
+	assert.Nil(t, owner.Controller)
+
+	// Results written before they were owned gain the owner without being sent again
+	legacy := res.DeepCopy()
+	legacy.OwnerReferences = nil
+	c := fake.NewClientBuilder().WithScheme(scheme).WithStatusSubresource(&v1alpha1.Result{}).WithObjects(legacy).Build()
+	result, err := CreateOrUpdateResult(ctx, c, res, nil)
+	require.NoError(t, err)
+	assert.Equal(t, string(NoOpResult), result.Status.LifeCycle)
+
+	var stored v1alpha1.Result
+	require.NoError(t, c.Get(ctx, client.ObjectKey{Namespace: "k8sgpt", Name: res.Name}, &stored))
+	assert.Equal(t, res.OwnerReferences, stored.OwnerReferences)
+}
+
+func Test_ResultsSharedByInstances(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+	ctx := context.Background()
+	c := fake.NewClientBuilder().WithScheme(scheme).WithStatusSubresource(&v1alpha1.Result{}).Build()
+	spec := v1alpha1.ResultSpec{Kind: "Pod", Name: "default/web", Error: []v1alpha1.Failure{{Text: "back-off restarting"}}}
+
+	// Two instances in the same namespace report the same pod
+	for _, name := range []string{"k8sgpt", "k8sgpt-local"} {
+		config := v1alpha1.K8sGPT{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "k8sgpt", Name: name, UID: types.UID(name + "-uid")},
+			Spec:       v1alpha1.K8sGPTSpec{AI: &v1alpha1.AISpec{Backend: "openai"}},
+		}
+		rawResults := MapResults(integrations.Integrations{}, []v1alpha1.ResultSpec{spec}, config)
+		_, err := CreateOrUpdateResult(ctx, c, rawResults[ResultName("Pod", "default/web")], nil)
+		require.NoError(t, err)
+	}
+
+	var stored v1alpha1.Result
+	require.NoError(t, c.Get(ctx, client.ObjectKey{Namespace: "k8sgpt", Name: ResultName("Pod", "default/web")}, &stored))
+	require.Len(t, stored.OwnerReferences, 2)
+	// The API server refuses more than one controller reference
+	assert.Nil(t, metav1.GetControllerOfNoCopy(&stored))
+}
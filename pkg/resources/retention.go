@@ -0,0 +1,127 @@
+package resources
+
+import (
+	"sort"
+	"time"
+	"unicode/utf8"
+
+	"github.com/k8sgpt-ai/k8sgpt-operator/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// EvictedMaxResults is the reason of results evicted over the maximum result count
+	EvictedMaxResults = "max_results"
+	// EvictedMaxAge is the reason of results whose content did not change for the maximum age
+	EvictedMaxAge = "max_age"
+
+	truncatedSuffix = " [truncated]"
+)
+
+// Eviction is a result the retention of its K8sGPT instance does not keep
+type Eviction struct {
+	Result v1alpha1.Result
+	Reason string
+}
+
+// RetainResults returns the results of a K8sGPT instance its retention evicts: the results whose
+// content did not change for the maximum age, then the results over the maximum result count
+func RetainResults(results []v1alpha1.Result, retention v1alpha1.RetentionConfig, now metav1.Time) []Eviction {
+	var evictions []Eviction
+	kept := results
+	if maxAge, err := time.ParseDuration(retention.MaxAge); err == nil && retention.MaxAge != "" {
+		kept = nil
+		for _, result := range results {
+			if lastChanged := lastChanged(result); now.Sub(lastChanged.Time) > maxAge {
+				evictions = append(evictions, Eviction{Result: result, Reason: EvictedMaxAge})
+			} else {
+				kept = append(kept, result)
+			}
+		}
+	}
+	for _, result := range OverflowResults(kept, retention.MaxResults) {
+		evictions = append(evictions, Eviction{Result: result, Reason: EvictedMaxResults})
+	}
+	return evictions
+}
+
+// OverflowResults returns the results over the maximum count, none when max is not positive.
+// Results of a higher severity are kept first, then the results found first, so the results
+// already stored are not replaced by new ones of the same severity.
+func OverflowResults(results []v1alpha1.Result, max int) []v1alpha1.Result {
+	if max <= 0 || len(results) <= max {
+		return nil
+	}
+	ranked := append([]v1alpha1.Result(nil), results...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		a, b := ranked[i], ranked[j]
+		if aAbove, bAbove := a.Spec.Severity.AtLeast(b.Spec.Severity), b.Spec.Severity.AtLeast(a.Spec.Severity); aAbove != bAbove {
+			return aAbove
+		}
+		if firstA, firstB := firstSeen(a), firstSeen(b); !firstA.Equal(&firstB) {
+			return firstA.Before(&firstB)
+		}
+		return a.Name < b.Name
+	})
+	return ranked[max:]
+}
+
+// TruncateDetails cuts details longer than max bytes, marking that they were cut, and reports
+// whether it did. Details are kept when max is not positive.
+func TruncateDetails(resultSpec *v1alpha1.ResultSpec, max int) bool {
+	if max <= 0 || len(resultSpec.Details) <= max {
+		return false
+	}
+	cut := max - len(truncatedSuffix)
+	if cut < 0 {
+		cut = 0
+	}
+	for cut > 0 && !utf8.RuneStart(resultSpec.Details[cut]) {
+		cut--
+	}
+	resultSpec.Details = resultSpec.Details[:cut] + truncatedSuffix
+	if len(resultSpec.Details) > max {
+		resultSpec.Details = resultSpec.Details[:max]
+	}
+	return true
+}
+
+// EvictionTombstone returns the tombstone of a result the retention evicted at now for the reason.
+// A result evicted for its age keeps the hash of its errors and parent object, so that analysis
+// runs leave it out until they change.
+func EvictionTombstone(result v1alpha1.Result, reason string, now metav1.Time) v1alpha1.ResultTombstone {
+	tombstone := Tombstone(result, now)
+	tombstone.Evicted = reason
+	if reason == EvictedMaxAge {
+		tombstone.ContentHash = evictionHash(result.Spec)
+	}
+	return tombstone
+}
+
+// EvictedUnchanged reports whether the tombstone holds the eviction of a result for its age and
+// the result found by an analysis run did not change since. Its details are not compared, they
+// are missing while the AI backend is unavailable.
+func EvictedUnchanged(tombstone v1alpha1.ResultTombstone, resultSpec v1alpha1.ResultSpec) bool {
+	return tombstone.Evicted == EvictedMaxAge && tombstone.ContentHash == evictionHash(resultSpec)
+}
+
+func evictionHash(resultSpec v1alpha1.ResultSpec) string {
+	resultSpec.Details = ""
+	return ContentHash(resultSpec)
+}
+
+// lastChanged returns when the content of the result last changed, results written before it was
+// recorded count from when they were first found
+func lastChanged(result v1alpha1.Result) metav1.Time {
+	if result.Status.LastChanged != nil {
+		return *result.Status.LastChanged
+	}
+	return firstSeen(result)
+}
+
+func firstSeen(result v1alpha1.Result) metav1.Time {
+	if result.Status.FirstSeen != nil {
+		return *result.Status.FirstSeen
+	}
+	return result.CreationTimestamp
+}
@@ -0,0 +1,97 @@
+package resources
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/k8sgpt-ai/k8sgpt-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_RetainResults(t *testing.T) {
+	now := metav1.Now()
+	result := func(name string, severity v1alpha1.Severity, firstSeen, lastSeen time.Duration) v1alpha1.Result {
+		first, last := metav1.NewTime(now.Add(-firstSeen)), metav1.NewTime(now.Add(-lastSeen))
+		return v1alpha1.Result{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       v1alpha1.ResultSpec{Severity: severity},
+			Status:     v1alpha1.ResultStatus{FirstSeen: &first, LastSeen: &last},
+		}
+	}
+	results := []v1alpha1.Result{
+		result("critical-new", v1alpha1.SeverityCritical, time.Minute, 0),
+		result("low-old", v1alpha1.SeverityLow, time.Hour, 0),
+		result("low-new", v1alpha1.SeverityLow, time.Minute, 0),
+		result("medium-new", v1alpha1.SeverityMedium, time.Minute, 0),
+		result("high-gone", v1alpha1.SeverityHigh, 3*time.Hour, 2*time.Hour),
+		result("high-unchanged", v1alpha1.SeverityHigh, 3*time.Hour, 0),
+		result("high-changed", v1alpha1.SeverityHigh, 3*time.Hour, 0),
+	}
+	// Results found by every run are aged from when their content last changed
+	changed := metav1.NewTime(now.Add(-time.Minute))
+	results[6].Status.LastChanged = &changed
+
+	evictions := RetainResults(results, v1alpha1.RetentionConfig{MaxResults: 2, MaxAge: "1h"}, now)
+	reasons := map[string]string{}
+	for _, eviction := range evictions {
+		reasons[eviction.Result.Name] = eviction.Reason
+	}
+	assert.Equal(t, map[string]string{
+		"high-gone":      EvictedMaxAge,
+		"high-unchanged": EvictedMaxAge,
+		"medium-new":     EvictedMaxResults,
+		"low-new":        EvictedMaxResults,
+		"low-old":        EvictedMaxResults,
+	}, reasons)
+
+	// Of the same severity the results found first are kept
+	overflow := OverflowResults(results[1:3], 1)
+	assert.Len(t, overflow, 1)
+	assert.Equal(t, "low-new", overflow[0].Name)
+
+	assert.Empty(t, RetainResults(results, v1alpha1.RetentionConfig{}, now))
+}
+
+func Test_EvictionTombstone(t *testing.T) {
+	now := metav1.Now()
+	result := v1alpha1.Result{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod-default-web"},
+		Spec: v1alpha1.ResultSpec{
+			Error:   []v1alpha1.Failure{{Text: "back-off restarting failed container"}},
+			Details: "the container exits",
+		},
+		Status: v1alpha1.ResultStatus{Occurrences: 12},
+	}
+
+	tombstone := EvictionTombstone(result, EvictedMaxAge, now)
+	assert.Equal(t, "pod-default-web", tombstone.Name)
+	assert.Equal(t, 12, tombstone.Occurrences)
+	assert.Equal(t, EvictedMaxAge, tombstone.Evicted)
+
+	// Runs without details, e.g. while the AI backend is unavailable, find the same result
+	unchanged := result.Spec
+	unchanged.Details = ""
+	assert.True(t, EvictedUnchanged(tombstone, unchanged))
+	changed := result.Spec
+	changed.Error = append(changed.Error, v1alpha1.Failure{Text: "image not found"})
+	assert.False(t, EvictedUnchanged(tombstone, changed))
+
+	// Results evicted over the maximum count are stored again once there is room
+	overflow := EvictionTombstone(result, EvictedMaxResults, now)
+	assert.Empty(t, overflow.ContentHash)
+	assert.False(t, EvictedUnchanged(overflow, result.Spec))
+	assert.False(t, EvictedUnchanged(Tombstone(result, now), result.Spec))
+}
+
+func Test_TruncateDetails(t *testing.T) {
+	spec := v1alpha1.ResultSpec{Details: strings.Repeat("é", 100)}
+	assert.True(t, TruncateDetails(&spec, 51))
+	assert.LessOrEqual(t, len(spec.Details), 51)
+	assert.True(t, strings.HasSuffix(spec.Details, truncatedSuffix))
+	assert.True(t, strings.HasPrefix(spec.Details, strings.Repeat("é", 19)+truncatedSuffix))
+
+	assert.False(t, TruncateDetails(&spec, 51))
+	assert.False(t, TruncateDetails(&spec, 0))
+}
@@ -0,0 +1,117 @@
+package resources
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/k8sgpt-ai/k8sgpt-operator/api/v1alpha1"
+)
+
+// DefaultSeverity is the severity of results no rule matches
+const DefaultSeverity = v1alpha1.SeverityMedium
+
+// builtinSeverityRules classify the results the rules of a K8sGPT instance leave unmatched. They
+// follow the errors the k8sgpt analyzers report, the first matching rule wins.
+var builtinSeverityRules = []v1alpha1.SeverityRule{
+	{Kind: "Pod", Pattern: `(?i)CrashLoopBackOff|OOMKilled|back-off .* restarting failed container`, Severity: v1alpha1.SeverityCritical},
+	{Kind: "Pod", Pattern: `(?i)ImagePullBackOff|ErrImagePull|back-off pulling image|FailedScheduling|nodes are available`, Severity: v1alpha1.SeverityHigh},
+	{Kind: "Node", Pattern: `(?i)type Ready|NotReady|NodeStatusUnknown`, Severity: v1alpha1.SeverityCritical},
+	{Kind: "Node", Severity: v1alpha1.SeverityHigh},
+	{Kind: "Service", Pattern: `(?i)not ready endpoints`, Severity: v1alpha1.SeverityHigh},
+	{Kind: "PersistentVolumeClaim", Severity: v1alpha1.SeverityHigh},
+	{Kind: "Deployment", Severity: v1alpha1.SeverityHigh},
+	{Kind: "StatefulSet", Severity: v1alpha1.SeverityHigh},
+	{Kind: "ReplicaSet", Severity: v1alpha1.SeverityHigh},
+	{Kind: "MutatingWebhookConfiguration", Severity: v1alpha1.SeverityHigh},
+	{Kind: "ValidatingWebhookConfiguration", Severity: v1alpha1.SeverityHigh},
+	{Kind: "Security", Severity: v1alpha1.SeverityHigh},
+	{Kind: "CronJob", Pattern: `(?i)suspended`, Severity: v1alpha1.SeverityLow},
+	{Kind: "PodDisruptionBudget", Severity: v1alpha1.SeverityLow},
+	{Kind: "NetworkPolicy", Severity: v1alpha1.SeverityLow},
+	{Kind: "ConfigMap", Severity: v1alpha1.SeverityInfo},
+}
+
+var compiledBuiltinSeverityRules = mustCompileSeverityRules(builtinSeverityRules)
+
+type severityRule struct {
+	kind     string
+	pattern  *regexp.Regexp
+	severity v1alpha1.Severity
+}
+
+// SeverityClassifier assigns severities to results from the rules of a K8sGPT instance followed
+// by the built-in rules
+type SeverityClassifier struct {
+	rules []severityRule
+}
+
+// NewSeverityClassifier compiles the rules of a K8sGPT instance. Invalid rules are skipped so
+// they do not fail the analysis, ValidateSeverityRules reports them.
+func NewSeverityClassifier(rules []v1alpha1.SeverityRule) *SeverityClassifier {
+	compiled, _ := compileSeverityRules(rules)
+	return &SeverityClassifier{rules: append(compiled, compiledBuiltinSeverityRules...)}
+}
+
+// ValidateSeverityRules returns the reasons the rules of a K8sGPT instance that are skipped
+// are invalid, nil when every rule is valid
+func ValidateSeverityRules(rules []v1alpha1.SeverityRule) error {
+	_, err := compileSeverityRules(rules)
+	return err
+}
+
+// Classify returns the severity of the first rule matching the result
+func (c *SeverityClassifier) Classify(result v1alpha1.ResultSpec) v1alpha1.Severity {
+	for _, rule := range c.rules {
+		if rule.matches(result) {
+			return rule.severity
+		}
+	}
+	return DefaultSeverity
+}
+
+func (r severityRule) matches(result v1alpha1.ResultSpec) bool {
+	if r.kind != "" && r.kind != result.Kind {
+		return false
+	}
+	if r.pattern == nil {
+		return true
+	}
+	for _, failure := range result.Error {
+		if r.pattern.MatchString(failure.Text) {
+			return true
+		}
+	}
+	return false
+}
+
+// compileSeverityRules compiles the valid rules and returns the errors of the others
+func compileSeverityRules(rules []v1alpha1.SeverityRule) ([]severityRule, error) {
+	compiled := make([]severityRule, 0, len(rules))
+	var errs []error
+	for i, rule := range rules {
+		if !rule.Severity.Valid() {
+			errs = append(errs, fmt.Errorf("severity rule %d: unknown severity %q", i, rule.Severity))
+			continue
+		}
+		compiledRule := severityRule{kind: rule.Kind, severity: rule.Severity}
+		if rule.Pattern != "" {
+			pattern, err := regexp.Compile(rule.Pattern)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("severity rule %d: %w", i, err))
+				continue
+			}
+			compiledRule.pattern = pattern
+		}
+		compiled = append(compiled, compiledRule)
+	}
+	return compiled, errors.Join(errs...)
+}
+
+func mustCompileSeverityRules(rules []v1alpha1.SeverityRule) []severityRule {
+	compiled, err := compileSeverityRules(rules)
+	if err != nil {
+		panic(err)
+	}
+	return compiled
+}
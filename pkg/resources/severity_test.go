@@ -0,0 +1,80 @@
+package resources
+
+import (
+	"testing"
+
+	"github.com/k8sgpt-ai/k8sgpt-operator/api/v1alpha1"
+	"github.com/k8sgpt-ai/k8sgpt-operator/pkg/integrations"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_SeverityClassifier(t *testing.T) {
+	result := func(kind string, errors ...string) v1alpha1.ResultSpec {
+		spec := v1alpha1.ResultSpec{Kind: kind}
+		for _, text := range errors {
+			spec.Error = append(spec.Error, v1alpha1.Failure{Text: text})
+		}
+		return spec
+	}
+	crashLoop := result("Pod", "back-off 5m0s restarting failed container=web pod=web-5d4f-x2_default")
+	unusedConfigMap := result("ConfigMap", "ConfigMap settings is not used by any pods in the namespace")
+
+	builtin := NewSeverityClassifier(nil)
+	assert.Equal(t, v1alpha1.SeverityCritical, builtin.Classify(crashLoop))
+	assert.Equal(t, v1alpha1.SeverityHigh, builtin.Classify(result("Pod", "Back-off pulling image \"nginx:broken\"")))
+	assert.Equal(t, v1alpha1.SeverityInfo, builtin.Classify(unusedConfigMap))
+	assert.Equal(t, DefaultSeverity, builtin.Classify(result("Ingress", "Ingress uses the ingress class nginx which does not exist")))
+
+	// The rules of the instance come before the built-in rules
+	classifier := NewSeverityClassifier([]v1alpha1.SeverityRule{
+		{Kind: "Pod", Pattern: "container=batch-", Severity: v1alpha1.SeverityLow},
+		{Kind: "ConfigMap", Severity: v1alpha1.SeverityMedium},
+	})
+	assert.Equal(t, v1alpha1.SeverityLow, classifier.Classify(result("Pod", "back-off 10s restarting failed container=batch-report")))
+	assert.Equal(t, v1alpha1.SeverityCritical, classifier.Classify(crashLoop))
+	assert.Equal(t, v1alpha1.SeverityMedium, classifier.Classify(unusedConfigMap))
+
+	// Invalid rules are skipped and reported, the valid rules still apply
+	invalid := []v1alpha1.SeverityRule{
+		{Pattern: "(", Severity: v1alpha1.SeverityLow},
+		{Kind: "Pod", Severity: "Urgent"},
+		{Kind: "ConfigMap", Severity: v1alpha1.SeverityLow},
+	}
+	classifier = NewSeverityClassifier(invalid)
+	assert.Equal(t, v1alpha1.SeverityCritical, classifier.Classify(crashLoop))
+	assert.Equal(t, v1alpha1.SeverityLow, classifier.Classify(unusedConfigMap))
+	err := ValidateSeverityRules(invalid)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "severity rule 0")
+	assert.Contains(t, err.Error(), "severity rule 1")
+	assert.NotContains(t, err.Error(), "severity rule 2")
+	assert.NoError(t, ValidateSeverityRules(invalid[2:]))
+}
+
+func Test_SeverityAtLeast(t *testing.T) {
+	assert.True(t, v1alpha1.SeverityCritical.AtLeast(v1alpha1.SeverityHigh))
+	assert.True(t, v1alpha1.SeverityHigh.AtLeast(v1alpha1.SeverityHigh))
+	assert.False(t, v1alpha1.SeverityLow.AtLeast(v1alpha1.SeverityMedium))
+	assert.True(t, v1alpha1.SeverityInfo.AtLeast(""))
+	// Results that were not classified yet are below any minimum
+	assert.False(t, v1alpha1.Severity("").AtLeast(v1alpha1.SeverityInfo))
+}
+
+func Test_MapResultsClassifiesSeverity(t *testing.T) {
+	config := v1alpha1.K8sGPT{Spec: v1alpha1.K8sGPTSpec{AI: &v1alpha1.AISpec{Backend: "openai"},
+		Analysis: &v1alpha1.AnalysisConfig{SeverityRules: []v1alpha1.SeverityRule{
+			{Kind: "Service", Severity: v1alpha1.SeverityCritical}}}}}
+	specs := []v1alpha1.ResultSpec{
+		{Kind: "Service", Name: "default/web"},
+		{Kind: "ConfigMap", Name: "default/settings"},
+	}
+	results := MapResults(integrations.Integrations{}, specs, config)
+	assert.Equal(t, v1alpha1.SeverityCritical, results[ResultName("Service", "default/web")].Spec.Severity)
+	assert.Equal(t, v1alpha1.SeverityInfo, results[ResultName("ConfigMap", "default/settings")].Spec.Severity)
+
+	// An invalid rule does not fail the mapping, it is skipped
+	config.Spec.Analysis.SeverityRules[0].Pattern = "["
+	results = MapResults(integrations.Integrations{}, specs, config)
+	assert.Equal(t, DefaultSeverity, results[ResultName("Service", "default/web")].Spec.Severity)
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/k8sgpt-ai/k8sgpt-operator/api/v1alpha1"
 )
@@ -50,6 +51,22 @@ func buildMattermostMessage(kind, name, details, k8sgptCR, channel, username, ic
 	}
 }
 
+func buildMattermostResolvedMessage(kind, name, text, k8sgptCR, channel, username, iconURL string) MattermostMessage {
+	return MattermostMessage{
+		Text:     fmt.Sprintf(">*[%s] The %s %s is resolved*", k8sgptCR, kind, name),
+		Channel:  channel,
+		UserName: username,
+		IconURL:  iconURL,
+		Attachments: []attachment{
+			{
+				Text:  text,
+				Color: "good",
+				Title: "Resolved",
+			},
+		},
+	}
+}
+
 func (s *MattermostSink) Configure(config v1alpha1.K8sGPT, c Client, sinkSecretValue string) {
 	s.Endpoint = sinkSecretValue
 	if s.Endpoint == "" {
@@ -86,6 +103,17 @@ func (s *MattermostSink) Emit(results v1alpha1.ResultSpec) error {
 		results.Kind, results.Name, details, s.K8sGPT,
 		s.Channel, s.UserName, s.IconURL,
 	)
+	return s.send(message)
+}
+
+func (s *MattermostSink) EmitResolved(results v1alpha1.ResultSpec, duration time.Duration) error {
+	return s.send(buildMattermostResolvedMessage(
+		results.Kind, results.Name, resolvedText(duration), s.K8sGPT,
+		s.Channel, s.UserName, s.IconURL,
+	))
+}
+
+func (s *MattermostSink) send(message MattermostMessage) error {
 	payload, err := json.Marshal(message)
 	if err != nil {
 		return err
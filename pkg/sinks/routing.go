@@ -0,0 +1,89 @@
+package sinks
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/k8sgpt-ai/k8sgpt-operator/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DefaultSinkName is the name of the sink configured by spec.sink
+const DefaultSinkName = "default"
+
+// Configured returns the sinks of the K8sGPT instance that have a type and somewhere to send to.
+// The sink configured by spec.sink comes first, named DefaultSinkName.
+func Configured(config v1alpha1.K8sGPT) []v1alpha1.SinkSpec {
+	var configured []v1alpha1.SinkSpec
+	if config.Spec.Sink != nil {
+		configured = append(configured, v1alpha1.SinkSpec{Name: DefaultSinkName, WebhookRef: *config.Spec.Sink})
+	}
+	for _, sink := range config.Spec.Sinks {
+		if !slices.ContainsFunc(configured, func(s v1alpha1.SinkSpec) bool { return s.Name == sink.Name }) {
+			configured = append(configured, sink)
+		}
+	}
+	return slices.DeleteFunc(configured, func(sink v1alpha1.SinkSpec) bool {
+		return sink.Type == "" || (sink.Endpoint == "" && sink.Secret == nil)
+	})
+}
+
+// Matches reports whether the sink receives the change of the result
+func Matches(sink v1alpha1.SinkSpec, result v1alpha1.ResultSpec, lifeCycle v1alpha1.SinkLifeCycle) bool {
+	if !result.Severity.AtLeast(sink.MinSeverity) {
+		return false
+	}
+	if len(sink.Kinds) > 0 && !slices.Contains(sink.Kinds, result.Kind) {
+		return false
+	}
+	if len(sink.Namespaces) > 0 {
+		namespace, _, namespaced := strings.Cut(result.Name, "/")
+		if !namespaced || !slices.Contains(sink.Namespaces, namespace) {
+			return false
+		}
+	}
+	return len(sink.LifeCycles) == 0 || slices.Contains(sink.LifeCycles, lifeCycle)
+}
+
+// Delivery returns the last delivery of the result to the sink, if any. Results sent to the sink
+// configured by spec.sink before deliveries were recorded count as created there.
+func Delivery(status v1alpha1.ResultStatus, name string) *v1alpha1.SinkDelivery {
+	for i := range status.Deliveries {
+		if status.Deliveries[i].Name == name {
+			return &status.Deliveries[i]
+		}
+	}
+	if name == DefaultSinkName && status.Webhook != "" {
+		return &v1alpha1.SinkDelivery{Name: name, LifeCycle: string(v1alpha1.SinkLifeCycleCreated)}
+	}
+	return nil
+}
+
+// RecordDelivery records the delivery of the change of the result to the sink, err is why it failed
+func RecordDelivery(status *v1alpha1.ResultStatus, name string, lifeCycle v1alpha1.SinkLifeCycle,
+	now metav1.Time, err error) {
+	delivery := v1alpha1.SinkDelivery{Name: name, LifeCycle: string(lifeCycle)}
+	if previous := Delivery(*status, name); previous != nil {
+		delivery.DeliveredAt = previous.DeliveredAt
+	}
+	if err != nil {
+		delivery.Error = err.Error()
+	} else {
+		delivery.DeliveredAt = &now
+	}
+	for i := range status.Deliveries {
+		if status.Deliveries[i].Name == name {
+			status.Deliveries[i] = delivery
+			return
+		}
+	}
+	status.Deliveries = append(status.Deliveries, delivery)
+}
+
+// SinkConfig returns the K8sGPT instance as the sink expects it in Configure, with spec.sink set
+// to the sink
+func SinkConfig(config v1alpha1.K8sGPT, sink v1alpha1.SinkSpec) v1alpha1.K8sGPT {
+	sinkConfig := *config.DeepCopy()
+	sinkConfig.Spec.Sink = sink.WebhookRef.DeepCopy()
+	return sinkConfig
+}
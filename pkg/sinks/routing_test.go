@@ -0,0 +1,75 @@
+package sinks
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/k8sgpt-ai/k8sgpt-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_Configured(t *testing.T) {
+	config := v1alpha1.K8sGPT{Spec: v1alpha1.K8sGPTSpec{
+		Sink: &v1alpha1.WebhookRef{Type: "slack", Endpoint: "https://hooks.slack.com/default"},
+		Sinks: []v1alpha1.SinkSpec{
+			{Name: "oncall", WebhookRef: v1alpha1.WebhookRef{Type: "mattermost", Secret: &v1alpha1.SecretRef{Name: "oncall"}}},
+			{Name: "incomplete", WebhookRef: v1alpha1.WebhookRef{Type: "slack"}},
+			{Name: DefaultSinkName, WebhookRef: v1alpha1.WebhookRef{Type: "slack", Endpoint: "https://hooks.slack.com/other"}},
+		},
+	}}
+	configured := Configured(config)
+	require.Len(t, configured, 2)
+	assert.Equal(t, DefaultSinkName, configured[0].Name)
+	assert.Equal(t, "https://hooks.slack.com/default", configured[0].Endpoint)
+	assert.Equal(t, "oncall", configured[1].Name)
+
+	assert.Empty(t, Configured(v1alpha1.K8sGPT{}))
+}
+
+func Test_Matches(t *testing.T) {
+	sink := v1alpha1.SinkSpec{
+		WebhookRef: v1alpha1.WebhookRef{MinSeverity: v1alpha1.SeverityHigh},
+		Kinds:      []string{"Pod"},
+		Namespaces: []string{"payments"},
+		LifeCycles: []v1alpha1.SinkLifeCycle{v1alpha1.SinkLifeCycleCreated, v1alpha1.SinkLifeCycleResolved},
+	}
+	result := v1alpha1.ResultSpec{Kind: "Pod", Name: "payments/api-0", Severity: v1alpha1.SeverityCritical}
+	assert.True(t, Matches(sink, result, v1alpha1.SinkLifeCycleCreated))
+	assert.True(t, Matches(sink, result, v1alpha1.SinkLifeCycleResolved))
+	assert.False(t, Matches(sink, result, v1alpha1.SinkLifeCycleUpdated))
+
+	low := result
+	low.Severity = v1alpha1.SeverityLow
+	assert.False(t, Matches(sink, low, v1alpha1.SinkLifeCycleCreated))
+	service := result
+	service.Kind = "Service"
+	assert.False(t, Matches(sink, service, v1alpha1.SinkLifeCycleCreated))
+	elsewhere := result
+	elsewhere.Name = "default/api-0"
+	assert.False(t, Matches(sink, elsewhere, v1alpha1.SinkLifeCycleCreated))
+	node := v1alpha1.ResultSpec{Kind: "Pod", Name: "worker-1", Severity: v1alpha1.SeverityCritical}
+	assert.False(t, Matches(sink, node, v1alpha1.SinkLifeCycleCreated))
+
+	assert.True(t, Matches(v1alpha1.SinkSpec{}, low, v1alpha1.SinkLifeCycleUpdated))
+}
+
+func Test_RecordDelivery(t *testing.T) {
+	var status v1alpha1.ResultStatus
+	// Results sent to spec.sink before deliveries were recorded were received there
+	assert.Nil(t, Delivery(status, DefaultSinkName))
+	status.Webhook = "https://hooks.slack.com/default"
+	assert.Equal(t, string(v1alpha1.SinkLifeCycleCreated), Delivery(status, DefaultSinkName).LifeCycle)
+	assert.Nil(t, Delivery(status, "oncall"))
+
+	now := metav1.Now()
+	RecordDelivery(&status, "oncall", v1alpha1.SinkLifeCycleCreated, now, nil)
+	RecordDelivery(&status, "oncall", v1alpha1.SinkLifeCycleUpdated, now, errors.New("503 Service Unavailable"))
+	require.Len(t, status.Deliveries, 1)
+	delivery := Delivery(status, "oncall")
+	assert.Equal(t, string(v1alpha1.SinkLifeCycleUpdated), delivery.LifeCycle)
+	assert.Equal(t, "503 Service Unavailable", delivery.Error)
+	// The last successful delivery is kept
+	assert.Equal(t, &now, delivery.DeliveredAt)
+}
@@ -1,6 +1,7 @@
 package sinks
 
 import (
+	"fmt"
 	"net/http"
 	"time"
 
@@ -10,17 +11,22 @@ import (
 type ISink interface {
 	Configure(config v1alpha1.K8sGPT, c Client, sinkSecretValue string)
 	Emit(results v1alpha1.ResultSpec) error
+	// EmitResolved reports that the problem of a result, reported for the duration, is gone
+	EmitResolved(results v1alpha1.ResultSpec, duration time.Duration) error
 }
 
-func NewSink(sinkType string) ISink {
+// NewSink returns the sink of the type, it fails for types it does not know
+func NewSink(sinkType string) (ISink, error) {
 	switch sinkType {
 	case "slack":
-		return &SlackSink{}
+		return &SlackSink{}, nil
 	//Introduce more Sink Providers
 	case "mattermost":
-		return &MattermostSink{}
+		return &MattermostSink{}, nil
+	case "teams":
+		return &TeamsSink{}, nil
 	default:
-		return &SlackSink{}
+		return nil, fmt.Errorf("unknown sink type %q", sinkType)
 	}
 }
 
@@ -36,3 +42,11 @@ func NewClient(timeout time.Duration) *Client {
 		hclient: client,
 	}
 }
+
+// resolvedText describes how long the problem of a resolved result lasted
+func resolvedText(duration time.Duration) string {
+	if duration < time.Minute {
+		return "Resolved after less than a minute"
+	}
+	return fmt.Sprintf("Resolved after %s", duration.Truncate(time.Minute))
+}
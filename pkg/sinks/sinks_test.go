@@ -1,13 +1,17 @@
 package sinks
 
 import (
+	"encoding/json"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
 
 	"github.com/k8sgpt-ai/k8sgpt-operator/api/v1alpha1"
+	"github.com/k8sgpt-ai/k8sgpt-operator/pkg/resources"
 	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 func Test_NewSink(t *testing.T) {
@@ -27,16 +31,27 @@ func Test_NewSink(t *testing.T) {
 			want:     &MattermostSink{},
 		},
 		{
-			name:     "default sink",
+			name:     "teams sink",
+			sinkType: "teams",
+			want:     &TeamsSink{},
+		},
+		{
+			name:     "unknown sink",
 			sinkType: "unknown",
-			want:     &SlackSink{},
+			want:     nil,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := NewSink(tt.sinkType)
-			assert.NotNil(t, got, "NewSink() should not return nil")
+			got, err := NewSink(tt.sinkType)
+			if tt.want == nil {
+				assert.Error(t, err)
+				assert.Nil(t, got)
+				return
+			}
+			assert.NoError(t, err)
+			assert.IsType(t, tt.want, got)
 		})
 	}
 }
@@ -190,3 +205,131 @@ func Test_SlackSinkEmit(t *testing.T) {
 		})
 	}
 }
+
+func Test_SinkEmitResolved(t *testing.T) {
+	for _, sink := range []ISink{
+		&SlackSink{K8sGPT: "k8sgpt", Client: *NewClient(2 * time.Second)},
+		&MattermostSink{K8sGPT: "k8sgpt", Client: *NewClient(2 * time.Second)},
+		&TeamsSink{K8sGPT: "k8sgpt", Client: *NewClient(2 * time.Second)},
+	} {
+		var body string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			payload, _ := io.ReadAll(r.Body)
+			body = string(payload)
+			w.WriteHeader(http.StatusOK)
+		}))
+		switch s := sink.(type) {
+		case *SlackSink:
+			s.Endpoint = server.URL
+		case *MattermostSink:
+			s.Endpoint = server.URL
+		case *TeamsSink:
+			s.Endpoint = server.URL
+		}
+
+		err := sink.EmitResolved(v1alpha1.ResultSpec{Kind: "Pod", Name: "default/web"}, 2*time.Hour+5*time.Minute+3*time.Second)
+		server.Close()
+		assert.NoError(t, err)
+		assert.Contains(t, body, "The Pod default/web is resolved")
+		assert.Contains(t, body, "Resolved after 2h5m0s")
+	}
+}
+
+func Test_TeamsSinkConfigure(t *testing.T) {
+	sink := &TeamsSink{}
+	client := NewClient(2 * time.Second)
+	config := v1alpha1.K8sGPT{
+		ObjectMeta: metav1.ObjectMeta{Name: "k8sgpt", Namespace: "k8sgpt-operator-system"},
+		Spec: v1alpha1.K8sGPTSpec{
+			Sink: &v1alpha1.WebhookRef{
+				Endpoint:  "http://example.com",
+				ResultURL: "https://dashboard.example.com/{namespace}/results/{name}",
+			},
+		},
+	}
+
+	sink.Configure(config, *client, "http://secret.example.com")
+
+	assert.Equal(t, "http://secret.example.com", sink.Endpoint)
+	assert.Equal(t, "k8sgpt", sink.K8sGPT)
+	assert.Equal(t, "k8sgpt-operator-system", sink.Namespace)
+	assert.Equal(t, "https://dashboard.example.com/{namespace}/results/{name}", sink.ResultURL)
+	assert.Equal(t, client, &sink.Client)
+}
+
+func Test_TeamsSinkEmit(t *testing.T) {
+	tests := []struct {
+		name         string
+		resultURL    string
+		responseCode int
+		expectError  bool
+	}{
+		{
+			name:         "Incoming webhook",
+			resultURL:    "https://dashboard.example.com/{namespace}/results/{name}",
+			responseCode: http.StatusOK,
+		},
+		{
+			name:         "Workflow webhook without a result link",
+			responseCode: http.StatusAccepted,
+		},
+		{
+			name:         "Failed response",
+			responseCode: http.StatusBadRequest,
+			expectError:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var message TeamsMessage
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.NoError(t, json.NewDecoder(r.Body).Decode(&message))
+				w.WriteHeader(tt.responseCode)
+			}))
+			defer server.Close()
+			sink := &TeamsSink{
+				Endpoint:  server.URL,
+				K8sGPT:    "k8sgpt",
+				Client:    *NewClient(2 * time.Second),
+				Namespace: "k8sgpt-operator-system",
+				ResultURL: tt.resultURL,
+			}
+
+			err := sink.Emit(v1alpha1.ResultSpec{
+				Kind:     "Pod",
+				Name:     "default/web",
+				Error:    []v1alpha1.Failure{{Text: "Back-off pulling image"}},
+				Details:  "The image tag does not exist",
+				Severity: v1alpha1.SeverityCritical,
+			})
+
+			if tt.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, "message", message.Type)
+			assert.Len(t, message.Attachments, 1)
+			card := message.Attachments[0]
+			assert.Equal(t, "application/vnd.microsoft.card.adaptive", card.ContentType)
+			assert.Equal(t, "AdaptiveCard", card.Content.Type)
+			assert.Contains(t, card.Content.Body, CardElement{Type: "FactSet", Facts: []CardFact{
+				{Title: "Kind", Value: "Pod"},
+				{Title: "Name", Value: "web"},
+				{Title: "Namespace", Value: "default"},
+				{Title: "Severity", Value: "Critical"},
+				{Title: "Result", Value: "k8sgpt-operator-system/" + resources.ResultName("Pod", "default/web")},
+			}})
+			assert.Contains(t, card.Content.Body, CardElement{Type: "TextBlock", Text: "- Back-off pulling image", Wrap: true})
+			assert.Contains(t, card.Content.Body, CardElement{Type: "TextBlock", Text: "The image tag does not exist", Wrap: true})
+			if tt.resultURL == "" {
+				assert.Empty(t, card.Content.Actions)
+			} else {
+				assert.Equal(t, []CardAction{{Type: "Action.OpenUrl", Title: "View result",
+					URL: "https://dashboard.example.com/k8sgpt-operator-system/results/" + resources.ResultName("Pod", "default/web")}},
+					card.Content.Actions)
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/k8sgpt-ai/k8sgpt-operator/api/v1alpha1"
 )
@@ -43,6 +44,20 @@ func buildSlackMessage(kind, name, details, k8sgptCR string) SlackMessage {
 	}
 }
 
+func buildSlackResolvedMessage(kind, name, text, k8sgptCR string) SlackMessage {
+	return SlackMessage{
+		Text: fmt.Sprintf(">*[%s] The %s %s is resolved*", k8sgptCR, kind, name),
+		Attachments: []Attachment{
+			{
+				Type:  "mrkdwn",
+				Text:  text,
+				Color: "good",
+				Title: "Resolved",
+			},
+		},
+	}
+}
+
 func (s *SlackSink) Configure(config v1alpha1.K8sGPT, c Client, sinkSecretValue string) {
 	s.Endpoint = sinkSecretValue
 	// check if the webhook url is passed as a sinkSecretValue, if not use spec.sink.webhook
@@ -55,7 +70,14 @@ func (s *SlackSink) Configure(config v1alpha1.K8sGPT, c Client, sinkSecretValue
 }
 
 func (s *SlackSink) Emit(results v1alpha1.ResultSpec) error {
-	message := buildSlackMessage(results.Kind, results.Name, results.Details, s.K8sGPT)
+	return s.send(buildSlackMessage(results.Kind, results.Name, results.Details, s.K8sGPT))
+}
+
+func (s *SlackSink) EmitResolved(results v1alpha1.ResultSpec, duration time.Duration) error {
+	return s.send(buildSlackResolvedMessage(results.Kind, results.Name, resolvedText(duration), s.K8sGPT))
+}
+
+func (s *SlackSink) send(message SlackMessage) error {
 	payload, err := json.Marshal(message)
 	if err != nil {
 		return err
@@ -0,0 +1,185 @@
+package sinks
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/k8sgpt-ai/k8sgpt-operator/api/v1alpha1"
+	"github.com/k8sgpt-ai/k8sgpt-operator/pkg/resources"
+)
+
+var _ ISink = (*TeamsSink)(nil)
+
+// TeamsSink posts Adaptive Cards to Teams workflow and incoming webhooks
+type TeamsSink struct {
+	Endpoint  string
+	K8sGPT    string
+	Client    Client
+	Namespace string
+	ResultURL string
+}
+
+type TeamsMessage struct {
+	Type        string            `json:"type"`
+	Attachments []TeamsAttachment `json:"attachments"`
+}
+
+type TeamsAttachment struct {
+	ContentType string       `json:"contentType"`
+	Content     AdaptiveCard `json:"content"`
+}
+
+type AdaptiveCard struct {
+	Schema  string        `json:"$schema"`
+	Type    string        `json:"type"`
+	Version string        `json:"version"`
+	Body    []CardElement `json:"body"`
+	Actions []CardAction  `json:"actions,omitempty"`
+}
+
+type CardElement struct {
+	Type   string     `json:"type"`
+	Text   string     `json:"text,omitempty"`
+	Weight string     `json:"weight,omitempty"`
+	Size   string     `json:"size,omitempty"`
+	Color  string     `json:"color,omitempty"`
+	Wrap   bool       `json:"wrap,omitempty"`
+	Facts  []CardFact `json:"facts,omitempty"`
+}
+
+type CardFact struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+}
+
+type CardAction struct {
+	Type  string `json:"type"`
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+func buildTeamsMessage(title, color string, facts []CardFact, sections []CardElement, resultURL string) TeamsMessage {
+	card := AdaptiveCard{
+		Schema:  "http://adaptivecards.io/schemas/adaptive-card.json",
+		Type:    "AdaptiveCard",
+		Version: "1.4",
+		Body: append([]CardElement{
+			{Type: "TextBlock", Text: title, Weight: "Bolder", Size: "Medium", Color: color, Wrap: true},
+			{Type: "FactSet", Facts: facts},
+		}, sections...),
+	}
+	if resultURL != "" {
+		card.Actions = []CardAction{{Type: "Action.OpenUrl", Title: "View result", URL: resultURL}}
+	}
+	return TeamsMessage{
+		Type: "message",
+		Attachments: []TeamsAttachment{
+			{ContentType: "application/vnd.microsoft.card.adaptive", Content: card},
+		},
+	}
+}
+
+// cardSection is a titled block of text of a card
+func cardSection(title string, lines ...string) []CardElement {
+	section := []CardElement{{Type: "TextBlock", Text: title, Weight: "Bolder", Wrap: true}}
+	for _, line := range lines {
+		section = append(section, CardElement{Type: "TextBlock", Text: line, Wrap: true})
+	}
+	return section
+}
+
+func (s *TeamsSink) Configure(config v1alpha1.K8sGPT, c Client, sinkSecretValue string) {
+	s.Endpoint = sinkSecretValue
+	if s.Endpoint == "" {
+		s.Endpoint = config.Spec.Sink.Endpoint
+	}
+	s.ResultURL = config.Spec.Sink.ResultURL
+	s.Client = c
+	// take the name of the K8sGPT Custom ResourceRef
+	s.K8sGPT = config.Name
+	// results are created in the namespace of the K8sGPT instance
+	s.Namespace = config.Namespace
+}
+
+func (s *TeamsSink) Emit(results v1alpha1.ResultSpec) error {
+	var sections []CardElement
+	if len(results.Error) > 0 {
+		var errs []string
+		for _, failure := range results.Error {
+			errs = append(errs, "- "+failure.Text)
+		}
+		sections = append(sections, cardSection("Errors", errs...)...)
+	}
+	if results.Details != "" {
+		sections = append(sections, cardSection("Details", results.Details)...)
+	}
+	return s.send(buildTeamsMessage(
+		fmt.Sprintf("[%s] K8sGPT analysis of the %s %s", s.K8sGPT, results.Kind, results.Name),
+		"Attention", s.facts(results), sections, s.resultURL(results),
+	))
+}
+
+func (s *TeamsSink) EmitResolved(results v1alpha1.ResultSpec, duration time.Duration) error {
+	return s.send(buildTeamsMessage(
+		fmt.Sprintf("[%s] The %s %s is resolved", s.K8sGPT, results.Kind, results.Name),
+		"Good", s.facts(results), []CardElement{{Type: "TextBlock", Text: resolvedText(duration), Wrap: true}},
+		s.resultURL(results),
+	))
+}
+
+// facts describes the object of the result and the result itself
+func (s *TeamsSink) facts(results v1alpha1.ResultSpec) []CardFact {
+	namespace, name, namespaced := strings.Cut(results.Name, "/")
+	if !namespaced {
+		namespace, name = "", results.Name
+	}
+	facts := []CardFact{{Title: "Kind", Value: results.Kind}, {Title: "Name", Value: name}}
+	if namespace != "" {
+		facts = append(facts, CardFact{Title: "Namespace", Value: namespace})
+	}
+	if results.Severity != "" {
+		facts = append(facts, CardFact{Title: "Severity", Value: string(results.Severity)})
+	}
+	return append(facts, CardFact{Title: "Result",
+		Value: fmt.Sprintf("%s/%s", s.Namespace, resources.ResultName(results.Kind, results.Name))})
+}
+
+// resultURL returns the link to the result, if one is configured
+func (s *TeamsSink) resultURL(results v1alpha1.ResultSpec) string {
+	if s.ResultURL == "" {
+		return ""
+	}
+	return strings.NewReplacer(
+		"{namespace}", s.Namespace,
+		"{name}", resources.ResultName(results.Kind, results.Name),
+	).Replace(s.ResultURL)
+}
+
+func (s *TeamsSink) send(message TeamsMessage) error {
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, s.Endpoint, bytes.NewBuffer(payload))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.Client.hclient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// Incoming webhooks answer 200, workflow webhooks 202 as the flow runs asynchronously
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
+		return fmt.Errorf("failed to send report: %s", resp.Status)
+	}
+
+	return nil
+}